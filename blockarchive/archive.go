@@ -0,0 +1,109 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package blockarchive
+
+// Block archive is a directory with:
+//   - archive.json: Info, written last (archive is complete when it exists)
+//   - blocks.zst: one independent zstd frame of JSON BlockRecord per block
+//   - blocks.idx: offset and length (uint64 big endian each) of frame of each
+//     height from BaseHeight to LastHeight
+//   - txs.idx: tx hash (32 bytes), height (int64 big endian) and index
+//     (uint32 big endian) of each tx sorted by tx hash
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ndidplatform/migration-tools/tendermint"
+)
+
+const FormatVersion = 1
+
+const (
+	InfoFilename        = "archive.json"
+	BlocksFilename      = "blocks.zst"
+	BlockIndexFilename  = "blocks.idx"
+	TxIndexFilename     = "txs.idx"
+	blockIndexEntrySize = 16
+	txHashSize          = 32
+	txIndexEntrySize    = txHashSize + 8 + 4
+)
+
+var ErrNotFound = errors.New("not found")
+
+type Info struct {
+	FormatVersion     int       `json:"format_version"`
+	TendermintVersion string    `json:"tendermint_version"`
+	BlockEncoding     string    `json:"block_encoding"`
+	ChainID           string    `json:"chain_id"`
+	BaseHeight        int64     `json:"base_height"`
+	LastHeight        int64     `json:"last_height"`
+	BlockCount        int64     `json:"block_count"`
+	TxCount           int64     `json:"tx_count"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type BlockRecord struct {
+	Meta tendermint.BlockMeta `json:"meta"`
+	// Block is encoded block as stored in block store (Info.BlockEncoding)
+	Block []byte `json:"block,omitempty"`
+	// ABCIResponses is encoded ABCI responses as stored in state DB
+	ABCIResponses []byte     `json:"abci_responses,omitempty"`
+	Txs           []TxRecord `json:"txs"`
+}
+
+type TxRecord struct {
+	Hash tendermint.HexBytes `json:"hash"`
+	Tx   []byte              `json:"tx"`
+	// Result is nil when ABCI responses are not stored
+	Result *tendermint.TxResult `json:"result,omitempty"`
+}
+
+// TxLookupResult is a tx found by hash
+type TxLookupResult struct {
+	Height    int64     `json:"height"`
+	Index     uint32    `json:"index"`
+	BlockTime time.Time `json:"block_time"`
+	TxRecord
+}
+
+// NewBlockRecord creates archive record of block loaded from block store
+func NewBlockRecord(block *tendermint.Block) (record *BlockRecord) {
+	record = &BlockRecord{
+		Meta:          block.Meta,
+		Block:         block.Data,
+		ABCIResponses: block.ABCIResponses,
+		Txs:           make([]TxRecord, len(block.Txs)),
+	}
+	for index, tx := range block.Txs {
+		record.Txs[index] = TxRecord{
+			Hash: tendermint.TxHash(tx),
+			Tx:   tx,
+		}
+		if block.TxResults != nil {
+			txResult := block.TxResults[index]
+			record.Txs[index].Result = &txResult
+		}
+	}
+	return record
+}
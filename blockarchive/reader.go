@@ -0,0 +1,197 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package blockarchive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Archive reads blocks and txs from an archive directory
+type Archive struct {
+	info           Info
+	blocksFile     *os.File
+	blockIndexFile *os.File
+	txIndexFile    *os.File
+	txCount        int64
+	decoder        *zstd.Decoder
+}
+
+// Open opens a complete archive (written by Writer) in dir
+func Open(dir string) (archive *Archive, err error) {
+	infoJSON, err := os.ReadFile(filepath.Join(dir, InfoFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("archive not found or incomplete: " + dir)
+		}
+		return nil, err
+	}
+	archive = new(Archive)
+	err = json.Unmarshal(infoJSON, &archive.info)
+	if err != nil {
+		return nil, err
+	}
+	if archive.info.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("unsupported archive format version: %d", archive.info.FormatVersion)
+	}
+
+	defer func() {
+		if err != nil {
+			archive.Close()
+		}
+	}()
+	archive.blocksFile, err = os.Open(filepath.Join(dir, BlocksFilename))
+	if err != nil {
+		return nil, err
+	}
+	archive.blockIndexFile, err = os.Open(filepath.Join(dir, BlockIndexFilename))
+	if err != nil {
+		return nil, err
+	}
+	archive.txIndexFile, err = os.Open(filepath.Join(dir, TxIndexFilename))
+	if err != nil {
+		return nil, err
+	}
+	txIndexFileInfo, err := archive.txIndexFile.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if txIndexFileInfo.Size()%txIndexEntrySize != 0 {
+		return nil, errors.New("invalid tx index file size")
+	}
+	archive.txCount = txIndexFileInfo.Size() / txIndexEntrySize
+	archive.decoder, err = zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+func (a *Archive) Info() Info {
+	return a.info
+}
+
+func (a *Archive) Close() (err error) {
+	if a.decoder != nil {
+		a.decoder.Close()
+	}
+	for _, file := range []*os.File{a.blocksFile, a.blockIndexFile, a.txIndexFile} {
+		if file == nil {
+			continue
+		}
+		closeErr := file.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// GetBlock returns block record at height. ErrNotFound is returned when height
+// is not in archive.
+func (a *Archive) GetBlock(height int64) (record *BlockRecord, err error) {
+	if a.info.BlockCount == 0 || height < a.info.BaseHeight || height > a.info.LastHeight {
+		return nil, ErrNotFound
+	}
+	var indexEntry [blockIndexEntrySize]byte
+	_, err = a.blockIndexFile.ReadAt(indexEntry[:], (height-a.info.BaseHeight)*blockIndexEntrySize)
+	if err != nil {
+		return nil, err
+	}
+	offset := binary.BigEndian.Uint64(indexEntry[0:8])
+	length := binary.BigEndian.Uint64(indexEntry[8:16])
+
+	frame := make([]byte, length)
+	_, err = a.blocksFile.ReadAt(frame, int64(offset))
+	if err != nil {
+		return nil, err
+	}
+	recordJSON, err := a.decoder.DecodeAll(frame, nil)
+	if err != nil {
+		return nil, err
+	}
+	record = new(BlockRecord)
+	err = json.Unmarshal(recordJSON, record)
+	if err != nil {
+		return nil, err
+	}
+	if record.Meta.Height != height {
+		return nil, fmt.Errorf("block index is corrupted: height %d, record height: %d", height, record.Meta.Height)
+	}
+	return record, nil
+}
+
+// GetTx returns tx with hash. ErrNotFound is returned when tx is not in archive.
+func (a *Archive) GetTx(hash []byte) (result *TxLookupResult, err error) {
+	if len(hash) != txHashSize {
+		return nil, fmt.Errorf("invalid tx hash size: %d", len(hash))
+	}
+	var readErr error
+	readEntry := func(i int64) (entry [txIndexEntrySize]byte) {
+		if readErr == nil {
+			_, readErr = a.txIndexFile.ReadAt(entry[:], i*txIndexEntrySize)
+		}
+		return entry
+	}
+	i := sort.Search(int(a.txCount), func(i int) bool {
+		entry := readEntry(int64(i))
+		return bytes.Compare(entry[0:txHashSize], hash) >= 0
+	})
+	if readErr != nil {
+		return nil, readErr
+	}
+	if int64(i) >= a.txCount {
+		return nil, ErrNotFound
+	}
+	entry := readEntry(int64(i))
+	if readErr != nil {
+		return nil, readErr
+	}
+	if !bytes.Equal(entry[0:txHashSize], hash) {
+		return nil, ErrNotFound
+	}
+	height := int64(binary.BigEndian.Uint64(entry[txHashSize : txHashSize+8]))
+	index := binary.BigEndian.Uint32(entry[txHashSize+8:])
+
+	record, err := a.GetBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	if int(index) >= len(record.Txs) {
+		return nil, fmt.Errorf("tx index is corrupted: height %d, index %d", height, index)
+	}
+	return &TxLookupResult{
+		Height:    height,
+		Index:     index,
+		BlockTime: record.Meta.Time,
+		TxRecord:  record.Txs[index],
+	}, nil
+}
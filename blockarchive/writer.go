@@ -0,0 +1,210 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package blockarchive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type txIndexEntry struct {
+	hash   []byte
+	height int64
+	index  uint32
+}
+
+// Writer writes blocks of consecutive heights to a new archive
+type Writer struct {
+	dir            string
+	info           Info
+	blocksFile     *os.File
+	blockIndexFile *os.File
+	blockIndex     *bufio.Writer
+	encoder        *zstd.Encoder
+	offset         uint64
+	txIndex        []txIndexEntry
+}
+
+// Create creates a new archive in dir. dir must not exist or be empty.
+// Info.BaseHeight, LastHeight, BlockCount, TxCount and CreatedAt are set by
+// the writer.
+func Create(dir string, info Info) (writer *Writer, err error) {
+	entries, err := os.ReadDir(dir)
+	if err == nil && len(entries) > 0 {
+		return nil, errors.New("archive directory is not empty: " + dir)
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	blocksFile, err := os.OpenFile(filepath.Join(dir, BlocksFilename), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	blockIndexFile, err := os.OpenFile(filepath.Join(dir, BlockIndexFilename), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		blocksFile.Close()
+		return nil, err
+	}
+
+	info.FormatVersion = FormatVersion
+	info.BaseHeight = 0
+	info.LastHeight = 0
+	info.BlockCount = 0
+	info.TxCount = 0
+	return &Writer{
+		dir:            dir,
+		info:           info,
+		blocksFile:     blocksFile,
+		blockIndexFile: blockIndexFile,
+		blockIndex:     bufio.NewWriter(blockIndexFile),
+		encoder:        encoder,
+		txIndex:        make([]txIndexEntry, 0),
+	}, nil
+}
+
+// WriteBlock appends block record. Height must be next to previous block.
+func (w *Writer) WriteBlock(record *BlockRecord) (err error) {
+	height := record.Meta.Height
+	if w.info.BlockCount == 0 {
+		w.info.BaseHeight = height
+	} else if height != w.info.LastHeight+1 {
+		return fmt.Errorf("block height %d is not next to last written block height %d", height, w.info.LastHeight)
+	}
+
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	frame := w.encoder.EncodeAll(recordJSON, nil)
+	_, err = w.blocksFile.Write(frame)
+	if err != nil {
+		return err
+	}
+
+	var indexEntry [blockIndexEntrySize]byte
+	binary.BigEndian.PutUint64(indexEntry[0:8], w.offset)
+	binary.BigEndian.PutUint64(indexEntry[8:16], uint64(len(frame)))
+	_, err = w.blockIndex.Write(indexEntry[:])
+	if err != nil {
+		return err
+	}
+	w.offset += uint64(len(frame))
+
+	for index, tx := range record.Txs {
+		w.txIndex = append(w.txIndex, txIndexEntry{
+			hash:   tx.Hash,
+			height: height,
+			index:  uint32(index),
+		})
+	}
+
+	w.info.LastHeight = height
+	w.info.BlockCount++
+	w.info.TxCount += int64(len(record.Txs))
+	return nil
+}
+
+// Close writes tx index and archive info. Archive is incomplete (cannot be
+// opened) if Close is not called or returns error.
+func (w *Writer) Close() (err error) {
+	defer w.encoder.Close()
+	defer w.blockIndexFile.Close()
+	defer w.blocksFile.Close()
+
+	err = w.blockIndex.Flush()
+	if err != nil {
+		return err
+	}
+	err = w.blockIndexFile.Sync()
+	if err != nil {
+		return err
+	}
+	err = w.blocksFile.Sync()
+	if err != nil {
+		return err
+	}
+
+	err = w.writeTxIndex()
+	if err != nil {
+		return err
+	}
+
+	w.info.CreatedAt = time.Now()
+	infoJSON, err := json.MarshalIndent(w.info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(w.dir, InfoFilename), infoJSON, 0644)
+}
+
+// Info returns info of blocks written so far
+func (w *Writer) Info() Info {
+	return w.info
+}
+
+func (w *Writer) writeTxIndex() (err error) {
+	sort.Slice(w.txIndex, func(i, j int) bool {
+		return bytes.Compare(w.txIndex[i].hash, w.txIndex[j].hash) < 0
+	})
+
+	txIndexFile, err := os.OpenFile(filepath.Join(w.dir, TxIndexFilename), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer txIndexFile.Close()
+	txIndexWriter := bufio.NewWriter(txIndexFile)
+	for _, entry := range w.txIndex {
+		if len(entry.hash) != txHashSize {
+			return fmt.Errorf("invalid tx hash size: %d", len(entry.hash))
+		}
+		var indexEntry [txIndexEntrySize]byte
+		copy(indexEntry[0:txHashSize], entry.hash)
+		binary.BigEndian.PutUint64(indexEntry[txHashSize:txHashSize+8], uint64(entry.height))
+		binary.BigEndian.PutUint32(indexEntry[txHashSize+8:], entry.index)
+		_, err = txIndexWriter.Write(indexEntry[:])
+		if err != nil {
+			return err
+		}
+	}
+	err = txIndexWriter.Flush()
+	if err != nil {
+		return err
+	}
+	return txIndexFile.Sync()
+}
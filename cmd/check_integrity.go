@@ -0,0 +1,229 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ndidplatform/migration-tools/statedata"
+	"github.com/ndidplatform/migration-tools/utils"
+)
+
+func checkIntegrity(version string, dataFilePath string, repairDirPath string, reportFilePath string) (err error) {
+	startTime := time.Now()
+
+	abciVersion, err := parseABCIVersion(version)
+	if err != nil {
+		return err
+	}
+	if repairDirPath != "" && dataFilePath == "" {
+		return errors.New("repair requires initial state data file (--data-file)")
+	}
+
+	initialStateDataFilename := viper.GetString("INITIAL_STATE_DATA_FILENAME")
+	chainHistoryFilename := viper.GetString("CHAIN_HISTORY_FILENAME")
+	initialStateMetadataFilename := viper.GetString("METADATA_FILENAME")
+	if repairDirPath != "" {
+		// Do not overwrite existing initial state data (e.g. source data)
+		entries, err := os.ReadDir(repairDirPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("repair output directory is not empty: %s", repairDirPath)
+		}
+		err = os.MkdirAll(repairDirPath, 0755)
+		if err != nil {
+			return err
+		}
+	}
+
+	source, err := openStateDataSource(abciVersion, stateDataSourcePath(dataFilePath))
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	var save func(key []byte, value []byte) (err error)
+	var repairedKeyCount int64
+	if repairDirPath != "" {
+		repairFile, err := os.OpenFile(path.Join(repairDirPath, initialStateDataFilename), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return err
+		}
+		defer repairFile.Close()
+
+		save = func(key []byte, value []byte) (err error) {
+			var kv KeyValue
+			kv.Key = key
+			kv.Value = value
+			jsonStr, err := json.Marshal(kv)
+			if err != nil {
+				return err
+			}
+			err = utils.AppendLineToOpenedFile(repairFile, jsonStr)
+			if err != nil {
+				return err
+			}
+			repairedKeyCount++
+			return nil
+		}
+	}
+
+	report, err := statedata.CheckIntegrity(source, abciVersion, save)
+	if err != nil {
+		return err
+	}
+
+	if repairDirPath != "" {
+		err = writeRepairedMetadata(
+			filepath.Dir(dataFilePath),
+			repairDirPath,
+			chainHistoryFilename,
+			initialStateMetadataFilename,
+			repairedKeyCount,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	reportJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if reportFilePath != "" {
+		err = os.WriteFile(reportFilePath, reportJSON, 0644)
+		if err != nil {
+			return err
+		}
+	} else {
+		fmt.Println(string(reportJSON))
+	}
+
+	log.Println("===== Integrity Check Summary =====")
+	log.Println("total key count:", report.KeyCount)
+	log.Println("checked key count:", report.CheckedKeyCount)
+	log.Println("issue count:", len(report.Issues))
+	log.Println("issue count by check:", report.IssueCounts)
+	if repairDirPath != "" {
+		log.Println("repaired initial state data directory:", repairDirPath)
+		log.Println("repaired initial state data key count:", repairedKeyCount)
+	}
+	log.Println("time used:", time.Since(startTime))
+
+	if len(report.Issues) > 0 && repairDirPath == "" {
+		return fmt.Errorf("integrity check failed: %d issue(s)", len(report.Issues))
+	}
+
+	return nil
+}
+
+// writeRepairedMetadata writes metadata (of source initial state data if
+// exists) with key count of repaired data and copies chain history to repair
+// output directory, so that repaired initial state data is used the same way
+// as source initial state data
+func writeRepairedMetadata(
+	sourceDirPath string,
+	repairDirPath string,
+	chainHistoryFilename string,
+	initialStateMetadataFilename string,
+	repairedKeyCount int64,
+) (err error) {
+	var metadata Metadata
+	metadataJson, err := os.ReadFile(path.Join(sourceDirPath, initialStateMetadataFilename))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		err = json.Unmarshal(metadataJson, &metadata)
+		if err != nil {
+			return err
+		}
+	}
+	metadata.TotalKeyCount = repairedKeyCount
+	metadataJson, err = json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path.Join(repairDirPath, initialStateMetadataFilename), metadataJson, 0644)
+	if err != nil {
+		return err
+	}
+
+	chainHistory, err := os.ReadFile(path.Join(sourceDirPath, chainHistoryFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		log.Println("chain history not found next to source data file, not copied")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(repairDirPath, chainHistoryFilename), chainHistory, 0644)
+}
+
+var checkIntegrityCmd = &cobra.Command{
+	Use:   "check-integrity [version]",
+	Short: "Check references between keys of ABCI state DB or initial state data (v7, v8, v9)",
+	Args:  cobra.ExactArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		curDir, _ := os.Getwd()
+		viper.SetDefault("ABCI_DB_TYPE", "goleveldb")
+		viper.SetDefault("ABCI_DB_DIR_PATH", path.Join(curDir, "../smart-contract/DB1"))
+
+		viper.SetDefault("INITIAL_STATE_DATA_FILENAME", "data")
+		viper.SetDefault("CHAIN_HISTORY_FILENAME", "chain_history")
+		viper.SetDefault("METADATA_FILENAME", "metadata")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dataFilePath, err := cmd.Flags().GetString("data-file")
+		if err != nil {
+			return err
+		}
+		repairDirPath, err := cmd.Flags().GetString("repair")
+		if err != nil {
+			return err
+		}
+		reportFilePath, err := cmd.Flags().GetString("report")
+		if err != nil {
+			return err
+		}
+		return checkIntegrity(args[0], dataFilePath, repairDirPath, reportFilePath)
+	},
+}
+
+func init() {
+	checkIntegrityCmd.Flags().String("data-file", "", "initial state data file to read instead of ABCI state DB")
+	checkIntegrityCmd.Flags().String("repair", "", "write initial state data (data, metadata and chain history) with issues repaired to this directory, must be empty (requires --data-file)")
+	checkIntegrityCmd.Flags().String("report", "", "report file path (default stdout)")
+	rootCmd.AddCommand(checkIntegrityCmd)
+}
@@ -29,6 +29,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -38,6 +39,10 @@ import (
 
 	"github.com/ndidplatform/migration-tools/convert"
 	"github.com/ndidplatform/migration-tools/rand"
+	"github.com/ndidplatform/migration-tools/snapshot"
+	"github.com/ndidplatform/migration-tools/statedata"
+	"github.com/ndidplatform/migration-tools/tendermint"
+	"github.com/ndidplatform/migration-tools/transform"
 	"github.com/ndidplatform/migration-tools/utils"
 )
 
@@ -68,7 +73,8 @@ type KeyValue struct {
 }
 
 type Metadata struct {
-	TotalKeyCount int64 `json:"total_key_count"`
+	TotalKeyCount int64              `json:"total_key_count"`
+	Snapshot      *SnapshotReference `json:"snapshot,omitempty"`
 }
 
 func contains(a string, list []string) bool {
@@ -111,6 +117,30 @@ func createInitialStateData(fromVersion string, toVersion string) (err error) {
 		return errors.New("migrate to older versions is not supported")
 	}
 
+	// Validate seed of new v9 state data and node key map, and scan block
+	// archives for creation backfill before starting conversion
+	convertToV9 := stateDBDataToVersionIndex > stateDBDataFromVersionIndex &&
+		stateDBDataVersions[stateDBDataToVersionIndex].ABCIStateVersion == "9"
+	var creationIndex *convert.CreationIndex
+	if convertToV9 {
+		_, err = convert.GetV9Seed()
+		if err != nil {
+			return err
+		}
+		nodeKeyMap, err := convert.GetNodeKeyMap()
+		if err != nil {
+			return err
+		}
+		err = validateNodeKeyMapNodes(fromVersion, nodeKeyMap)
+		if err != nil {
+			return err
+		}
+		creationIndex, err = convert.GetV9CreationIndex()
+		if err != nil {
+			return err
+		}
+	}
+
 	logKeysWritten = viper.GetBool("LOG_KEYS_WRITTEN")
 	logKeysWrittenEvery = viper.GetInt64("LOG_KEYS_WRITTEN_EVERY")
 
@@ -119,11 +149,50 @@ func createInitialStateData(fromVersion string, toVersion string) (err error) {
 	utils.CreateDirIfNotExist(initialStateDataDirectoryPath)
 
 	initialStateDataFilename := viper.GetString("INITIAL_STATE_DATA_FILENAME")
-	// backupValidatorsFilename := viper.GetString("BACKUP_VALIDATORS_FILENAME")
+	backupValidatorsFilename := viper.GetString("BACKUP_VALIDATORS_FILENAME")
 	chainHistoryFilename := viper.GetString("CHAIN_HISTORY_FILENAME")
 	// backupBlockNumberStr := viper.GetString("BLOCK_NUMBER")
 	initialStateMetadataFilename := viper.GetString("METADATA_FILENAME")
 
+	snapshotReference, err := createSnapshot(initialStateDataDirectoryPath)
+	if err != nil {
+		return err
+	}
+
+	transformer, err := newTransformer(
+		initialStateDataDirectoryPath,
+		stateDBDataVersions[stateDBDataToVersionIndex].ABCIStateVersion,
+	)
+	if err != nil {
+		return err
+	}
+	transformerClosed := false
+	defer func() {
+		if !transformerClosed {
+			transformer.Close()
+		}
+	}()
+
+	if creationIndex != nil {
+		backfillReportFile, err := os.Create(path.Join(initialStateDataDirectoryPath, viper.GetString("BACKFILL_REPORT_FILENAME")))
+		if err != nil {
+			return err
+		}
+		defer backfillReportFile.Close()
+		creationIndex.StartConversion()
+		creationIndex.SetReport(backfillReportFile)
+		defer creationIndex.SetReport(nil)
+		defer creationIndex.LogReportSummary()
+	}
+
+	err = backupValidators(
+		fromVersion,
+		path.Join(initialStateDataDirectoryPath, backupValidatorsFilename),
+	)
+	if err != nil {
+		return err
+	}
+
 	if stateDBDataFromVersionIndex == stateDBDataToVersionIndex {
 		err = createInitStateDataSameVersion(
 			stateDBDataVersions[stateDBDataFromVersionIndex].ABCIStateVersion,
@@ -132,6 +201,8 @@ func createInitialStateData(fromVersion string, toVersion string) (err error) {
 			chainHistoryFilename,
 			initialStateDataFilename,
 			initialStateMetadataFilename,
+			snapshotReference,
+			transformer,
 		)
 		if err != nil {
 			return err
@@ -147,6 +218,8 @@ func createInitialStateData(fromVersion string, toVersion string) (err error) {
 				chainHistoryFilename,
 				initialStateDataFilename,
 				initialStateMetadataFilename,
+				snapshotReference,
+				transformer,
 			)
 			if err != nil {
 				return err
@@ -154,6 +227,13 @@ func createInitialStateData(fromVersion string, toVersion string) (err error) {
 		}
 	}
 
+	// Audit file write error must not be lost
+	transformerClosed = true
+	err = transformer.Close()
+	if err != nil {
+		return err
+	}
+
 	initialStateDataDirectoryAbsolutePath, err := filepath.Abs(initialStateDataDirectoryPath)
 	if err != nil {
 		log.Println("initial state directory:", initialStateDataDirectoryPath)
@@ -166,6 +246,51 @@ func createInitialStateData(fromVersion string, toVersion string) (err error) {
 	return nil
 }
 
+// validateNodeKeyMapNodes checks nodes in key map against source ABCI state DB
+func validateNodeKeyMapNodes(fromVersion string, nodeKeyMap convert.NodeKeyMap) (err error) {
+	if len(nodeKeyMap) == 0 {
+		return nil
+	}
+	abciVersion, err := parseABCIVersion(fromVersion)
+	if err != nil {
+		return err
+	}
+	source, err := openStateDataSource(abciVersion, viper.GetString("ABCI_DB_DIR_PATH"))
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	return convert.ValidateNodeKeyMapNodes(nodeKeyMap, func(key []byte) (value []byte, err error) {
+		value, err = source.Get(key)
+		if errors.Is(err, statedata.ErrNotFound) {
+			return nil, nil
+		}
+		return value, err
+	})
+}
+
+func backupValidators(fromVersion string, backupValidatorsFilePath string) (err error) {
+	tmHome := viper.GetString("TM_HOME")
+	tendermintVersion, ok := abciTendermintVersions[fromVersion]
+	if !ok {
+		return errors.New("unknown Tendermint version of ABCI version " + fromVersion)
+	}
+
+	validatorsBackup, err := loadValidatorsBackup(tendermintVersion, tmHome)
+	if err != nil {
+		return err
+	}
+	err = tendermint.WriteValidatorsBackup(backupValidatorsFilePath, validatorsBackup)
+	if err != nil {
+		return err
+	}
+
+	log.Println("validators written:", len(validatorsBackup.Validators))
+
+	return nil
+}
+
 func createInitStateDataSameVersion(
 	stateVersion string,
 	instanceDirName string,
@@ -173,6 +298,8 @@ func createInitStateDataSameVersion(
 	chainHistoryFilename string,
 	initialStateDataFilename string,
 	initialStateMetadataFilename string,
+	snapshotReference *SnapshotReference,
+	transformer *transform.Transformer,
 ) (err error) {
 	log.Println("processing version:", stateVersion)
 
@@ -230,6 +357,12 @@ func createInitStateDataSameVersion(
 		return nil
 	}
 
+	stateVersionNumber, err := strconv.Atoi(stateVersion)
+	if err != nil {
+		return err
+	}
+	saveKeyValue = transformer.WrapSaveKeyValue(stateVersionNumber, saveKeyValue)
+
 	switch stateVersion {
 	case "7":
 		err = convert.ReadInputStateDBDataV7AndBackup(saveNewChainHistory, saveKeyValue)
@@ -243,6 +376,7 @@ func createInitStateDataSameVersion(
 	// write metadata file
 	var metadata Metadata
 	metadata.TotalKeyCount = initialStateKeyCount
+	metadata.Snapshot = snapshotReference
 	metadataJson, err := json.Marshal(metadata)
 	if err != nil {
 		return err
@@ -266,6 +400,8 @@ func loopConvert(
 	chainHistoryFilename string,
 	initialStateDataFilename string,
 	initialStateMetadataFilename string,
+	snapshotReference *SnapshotReference,
+	transformer *transform.Transformer,
 ) (err error) {
 	log.Println("converting version:", stateDBDataVersions[i], "to version:", stateDBDataVersions[i+1])
 
@@ -386,6 +522,12 @@ func loopConvert(
 		}
 	}
 
+	toStateVersion, err := strconv.Atoi(stateDBDataVersions[i+1].ABCIStateVersion)
+	if err != nil {
+		return err
+	}
+	saveKeyValue = transformer.WrapSaveKeyValue(toStateVersion, saveKeyValue)
+
 	switch stateDBDataVersions[i].ABCIStateVersion {
 	case "1":
 		// TODO: v1 -> v2
@@ -551,6 +693,7 @@ func loopConvert(
 	// write metadata file
 	var metadata Metadata
 	metadata.TotalKeyCount = initialStateKeyCount
+	metadata.Snapshot = snapshotReference
 	metadataJson, err := json.Marshal(metadata)
 	if err != nil {
 		return err
@@ -570,7 +713,7 @@ func cleanup(instanceDirName string) {
 }
 
 var createInitialStateDataCmd = &cobra.Command{
-	Use:   "create-initial-state-data [fromVersion] [toVersion]",
+	Use:   "create-initial-state-data [fromVersion|auto] [toVersion]",
 	Short: "Create initial ABCI state data for InitChain on migration",
 	Args:  cobra.MinimumNArgs(2),
 	PreRun: func(cmd *cobra.Command, args []string) {
@@ -588,9 +731,28 @@ var createInitialStateDataCmd = &cobra.Command{
 		viper.SetDefault("CHAIN_HISTORY_FILENAME", "chain_history")
 		viper.SetDefault("METADATA_FILENAME", "metadata")
 		viper.SetDefault("BLOCK_NUMBER", "")
+		viper.SetDefault("SNAPSHOT", false)
+		viper.SetDefault("SNAPSHOT_DIR", "")
+		viper.SetDefault("SNAPSHOT_MODE", snapshot.ModeAuto)
+		viper.SetDefault("TRANSFORM_RULES_FILE", "")
+		viper.SetDefault("TRANSFORM_DRY_RUN", false)
+		viper.SetDefault("TRANSFORM_AUDIT_FILENAME", "transform_audit")
+		viper.SetDefault("V9_NEW_STATE_SEED_FILE", "")
+		viper.SetDefault("V9_NODE_KEY_MAP_FILE", "")
+		viper.SetDefault("BACKFILL_BLOCK_ARCHIVES", "")
+		viper.SetDefault("BACKFILL_REPORT_FILENAME", "creation_backfill_report")
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return createInitialStateData(args[0], args[1])
+		fromVersion := args[0]
+		if fromVersion == "auto" {
+			var err error
+			fromVersion, err = detectFromVersion()
+			if err != nil {
+				return err
+			}
+			log.Println("detected fromVersion:", fromVersion)
+		}
+		return createInitialStateData(fromVersion, args[1])
 	},
 }
 
@@ -0,0 +1,447 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/ndidplatform/migration-tools/tendermint"
+	tendermint_0_26_4 "github.com/ndidplatform/migration-tools/tendermint/0_26_4"
+	tendermint_0_30_2 "github.com/ndidplatform/migration-tools/tendermint/0_30_2"
+	tendermint_0_32_1 "github.com/ndidplatform/migration-tools/tendermint/0_32_1"
+	tendermint_0_33_2 "github.com/ndidplatform/migration-tools/tendermint/0_33_2"
+	tendermint_0_34_19 "github.com/ndidplatform/migration-tools/tendermint/0_34_19"
+	cometbft_0_37 "github.com/ndidplatform/migration-tools/tendermint/cometbft_0_37"
+	cometbft_0_38 "github.com/ndidplatform/migration-tools/tendermint/cometbft_0_38"
+	"github.com/ndidplatform/migration-tools/utils"
+)
+
+const (
+	confidenceHigh   = "high"
+	confidenceMedium = "medium"
+	confidenceLow    = "low"
+)
+
+var confidenceLevels = map[string]int{
+	confidenceLow:    0,
+	confidenceMedium: 1,
+	confidenceHigh:   2,
+}
+
+var tendermintStateVersionDecoders = []struct {
+	TendermintVersion string
+	Decode            func(stateBytes []byte) (*tendermint.StateVersion, error)
+}{
+	{"0.26.4", tendermint_0_26_4.DecodeStateVersion},
+	{"0.30.2", tendermint_0_30_2.DecodeStateVersion},
+	{"0.32.1", tendermint_0_32_1.DecodeStateVersion},
+	{"0.33.2", tendermint_0_33_2.DecodeStateVersion},
+	{"0.34.19", tendermint_0_34_19.DecodeStateVersion},
+	{"0.37", cometbft_0_37.DecodeStateVersion},
+	{"0.38", cometbft_0_38.DecodeStateVersion},
+}
+
+// Keys (without "kvPairKey:" prefix) which exist only in state DB of a range
+// of ABCI versions. 0 means no bound.
+var abciVersionMarkers = []struct {
+	Name         string
+	KeyPrefix    string
+	EmptyValue   bool
+	SinceVersion int
+	UntilVersion int
+}{
+	{"NodeKey", "NodeKey|", false, 9, 0},
+	{"NodeSupportedFeature", "NodeSupportedFeature|", false, 9, 0},
+	{"SupportedIALList", "SupportedIALList", false, 9, 0},
+	{"SupportedAALList", "SupportedAALList", false, 9, 0},
+	{"Validator", "Validator", false, 8, 0},
+	{"Nonce", "n|", true, 8, 0},
+	{"val:", "val:", false, 0, 7},
+}
+
+var abciKvPairPrefixKey = []byte("kvPairKey:")
+
+type tendermintVersionDetection struct {
+	TendermintVersion string
+	Confidence        string
+	Candidates        []string
+	StateVersion      *tendermint.StateVersion
+}
+
+type abciVersionDetection struct {
+	ABCIVersion     string
+	Confidence      string
+	Candidates      []string
+	KeyCount        int64
+	KvPairKeyCount  int64
+	MarkerKeyCounts map[string]int64
+}
+
+func decodeTendermintStateVersion(
+	decode func(stateBytes []byte) (*tendermint.StateVersion, error),
+	stateBytes []byte,
+) (stateVersion *tendermint.StateVersion, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stateVersion = nil
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return decode(stateBytes)
+}
+
+func minorVersion(version string) string {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+	return parts[0] + "." + parts[1]
+}
+
+// detectTendermintVersion decodes Tendermint state with state decoder of
+// each supported Tendermint version and matches software version saved in
+// state with supported versions
+func detectTendermintVersion(tmHome string) (detection *tendermintVersionDetection, err error) {
+	stateBytes, err := tendermint.LoadStateBytes(tmHome)
+	if err != nil {
+		return nil, err
+	}
+
+	var decodedVersions []string
+	var minorMatchedVersions []string
+	var protocolMatchedVersions []string
+	detection = new(tendermintVersionDetection)
+	for _, decoder := range tendermintStateVersionDecoders {
+		stateVersion, err := decodeTendermintStateVersion(decoder.Decode, stateBytes)
+		if err != nil || stateVersion.ChainID == "" || stateVersion.LastBlockHeight < 0 {
+			log.Printf("Tendermint %s state decoder: failed\n", decoder.TendermintVersion)
+			continue
+		}
+		log.Printf(
+			"Tendermint %s state decoder: OK (software: %s, block protocol: %d)\n",
+			decoder.TendermintVersion,
+			stateVersion.Software,
+			stateVersion.BlockProtocol,
+		)
+		decodedVersions = append(decodedVersions, decoder.TendermintVersion)
+		if stateVersion.Software == decoder.TendermintVersion ||
+			(strings.Count(decoder.TendermintVersion, ".") == 1 &&
+				minorVersion(stateVersion.Software) == decoder.TendermintVersion) {
+			detection.TendermintVersion = decoder.TendermintVersion
+			detection.Confidence = confidenceHigh
+			detection.Candidates = []string{decoder.TendermintVersion}
+			detection.StateVersion = stateVersion
+			return detection, nil
+		}
+		if minorVersion(stateVersion.Software) == minorVersion(decoder.TendermintVersion) {
+			minorMatchedVersions = append(minorMatchedVersions, decoder.TendermintVersion)
+		}
+		if stateVersion.BlockProtocolMatched {
+			protocolMatchedVersions = append(protocolMatchedVersions, decoder.TendermintVersion)
+		}
+		detection.StateVersion = stateVersion
+	}
+
+	switch {
+	case len(minorMatchedVersions) == 1:
+		detection.Candidates = minorMatchedVersions
+		detection.Confidence = confidenceMedium
+	case len(protocolMatchedVersions) == 1:
+		detection.Candidates = protocolMatchedVersions
+		detection.Confidence = confidenceMedium
+	case len(protocolMatchedVersions) > 1:
+		detection.Candidates = protocolMatchedVersions
+		detection.Confidence = confidenceLow
+	case len(decodedVersions) > 0:
+		detection.Candidates = decodedVersions
+		detection.Confidence = confidenceLow
+	default:
+		return nil, errors.New("Tendermint state cannot be decoded by any supported Tendermint version")
+	}
+	detection.TendermintVersion = detection.Candidates[len(detection.Candidates)-1]
+
+	return detection, nil
+}
+
+// detectABCIVersion looks for keys which exist only in state DB of some ABCI
+// versions. Candidates are narrowed down to ABCI versions using detected
+// Tendermint version when tendermintVersion is not empty.
+func detectABCIVersion(
+	dbType string,
+	dbDir string,
+	tendermintVersion string,
+) (detection *abciVersionDetection, err error) {
+	db, err := utils.OpenDBReadOnly("didDB", dbm.BackendType(dbType), dbDir)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	detection = &abciVersionDetection{
+		MarkerKeyCounts: make(map[string]int64),
+	}
+
+	itr, err := db.Iterator(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		key := itr.Key()
+		detection.KeyCount++
+		if bytes.HasPrefix(key, abciKvPairPrefixKey) {
+			detection.KvPairKeyCount++
+			key = bytes.TrimPrefix(key, abciKvPairPrefixKey)
+		}
+		for _, marker := range abciVersionMarkers {
+			if !bytes.HasPrefix(key, []byte(marker.KeyPrefix)) {
+				continue
+			}
+			if marker.EmptyValue && len(itr.Value()) != 0 {
+				continue
+			}
+			detection.MarkerKeyCounts[marker.Name]++
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return nil, err
+	}
+	if detection.KeyCount == 0 {
+		return nil, errors.New("ABCI state DB is empty")
+	}
+
+	var candidates []string
+	for abciVersion, abciTendermintVersion := range abciTendermintVersions {
+		if tendermintVersion == "" || abciTendermintVersion == tendermintVersion {
+			candidates = append(candidates, abciVersion)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no supported ABCI version uses Tendermint %s", tendermintVersion)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return abciVersionNumber(candidates[i]) < abciVersionNumber(candidates[j])
+	})
+
+	markerMatched := false
+	for _, marker := range abciVersionMarkers {
+		if detection.MarkerKeyCounts[marker.Name] == 0 {
+			continue
+		}
+		var filtered []string
+		for _, candidate := range candidates {
+			version := abciVersionNumber(candidate)
+			if (marker.SinceVersion == 0 || version >= marker.SinceVersion) &&
+				(marker.UntilVersion == 0 || version <= marker.UntilVersion) {
+				filtered = append(filtered, candidate)
+			}
+		}
+		if len(filtered) == 0 {
+			return nil, fmt.Errorf(
+				"ABCI state DB has %s keys which do not exist in ABCI versions %v",
+				marker.Name,
+				candidates,
+			)
+		}
+		if len(filtered) < len(candidates) {
+			markerMatched = true
+		}
+		candidates = filtered
+	}
+
+	// ABCI versions since which marker keys are introduced are unlikely when
+	// none of those keys is found
+	markerAbsent := false
+	for _, marker := range abciVersionMarkers {
+		if marker.SinceVersion == 0 || hasMarkerKeysSince(marker.SinceVersion, detection.MarkerKeyCounts) {
+			continue
+		}
+		var filtered []string
+		for _, candidate := range candidates {
+			if abciVersionNumber(candidate) < marker.SinceVersion {
+				filtered = append(filtered, candidate)
+			}
+		}
+		if len(filtered) > 0 && len(filtered) < len(candidates) {
+			markerAbsent = true
+			candidates = filtered
+		}
+	}
+
+	detection.Candidates = candidates
+	detection.ABCIVersion = candidates[len(candidates)-1]
+	switch {
+	case !isSameStateDBDataVersion(candidates):
+		detection.Confidence = confidenceLow
+	case markerMatched && !markerAbsent:
+		detection.Confidence = confidenceHigh
+	default:
+		detection.Confidence = confidenceMedium
+	}
+
+	return detection, nil
+}
+
+func abciVersionNumber(abciVersion string) int {
+	version, _ := strconv.Atoi(abciVersion)
+	return version
+}
+
+func hasMarkerKeysSince(sinceVersion int, markerKeyCounts map[string]int64) bool {
+	for _, marker := range abciVersionMarkers {
+		if marker.SinceVersion == sinceVersion && markerKeyCounts[marker.Name] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isSameStateDBDataVersion returns true when state DB data structure of all
+// ABCI versions are the same (e.g. v7 and v8), so any of them can be used
+func isSameStateDBDataVersion(abciVersions []string) bool {
+	for _, stateDBDataVersion := range stateDBDataVersions {
+		if contains(abciVersions[0], stateDBDataVersion.ABCIAppVersions) {
+			for _, abciVersion := range abciVersions {
+				if !contains(abciVersion, stateDBDataVersion.ABCIAppVersions) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func detectVersions() (
+	tendermintDetection *tendermintVersionDetection,
+	abciDetection *abciVersionDetection,
+	confidence string,
+	err error,
+) {
+	tmHome := viper.GetString("TM_HOME")
+	dbType := viper.GetString("ABCI_DB_TYPE")
+	dbDir := viper.GetString("ABCI_DB_DIR_PATH")
+
+	tendermintDetection, err = detectTendermintVersion(tmHome)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	tendermintVersion := ""
+	if tendermintDetection.Confidence != confidenceLow {
+		tendermintVersion = tendermintDetection.TendermintVersion
+	}
+	abciDetection, err = detectABCIVersion(dbType, dbDir, tendermintVersion)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if tendermintVersion == "" {
+		abciTendermintVersion := abciTendermintVersions[abciDetection.ABCIVersion]
+		if abciDetection.Confidence != confidenceLow &&
+			contains(abciTendermintVersion, tendermintDetection.Candidates) {
+			tendermintDetection.TendermintVersion = abciTendermintVersion
+		}
+	}
+
+	confidence = tendermintDetection.Confidence
+	if confidenceLevels[abciDetection.Confidence] < confidenceLevels[confidence] {
+		confidence = abciDetection.Confidence
+	}
+
+	log.Println("===== Detected Versions =====")
+	if tendermintDetection.StateVersion != nil {
+		log.Println("Tendermint software version in state:", tendermintDetection.StateVersion.Software)
+		log.Println("Tendermint block protocol in state:", tendermintDetection.StateVersion.BlockProtocol)
+		log.Println("Chain ID:", tendermintDetection.StateVersion.ChainID)
+	}
+	log.Printf(
+		"Tendermint version: %s (confidence: %s, candidates: %v)\n",
+		tendermintDetection.TendermintVersion,
+		tendermintDetection.Confidence,
+		tendermintDetection.Candidates,
+	)
+	log.Println("ABCI state DB key count:", abciDetection.KeyCount)
+	log.Println("ABCI state DB key count with kvPairKey: prefix:", abciDetection.KvPairKeyCount)
+	log.Println("ABCI state DB version marker key counts:", abciDetection.MarkerKeyCounts)
+	log.Printf(
+		"ABCI version: %s (confidence: %s, candidates: %v)\n",
+		abciDetection.ABCIVersion,
+		abciDetection.Confidence,
+		abciDetection.Candidates,
+	)
+	log.Printf(
+		"Detected: ABCI version %s with Tendermint %s (confidence: %s)\n",
+		abciDetection.ABCIVersion,
+		tendermintDetection.TendermintVersion,
+		confidence,
+	)
+
+	return tendermintDetection, abciDetection, confidence, nil
+}
+
+// detectFromVersion detects ABCI version of source data for "auto"
+// fromVersion. Low confidence result is refused.
+func detectFromVersion() (fromVersion string, err error) {
+	_, abciDetection, confidence, err := detectVersions()
+	if err != nil {
+		return "", err
+	}
+	if confidence == confidenceLow {
+		return "", fmt.Errorf(
+			"cannot detect ABCI version with enough confidence (candidates: %v), please specify fromVersion",
+			abciDetection.Candidates,
+		)
+	}
+	return abciDetection.ABCIVersion, nil
+}
+
+var detectCmd = &cobra.Command{
+	Use:   "detect",
+	Short: "Detect ABCI and Tendermint versions of source data",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		curDir, _ := os.Getwd()
+		viper.SetDefault("TM_HOME", path.Join(curDir, "../smart-contract/config/tendermint/IdP"))
+
+		viper.SetDefault("ABCI_DB_TYPE", "goleveldb")
+		viper.SetDefault("ABCI_DB_DIR_PATH", path.Join(curDir, "../smart-contract/DB1"))
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, _, _, err := detectVersions()
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(detectCmd)
+}
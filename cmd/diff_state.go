@@ -0,0 +1,185 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/ndidplatform/migration-tools/statedata"
+)
+
+// convertStateDataSource copies state data at sourcePath to a LevelDB in
+// dbDir converted to toVersion
+func convertStateDataSource(
+	abciVersion int,
+	sourcePath string,
+	toVersion int,
+	dbDir string,
+) (db *leveldb.DB, err error) {
+	source, err := openStateDataSource(abciVersion, sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	log.Println("reading state data:", sourcePath, "version:", abciVersion)
+	return statedata.ConvertSource(source, abciVersion, toVersion, dbDir)
+}
+
+func diffState(
+	versionA string,
+	sourcePathA string,
+	versionB string,
+	sourcePathB string,
+	outputPath string,
+	ignorePrefixes []string,
+) (err error) {
+	startTime := time.Now()
+
+	abciVersionA, err := parseABCIVersion(versionA)
+	if err != nil {
+		return err
+	}
+	abciVersionB, err := parseABCIVersion(versionB)
+	if err != nil {
+		return err
+	}
+	// State data of older version is converted to newer version before compare
+	toVersion := abciVersionA
+	if abciVersionB > toVersion {
+		toVersion = abciVersionB
+	}
+
+	tempDir, err := os.MkdirTemp("", "ndid-diff-state-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	dbA, err := convertStateDataSource(abciVersionA, sourcePathA, toVersion, path.Join(tempDir, "a"))
+	if err != nil {
+		return err
+	}
+	defer dbA.Close()
+	dbB, err := convertStateDataSource(abciVersionB, sourcePathB, toVersion, path.Join(tempDir, "b"))
+	if err != nil {
+		return err
+	}
+	defer dbB.Close()
+
+	var output io.Writer = os.Stdout
+	if outputPath != "" {
+		outputFile, err := os.Create(outputPath)
+		if err != nil {
+			return err
+		}
+		defer outputFile.Close()
+		output = outputFile
+	}
+	outputWriter := bufio.NewWriter(output)
+
+	summary, err := statedata.Diff(dbA, dbB, toVersion, ignorePrefixes, func(entry *statedata.DiffEntry) error {
+		entryJSON, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		_, err = outputWriter.Write(append(entryJSON, '\n'))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = outputWriter.Flush()
+	if err != nil {
+		return err
+	}
+
+	prefixes := make([]string, 0, len(summary.ByPrefix))
+	for prefix := range summary.ByPrefix {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	log.Println("===== Diff State Summary =====")
+	log.Println("compared as ABCI version:", toVersion)
+	log.Println("unchanged key count:", summary.Unchanged)
+	log.Println("added key count:", summary.Added)
+	log.Println("removed key count:", summary.Removed)
+	log.Println("changed key count:", summary.Changed)
+	for _, prefix := range prefixes {
+		counts := summary.ByPrefix[prefix]
+		if counts.Added == 0 && counts.Removed == 0 && counts.Changed == 0 {
+			continue
+		}
+		log.Printf(
+			"prefix: %s unchanged: %d added: %d removed: %d changed: %d\n",
+			prefix,
+			counts.Unchanged,
+			counts.Added,
+			counts.Removed,
+			counts.Changed,
+		)
+	}
+	log.Println("time used:", time.Since(startTime))
+
+	return nil
+}
+
+var diffStateCmd = &cobra.Command{
+	Use:   "diff-state [versionA] [pathA] [versionB] [pathB]",
+	Short: "Compare ABCI state data of two state DBs or initial state data files",
+	Long: `Compare ABCI state data A (old) and B (new). Path is ABCI state DB directory or initial state data file.
+State data of older version is converted to the newer version before comparing.
+Added, removed and changed keys are written as JSON lines with field-level differences of decoded values.`,
+	Args: cobra.ExactArgs(4),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.SetDefault("ABCI_DB_TYPE", "goleveldb")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputPath, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		ignorePrefixes, err := cmd.Flags().GetStringSlice("ignore-prefix")
+		if err != nil {
+			return err
+		}
+		return diffState(args[0], args[1], args[2], args[3], outputPath, ignorePrefixes)
+	},
+}
+
+func init() {
+	diffStateCmd.Flags().StringP("output", "o", "", "JSON lines output file path (default stdout)")
+	diffStateCmd.Flags().StringSlice("ignore-prefix", nil, "key prefix to skip (e.g. \"stateKey\"), can be repeated")
+	rootCmd.AddCommand(diffStateCmd)
+}
@@ -44,6 +44,7 @@ func endInit(version string) (err error) {
 
 	tendermintRPCHost := viper.GetString("TENDERMINT_RPC_HOST")
 	tendermintRPCPort := viper.GetString("TENDERMINT_RPC_PORT")
+	tendermintRPCConnectionConfig := tendermintRPCConnectionConfig()
 
 	switch version {
 	case "7", "8":
@@ -53,6 +54,7 @@ func endInit(version string) (err error) {
 			keyDir,
 			tendermintRPCHost,
 			tendermintRPCPort,
+			tendermintRPCConnectionConfig,
 		)
 	case "9":
 		err = v9.EndInit(
@@ -61,6 +63,7 @@ func endInit(version string) (err error) {
 			keyDir,
 			tendermintRPCHost,
 			tendermintRPCPort,
+			tendermintRPCConnectionConfig,
 		)
 	default:
 		return errors.New("unsupported ABCI version")
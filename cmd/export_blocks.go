@@ -0,0 +1,186 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ndidplatform/migration-tools/blockarchive"
+	"github.com/ndidplatform/migration-tools/tendermint"
+	tendermint_0_26_4 "github.com/ndidplatform/migration-tools/tendermint/0_26_4"
+	tendermint_0_30_2 "github.com/ndidplatform/migration-tools/tendermint/0_30_2"
+	tendermint_0_32_1 "github.com/ndidplatform/migration-tools/tendermint/0_32_1"
+	tendermint_0_33_2 "github.com/ndidplatform/migration-tools/tendermint/0_33_2"
+	tendermint_0_34_19 "github.com/ndidplatform/migration-tools/tendermint/0_34_19"
+	cometbft_0_37 "github.com/ndidplatform/migration-tools/tendermint/cometbft_0_37"
+	cometbft_0_38 "github.com/ndidplatform/migration-tools/tendermint/cometbft_0_38"
+)
+
+var tendermintBlockCodecs = map[string]tendermint.BlockCodec{
+	tendermint_0_26_4.TendermintVersion:  tendermint_0_26_4.BlockCodec{},
+	tendermint_0_30_2.TendermintVersion:  tendermint_0_30_2.BlockCodec{},
+	tendermint_0_32_1.TendermintVersion:  tendermint_0_32_1.BlockCodec{},
+	tendermint_0_33_2.TendermintVersion:  tendermint_0_33_2.BlockCodec{},
+	tendermint_0_34_19.TendermintVersion: tendermint_0_34_19.BlockCodec{},
+	cometbft_0_37.TendermintVersion:      cometbft_0_37.BlockCodec{},
+	cometbft_0_38.TendermintVersion:      cometbft_0_38.BlockCodec{},
+}
+
+func exportBlocks(tendermintVersion string) (err error) {
+	startTime := time.Now()
+
+	tmHome := viper.GetString("TM_HOME")
+	fromHeight := viper.GetInt64("EXPORT_BLOCKS_FROM_HEIGHT")
+	toHeight := viper.GetInt64("EXPORT_BLOCKS_TO_HEIGHT")
+	logBlocksExportedEvery := viper.GetInt64("LOG_BLOCKS_EXPORTED_EVERY")
+
+	tendermintVersion, err = resolveTendermintVersion(tmHome, tendermintVersion)
+	if err != nil {
+		return err
+	}
+	blockCodec, ok := tendermintBlockCodecs[tendermintVersion]
+	if !ok {
+		return errors.New("unsupported Tendermint version")
+	}
+
+	blockReader, err := tendermint.OpenBlockReader(tmHome, blockCodec)
+	if err != nil {
+		return err
+	}
+	defer blockReader.Close()
+
+	if blockReader.Height() == 0 {
+		return errors.New("no block in block store")
+	}
+	if fromHeight == 0 {
+		fromHeight = blockReader.Base()
+	}
+	if toHeight == 0 {
+		toHeight = blockReader.Height()
+	}
+	if fromHeight < blockReader.Base() || toHeight > blockReader.Height() || fromHeight > toHeight {
+		return fmt.Errorf(
+			"invalid block height range %d-%d, block store range: %d-%d",
+			fromHeight,
+			toHeight,
+			blockReader.Base(),
+			blockReader.Height(),
+		)
+	}
+
+	firstBlock, err := blockReader.LoadBlock(fromHeight)
+	if err != nil {
+		return err
+	}
+	chainID := firstBlock.Meta.ChainID
+
+	archiveDir := path.Join(
+		viper.GetString("BLOCK_ARCHIVE_DIR"),
+		fmt.Sprintf("%s_%d_%d", chainID, fromHeight, toHeight),
+	)
+	log.Println("Tendermint version:", tendermintVersion)
+	log.Println("chain ID:", chainID)
+	log.Printf("exporting blocks: %d-%d\n", fromHeight, toHeight)
+
+	archiveWriter, err := blockarchive.Create(archiveDir, blockarchive.Info{
+		TendermintVersion: tendermintVersion,
+		BlockEncoding:     blockReader.BlockEncoding(),
+		ChainID:           chainID,
+	})
+	if err != nil {
+		return err
+	}
+
+	for height := fromHeight; height <= toHeight; height++ {
+		block := firstBlock
+		if height != fromHeight {
+			block, err = blockReader.LoadBlock(height)
+			if err != nil {
+				archiveWriter.Close()
+				return err
+			}
+		}
+		if block.Meta.ChainID != chainID {
+			archiveWriter.Close()
+			return fmt.Errorf("unexpected chain ID %s at height %d", block.Meta.ChainID, height)
+		}
+		err = archiveWriter.WriteBlock(blockarchive.NewBlockRecord(block))
+		if err != nil {
+			archiveWriter.Close()
+			return err
+		}
+		exportedCount := height - fromHeight + 1
+		if logBlocksExportedEvery > 0 && exportedCount%logBlocksExportedEvery == 0 {
+			log.Println("blocks exported:", exportedCount)
+		}
+	}
+
+	err = archiveWriter.Close()
+	if err != nil {
+		return err
+	}
+	archiveInfo := archiveWriter.Info()
+
+	archiveAbsoluteDir, err := filepath.Abs(archiveDir)
+	if err != nil {
+		archiveAbsoluteDir = archiveDir
+	}
+	log.Println("block archive directory:", archiveAbsoluteDir)
+	log.Println("blocks exported:", archiveInfo.BlockCount)
+	log.Println("txs exported:", archiveInfo.TxCount)
+	log.Println("export blocks done")
+	log.Println("time used:", time.Since(startTime))
+
+	return nil
+}
+
+var exportBlocksCmd = &cobra.Command{
+	Use:   "export-blocks [tendermintVersion|auto]",
+	Short: "Export Tendermint blocks, block metas and ABCI responses to a block archive",
+	Args:  cobra.MinimumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		curDir, _ := os.Getwd()
+		viper.SetDefault("TM_HOME", path.Join(curDir, "../smart-contract/config/tendermint/IdP"))
+
+		viper.SetDefault("BLOCK_ARCHIVE_DIR", "./_block_archive/")
+		viper.SetDefault("EXPORT_BLOCKS_FROM_HEIGHT", 0)
+		viper.SetDefault("EXPORT_BLOCKS_TO_HEIGHT", 0)
+		viper.SetDefault("LOG_BLOCKS_EXPORTED_EVERY", 10000)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return exportBlocks(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportBlocksCmd)
+}
@@ -0,0 +1,205 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ndidplatform/migration-tools/statedata"
+	"github.com/ndidplatform/migration-tools/utils"
+)
+
+func exportSample(version string, dataFilePath string, outputDirPath string, options statedata.SampleOptions) (err error) {
+	startTime := time.Now()
+
+	abciVersion, err := parseABCIVersion(version)
+	if err != nil {
+		return err
+	}
+	if options.Salt == "" {
+		options.Salt = viper.GetString("EXPORT_SAMPLE_SALT")
+	}
+	if options.Salt == "" {
+		return errors.New("salt is required (--salt or EXPORT_SAMPLE_SALT)")
+	}
+	if options.RefGroupCount < 0 || options.RequestCount < 0 {
+		return errors.New("sample count must not be negative")
+	}
+
+	if outputDirPath == "" {
+		return errors.New("output directory is required (--output)")
+	}
+	// Do not overwrite existing initial state data
+	entries, err := os.ReadDir(outputDirPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("output directory is not empty: %s", outputDirPath)
+	}
+	err = os.MkdirAll(outputDirPath, 0755)
+	if err != nil {
+		return err
+	}
+
+	initialStateDataFilename := viper.GetString("INITIAL_STATE_DATA_FILENAME")
+	chainHistoryFilename := viper.GetString("CHAIN_HISTORY_FILENAME")
+	initialStateMetadataFilename := viper.GetString("METADATA_FILENAME")
+
+	source, err := openStateDataSource(abciVersion, stateDataSourcePath(dataFilePath))
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	initialStateDataFile, err := utils.OpenFileForAppend(path.Join(outputDirPath, initialStateDataFilename))
+	if err != nil {
+		return err
+	}
+	defer initialStateDataFile.Close()
+
+	var initialStateKeyCount int64 = 0
+	save := func(key []byte, value []byte) (err error) {
+		var kv KeyValue
+		kv.Key = key
+		kv.Value = value
+		jsonStr, err := json.Marshal(kv)
+		if err != nil {
+			return err
+		}
+		err = utils.AppendLineToOpenedFile(initialStateDataFile, jsonStr)
+		if err != nil {
+			return err
+		}
+		initialStateKeyCount++
+		return nil
+	}
+	var chainHistory []byte
+	saveChainHistory := func(value []byte) (err error) {
+		chainHistory = value
+		return nil
+	}
+
+	report, err := statedata.Sample(source, abciVersion, options, save, saveChainHistory)
+	if err != nil {
+		return err
+	}
+
+	if chainHistory == nil && dataFilePath != "" {
+		// Chain history of initial state data is in a separate file
+		chainHistory, err = os.ReadFile(filepath.Join(filepath.Dir(dataFilePath), chainHistoryFilename))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if chainHistory == nil {
+		log.Println("chain history not found in source, writing empty chain history")
+		chainHistory = []byte(`{"chains":[]}`)
+	}
+	err = utils.AppendLineToFile(path.Join(outputDirPath, chainHistoryFilename), chainHistory)
+	if err != nil {
+		return err
+	}
+
+	// write metadata file
+	var metadata Metadata
+	metadata.TotalKeyCount = initialStateKeyCount
+	metadataJson, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path.Join(outputDirPath, initialStateMetadataFilename), metadataJson, 0644)
+	if err != nil {
+		return err
+	}
+
+	log.Println("===== Export Sample Summary =====")
+	log.Println("source key count:", report.KeyCount)
+	log.Println("sampled reference group count:", report.RefGroupCount)
+	log.Println("sampled identity count:", report.IdentityCount)
+	log.Println("sampled accessor count:", report.AccessorCount)
+	log.Println("sampled request count:", report.RequestCount)
+	log.Println("total initial state key count:", initialStateKeyCount)
+	log.Println("output directory:", outputDirPath)
+	log.Println("time used:", time.Since(startTime))
+
+	return nil
+}
+
+var exportSampleCmd = &cobra.Command{
+	Use:   "export-sample [version]",
+	Short: "Export sampled and pseudonymised initial state data from ABCI state DB or initial state data (v9)",
+	Args:  cobra.ExactArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		curDir, _ := os.Getwd()
+		viper.SetDefault("ABCI_DB_TYPE", "goleveldb")
+		viper.SetDefault("ABCI_DB_DIR_PATH", path.Join(curDir, "../smart-contract/DB1"))
+
+		viper.SetDefault("INITIAL_STATE_DATA_FILENAME", "data")
+		viper.SetDefault("CHAIN_HISTORY_FILENAME", "chain_history")
+		viper.SetDefault("METADATA_FILENAME", "metadata")
+		viper.SetDefault("EXPORT_SAMPLE_SALT", "")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dataFilePath, err := cmd.Flags().GetString("data-file")
+		if err != nil {
+			return err
+		}
+		outputDirPath, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		var options statedata.SampleOptions
+		options.RefGroupCount, err = cmd.Flags().GetInt("ref-groups")
+		if err != nil {
+			return err
+		}
+		options.RequestCount, err = cmd.Flags().GetInt("requests")
+		if err != nil {
+			return err
+		}
+		options.Salt, err = cmd.Flags().GetString("salt")
+		if err != nil {
+			return err
+		}
+		return exportSample(args[0], dataFilePath, outputDirPath, options)
+	},
+}
+
+func init() {
+	exportSampleCmd.Flags().String("data-file", "", "initial state data file to read instead of ABCI state DB")
+	exportSampleCmd.Flags().StringP("output", "o", "", "output directory of sampled initial state data (must be empty)")
+	exportSampleCmd.Flags().Int("ref-groups", 100, "number of reference groups to sample")
+	exportSampleCmd.Flags().Int("requests", 100, "number of requests to sample")
+	exportSampleCmd.Flags().String("salt", "", "salt of pseudonyms and sampling (default EXPORT_SAMPLE_SALT)")
+	rootCmd.AddCommand(exportSampleCmd)
+}
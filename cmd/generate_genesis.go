@@ -0,0 +1,181 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	v9 "github.com/ndidplatform/migration-tools/did/v9"
+	"github.com/ndidplatform/migration-tools/tendermint"
+	tendermint_0_34_19 "github.com/ndidplatform/migration-tools/tendermint/0_34_19"
+	tmtypes "github.com/ndidplatform/migration-tools/tendermint/0_34_19/types"
+)
+
+func generateGenesis() (err error) {
+	startTime := time.Now()
+
+	initialStateDataDir := viper.GetString("INITIAL_STATE_DATA_DIR")
+	initialStateDataFileName := viper.GetString("INITIAL_STATE_DATA_FILENAME")
+	backupValidatorsFilename := viper.GetString("BACKUP_VALIDATORS_FILENAME")
+	chainHistoryFileName := viper.GetString("CHAIN_HISTORY_FILENAME")
+	abciInitialStateDirPath := viper.GetString("ABCI_INITIAL_STATE_DIR_PATH")
+	if abciInitialStateDirPath == "" {
+		abciInitialStateDirPath = initialStateDataDir
+	}
+	newChainID := viper.GetString("NEW_CHAIN_ID")
+	genesisTimeStr := viper.GetString("GENESIS_TIME")
+	genesisOutputFilepath := viper.GetString("GENESIS_OUTPUT_FILEPATH")
+	if genesisOutputFilepath == "" {
+		genesisOutputFilepath = path.Join(initialStateDataDir, "genesis.json")
+	}
+
+	validatorsBackup, err := tendermint.ReadValidatorsBackup(path.Join(initialStateDataDir, backupValidatorsFilename))
+	if err != nil {
+		return err
+	}
+
+	if newChainID == "" {
+		oldChainID, err := getLatestChainID(path.Join(initialStateDataDir, chainHistoryFileName))
+		if err != nil {
+			return err
+		}
+		if oldChainID == "" {
+			oldChainID = validatorsBackup.ChainID
+		}
+		if oldChainID == "" {
+			return errors.New("cannot derive new chain ID, set NEW_CHAIN_ID")
+		}
+		newChainID = tendermint_0_34_19.DeriveChainID(oldChainID)
+		log.Printf("new chain ID derived from old chain ID %s\n", oldChainID)
+	}
+
+	genesisTime := time.Now().UTC()
+	if genesisTimeStr != "" {
+		genesisTime, err = time.Parse(time.RFC3339Nano, genesisTimeStr)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Fingerprint of initial state data the new chain is started with, logged
+	// only (app hash in genesis must be empty or equal to app hash returned by
+	// ABCI InitChain)
+	initialStateDataHash, err := hashFile(path.Join(abciInitialStateDirPath, initialStateDataFileName))
+	if err != nil {
+		return err
+	}
+
+	genesisDoc, err := tendermint_0_34_19.GenerateGenesis(
+		validatorsBackup,
+		newChainID,
+		genesisTime,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = genesisDoc.SaveAs(genesisOutputFilepath)
+	if err != nil {
+		return err
+	}
+
+	// Make sure written file can be loaded by Tendermint
+	_, err = tmtypes.GenesisDocFromFile(genesisOutputFilepath)
+	if err != nil {
+		return err
+	}
+
+	log.Println("===== Genesis =====")
+	log.Println("chain ID:", genesisDoc.ChainID)
+	log.Println("genesis time:", genesisDoc.GenesisTime.Format(time.RFC3339Nano))
+	log.Println("validator count:", len(genesisDoc.Validators))
+	log.Println("initial state data SHA-256:", strings.ToUpper(hex.EncodeToString(initialStateDataHash)))
+	log.Println("genesis file:", genesisOutputFilepath)
+	log.Println("time used:", time.Since(startTime))
+
+	return nil
+}
+
+func getLatestChainID(chainHistoryFilePath string) (chainID string, err error) {
+	chainHistoryData, err := os.ReadFile(chainHistoryFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	var chainHistory v9.ChainHistory
+	err = json.Unmarshal(chainHistoryData, &chainHistory)
+	if err != nil {
+		return "", err
+	}
+	if len(chainHistory.Chains) == 0 {
+		return "", nil
+	}
+	return chainHistory.Chains[len(chainHistory.Chains)-1].ChainID, nil
+}
+
+func hashFile(filePath string) (hash []byte, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, file)
+	if err != nil {
+		return nil, err
+	}
+	return hasher.Sum(nil), nil
+}
+
+var generateGenesisCmd = &cobra.Command{
+	Use:   "generate-genesis",
+	Short: "Generate Tendermint 0.34 genesis.json of new chain from validators backup",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.SetDefault("INITIAL_STATE_DATA_DIR", "./_initial_state_data/")
+		viper.SetDefault("INITIAL_STATE_DATA_FILENAME", "data")
+		viper.SetDefault("BACKUP_VALIDATORS_FILENAME", "validators")
+		viper.SetDefault("CHAIN_HISTORY_FILENAME", "chain_history")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return generateGenesis()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(generateGenesisCmd)
+}
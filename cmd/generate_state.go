@@ -0,0 +1,165 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/ndidplatform/migration-tools/statedata"
+)
+
+const generateStateBatchSize = 10000
+
+func generateState(version string, outputDirPath string, options statedata.GenerateOptions) (err error) {
+	startTime := time.Now()
+
+	abciVersion, err := parseABCIVersion(version)
+	if err != nil {
+		return err
+	}
+	if outputDirPath == "" {
+		return errors.New("output directory is required (--output)")
+	}
+	// Do not write to existing state DB
+	entries, err := os.ReadDir(outputDirPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("output directory is not empty: %s", outputDirPath)
+	}
+
+	db, err := dbm.NewGoLevelDB("didDB", outputDirPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	batch := db.NewBatch()
+	defer func() {
+		batch.Close()
+	}()
+	var batchKeyCount int
+	save := func(key []byte, value []byte) (err error) {
+		err = batch.Set(key, value)
+		if err != nil {
+			return err
+		}
+		batchKeyCount++
+		if batchKeyCount < generateStateBatchSize {
+			return nil
+		}
+		err = batch.Write()
+		if err != nil {
+			return err
+		}
+		batch.Close()
+		batch = db.NewBatch()
+		batchKeyCount = 0
+		return nil
+	}
+
+	report, err := statedata.Generate(abciVersion, options, save)
+	if err != nil {
+		return err
+	}
+	err = batch.WriteSync()
+	if err != nil {
+		return err
+	}
+
+	log.Println("===== Generate State Summary =====")
+	log.Println("ABCI version:", abciVersion)
+	log.Println("total key count:", report.KeyCount)
+	log.Println("key count by prefix:", report.KeyCounts)
+	log.Println("state DB directory:", outputDirPath)
+	log.Println("time used:", time.Since(startTime))
+
+	return nil
+}
+
+var generateStateCmd = &cobra.Command{
+	Use:   "generate-state [version]",
+	Short: "Generate ABCI state DB with synthetic state data (v7, v8, v9)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputDirPath, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		var options statedata.GenerateOptions
+		for _, flag := range []struct {
+			name  string
+			value *int
+		}{
+			{"nodes", &options.NodeCount},
+			{"services", &options.ServiceCount},
+			{"ref-groups", &options.RefGroupCount},
+			{"accessors", &options.AccessorCount},
+			{"requests", &options.RequestCount},
+			{"request-versions", &options.RequestVersionCount},
+			{"tokens", &options.TokenCount},
+			{"service-prices", &options.ServicePriceCount},
+		} {
+			*flag.value, err = cmd.Flags().GetInt(flag.name)
+			if err != nil {
+				return err
+			}
+		}
+		options.Seed, err = cmd.Flags().GetInt64("seed")
+		if err != nil {
+			return err
+		}
+		options.ChainID, err = cmd.Flags().GetString("chain-id")
+		if err != nil {
+			return err
+		}
+		options.BlockHeight, err = cmd.Flags().GetInt64("height")
+		if err != nil {
+			return err
+		}
+		return generateState(args[0], outputDirPath, options)
+	},
+}
+
+func init() {
+	generateStateCmd.Flags().StringP("output", "o", "", "output directory of generated state DB (must be empty)")
+	generateStateCmd.Flags().Int("nodes", 9, "number of nodes other than NDID (IdP, RP and AS in turn)")
+	generateStateCmd.Flags().Int("services", 3, "number of services (provided by every AS)")
+	generateStateCmd.Flags().Int("ref-groups", 100, "number of reference groups")
+	generateStateCmd.Flags().Int("accessors", 1, "number of accessors of each IdP in each reference group")
+	generateStateCmd.Flags().Int("requests", 100, "number of requests")
+	generateStateCmd.Flags().Int("request-versions", 3, "number of versions of each request")
+	generateStateCmd.Flags().Int("tokens", 9, "number of nodes with token")
+	generateStateCmd.Flags().Int("service-prices", 2, "number of prices of each service of each AS")
+	generateStateCmd.Flags().Int64("seed", 1, "seed of generated IDs and values")
+	generateStateCmd.Flags().String("chain-id", "generated-chain", "chain ID of creation of requests, accessors, node keys and service prices")
+	generateStateCmd.Flags().Int64("height", 1000, "block height of ABCI state (creation block heights are up to this height)")
+	rootCmd.AddCommand(generateStateCmd)
+}
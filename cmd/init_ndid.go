@@ -45,6 +45,7 @@ func initNdid(version string) (err error) {
 
 	tendermintRPCHost := viper.GetString("TENDERMINT_RPC_HOST")
 	tendermintRPCPort := viper.GetString("TENDERMINT_RPC_PORT")
+	tendermintRPCConnectionConfig := tendermintRPCConnectionConfig()
 
 	initialStateDataDir := viper.GetString("INITIAL_STATE_DATA_DIR")
 	chainHistoryFileName := viper.GetString("CHAIN_HISTORY_FILENAME")
@@ -58,6 +59,7 @@ func initNdid(version string) (err error) {
 			keyDir,
 			tendermintRPCHost,
 			tendermintRPCPort,
+			tendermintRPCConnectionConfig,
 			initialStateDataDir,
 			chainHistoryFileName,
 		)
@@ -69,6 +71,7 @@ func initNdid(version string) (err error) {
 			keyDir,
 			tendermintRPCHost,
 			tendermintRPCPort,
+			tendermintRPCConnectionConfig,
 			initialStateDataDir,
 			chainHistoryFileName,
 		)
@@ -97,12 +100,22 @@ var initNdidCmd = &cobra.Command{
 		viper.SetDefault("KEY_DIR", "./dev_keys/")
 		viper.SetDefault("TENDERMINT_RPC_HOST", "localhost")
 		viper.SetDefault("TENDERMINT_RPC_PORT", "45000")
+		setPreflightDefaults()
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return err
+		}
+		err = preflight(args[0], force)
+		if err != nil {
+			return err
+		}
 		return initNdid(args[0])
 	},
 }
 
 func init() {
+	addPreflightFlags(initNdidCmd)
 	rootCmd.AddCommand(initNdidCmd)
 }
@@ -0,0 +1,254 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	dbm "github.com/tendermint/tm-db"
+
+	v2 "github.com/ndidplatform/migration-tools/did/v2"
+	v3 "github.com/ndidplatform/migration-tools/did/v3"
+	v4 "github.com/ndidplatform/migration-tools/did/v4"
+	v6 "github.com/ndidplatform/migration-tools/did/v6"
+	v7 "github.com/ndidplatform/migration-tools/did/v7"
+	v8 "github.com/ndidplatform/migration-tools/did/v8"
+	v9 "github.com/ndidplatform/migration-tools/did/v9"
+	"github.com/ndidplatform/migration-tools/statedata"
+)
+
+var abciStateDBOpeners = map[int]func(dbType string, dbDir string) (dbm.DB, error){
+	2: func(dbType string, dbDir string) (dbm.DB, error) { return v2.GetStateDB(dbType, dbDir) },
+	3: func(dbType string, dbDir string) (dbm.DB, error) { return v3.GetStateDB(dbType, dbDir) },
+	4: func(dbType string, dbDir string) (dbm.DB, error) { return v4.GetStateDB(dbType, dbDir) },
+	6: func(dbType string, dbDir string) (dbm.DB, error) { return v6.GetStateDB(dbType, dbDir) },
+	7: func(dbType string, dbDir string) (dbm.DB, error) { return v7.GetStateDB(dbType, dbDir) },
+	8: func(dbType string, dbDir string) (dbm.DB, error) { return v8.GetStateDB(dbType, dbDir) },
+	9: func(dbType string, dbDir string) (dbm.DB, error) { return v9.GetStateDB(dbType, dbDir) },
+}
+
+func parseABCIVersion(version string) (abciVersion int, err error) {
+	abciVersion, err = strconv.Atoi(version)
+	if err != nil || abciVersion < statedata.MinABCIVersion || abciVersion > statedata.MaxABCIVersion {
+		return 0, fmt.Errorf("unsupported ABCI version: %s", version)
+	}
+	return abciVersion, nil
+}
+
+// openStateDataSource opens initial state data file or ABCI state DB
+// (read-only) of given version when sourcePath is a directory.
+func openStateDataSource(abciVersion int, sourcePath string) (source statedata.Source, err error) {
+	sourceInfo, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	if !sourceInfo.IsDir() {
+		return statedata.OpenFileSource(sourcePath)
+	}
+	openStateDB, ok := abciStateDBOpeners[abciVersion]
+	if !ok {
+		return nil, fmt.Errorf("reading state DB of ABCI version %d is not supported, use initial state data file", abciVersion)
+	}
+	db, err := openStateDB(viper.GetString("ABCI_DB_TYPE"), sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	return statedata.NewDBSource(db), nil
+}
+
+// stateDataSourcePath returns initial state data file path if set, otherwise
+// ABCI state DB directory path
+func stateDataSourcePath(dataFilePath string) string {
+	if dataFilePath != "" {
+		return dataFilePath
+	}
+	return viper.GetString("ABCI_DB_DIR_PATH")
+}
+
+func printJSON(v interface{}, indent bool) (err error) {
+	var b []byte
+	if indent {
+		b, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		b, err = json.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func inspectGet(version string, dataFilePath string, key string) (err error) {
+	abciVersion, err := parseABCIVersion(version)
+	if err != nil {
+		return err
+	}
+	source, err := openStateDataSource(abciVersion, stateDataSourcePath(dataFilePath))
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	keyBytes := statedata.StripKvPairPrefix([]byte(key))
+	value, err := source.Get(keyBytes)
+	if err == statedata.ErrNotFound {
+		return fmt.Errorf("key not found: %s", keyBytes)
+	}
+	if err != nil {
+		return err
+	}
+
+	return printJSON(statedata.DecodeValue(abciVersion, keyBytes, value), true)
+}
+
+// inspectScan prints decoded key-values with given key prefix as JSON lines
+func inspectScan(version string, dataFilePath string, prefix string, limit int64) (err error) {
+	abciVersion, err := parseABCIVersion(version)
+	if err != nil {
+		return err
+	}
+	source, err := openStateDataSource(abciVersion, stateDataSourcePath(dataFilePath))
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	var count int64
+	return source.Iterate(statedata.StripKvPairPrefix([]byte(prefix)), func(key []byte, value []byte) (stop bool, err error) {
+		err = printJSON(statedata.DecodeValue(abciVersion, key, value), false)
+		if err != nil {
+			return false, err
+		}
+		count++
+		return limit > 0 && count >= limit, nil
+	})
+}
+
+type inspectCountResult struct {
+	Total    int64            `json:"total"`
+	ByPrefix map[string]int64 `json:"by_prefix"`
+}
+
+func inspectCount(version string, dataFilePath string, prefix string) (err error) {
+	abciVersion, err := parseABCIVersion(version)
+	if err != nil {
+		return err
+	}
+	source, err := openStateDataSource(abciVersion, stateDataSourcePath(dataFilePath))
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	result := inspectCountResult{
+		ByPrefix: make(map[string]int64),
+	}
+	err = source.Iterate(statedata.StripKvPairPrefix([]byte(prefix)), func(key []byte, value []byte) (stop bool, err error) {
+		result.Total++
+		result.ByPrefix[statedata.KeyPrefix(key)]++
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return printJSON(result, true)
+}
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Inspect ABCI state DB or initial state data with values decoded to JSON",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		curDir, _ := os.Getwd()
+		viper.SetDefault("ABCI_DB_TYPE", "goleveldb")
+		viper.SetDefault("ABCI_DB_DIR_PATH", path.Join(curDir, "../smart-contract/DB1"))
+	},
+}
+
+var inspectGetCmd = &cobra.Command{
+	Use:   "get [version] [key]",
+	Short: "Print decoded value of a key",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dataFilePath, err := cmd.Flags().GetString("data-file")
+		if err != nil {
+			return err
+		}
+		return inspectGet(args[0], dataFilePath, args[1])
+	},
+}
+
+var inspectScanCmd = &cobra.Command{
+	Use:   "scan [version]",
+	Short: "Print decoded key-values with key prefix, one JSON per line",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dataFilePath, err := cmd.Flags().GetString("data-file")
+		if err != nil {
+			return err
+		}
+		prefix, err := cmd.Flags().GetString("prefix")
+		if err != nil {
+			return err
+		}
+		limit, err := cmd.Flags().GetInt64("limit")
+		if err != nil {
+			return err
+		}
+		return inspectScan(args[0], dataFilePath, prefix, limit)
+	},
+}
+
+var inspectCountCmd = &cobra.Command{
+	Use:   "count [version]",
+	Short: "Count keys by key prefix",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dataFilePath, err := cmd.Flags().GetString("data-file")
+		if err != nil {
+			return err
+		}
+		prefix, err := cmd.Flags().GetString("prefix")
+		if err != nil {
+			return err
+		}
+		return inspectCount(args[0], dataFilePath, prefix)
+	},
+}
+
+func init() {
+	inspectCmd.PersistentFlags().String("data-file", "", "initial state data file to read instead of ABCI state DB")
+	inspectScanCmd.Flags().String("prefix", "", "key prefix (e.g. \"NodeID|\")")
+	inspectScanCmd.Flags().Int64("limit", 0, "max number of keys to print (0 for no limit)")
+	inspectCountCmd.Flags().String("prefix", "", "key prefix (e.g. \"NodeID|\")")
+	inspectCmd.AddCommand(inspectGetCmd)
+	inspectCmd.AddCommand(inspectScanCmd)
+	inspectCmd.AddCommand(inspectCountCmd)
+	rootCmd.AddCommand(inspectCmd)
+}
@@ -0,0 +1,205 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	v5 "github.com/ndidplatform/migration-tools/did/v5"
+	v6 "github.com/ndidplatform/migration-tools/did/v6"
+	v7 "github.com/ndidplatform/migration-tools/did/v7"
+	v9 "github.com/ndidplatform/migration-tools/did/v9"
+	_log "github.com/ndidplatform/migration-tools/log"
+	"github.com/ndidplatform/migration-tools/tm_client"
+)
+
+type preflightCheckResult struct {
+	Name    string
+	Passed  bool
+	Skipped bool
+	Detail  string
+}
+
+func addPreflightFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("force", false, "proceed even if pre-restore readiness checks fail")
+}
+
+func setPreflightDefaults() {
+	viper.SetDefault("EXPECTED_CHAIN_ID", "")
+	viper.SetDefault("ALLOWED_PEER_IDS", "")
+}
+
+// preflight checks that target chain is ready for initializing with NDID
+// node and initial state data. It returns error if any check fails and
+// force is not set.
+func preflight(version string, force bool) (err error) {
+	tendermintRPCHost := viper.GetString("TENDERMINT_RPC_HOST")
+	tendermintRPCPort := viper.GetString("TENDERMINT_RPC_PORT")
+	expectedChainID := viper.GetString("EXPECTED_CHAIN_ID")
+	allowedPeerIDs := make([]string, 0)
+	for _, peerID := range strings.Split(viper.GetString("ALLOWED_PEER_IDS"), ",") {
+		peerID = strings.TrimSpace(peerID)
+		if peerID != "" {
+			allowedPeerIDs = append(allowedPeerIDs, peerID)
+		}
+	}
+
+	var isInitEnded func(tmClient *tm_client.TmClient) (bool, error)
+	switch version {
+	case "5":
+		isInitEnded = v5.IsInitEnded
+	case "6":
+		isInitEnded = v6.IsInitEnded
+	case "7", "8":
+		isInitEnded = v7.IsInitEnded
+	case "9":
+		isInitEnded = v9.IsInitEnded
+	}
+
+	logger, err := _log.NewLogger(&_log.Configuration{
+		EnableConsole:     true,
+		ConsoleLevel:      "info",
+		ConsoleJSONFormat: false,
+		Color:             true,
+	}, _log.InstanceGoLogger)
+	if err != nil {
+		return err
+	}
+	config := tm_client.DefaultConfig()
+	config.Connection = tendermintRPCConnectionConfig()
+	config.ReconnectMaxAttempts = 0
+	tmClient, err := tm_client.NewWithConfig(logger, config)
+	if err != nil {
+		return err
+	}
+	_, err = tmClient.Connect(context.Background(), tendermintRPCHost, tendermintRPCPort)
+	if err != nil {
+		return err
+	}
+	defer tmClient.Close(context.Background())
+
+	status, err := tmClient.Status(context.Background())
+	if err != nil {
+		return err
+	}
+	netInfo, err := tmClient.NetInfo(context.Background())
+	if err != nil {
+		return err
+	}
+
+	results := checkChainStatus(status, netInfo, expectedChainID, allowedPeerIDs)
+
+	if isInitEnded != nil {
+		initEnded, err := isInitEnded(tmClient)
+		switch {
+		case err != nil:
+			results = append(results, preflightCheckResult{Name: "ABCI in InitState", Detail: err.Error()})
+		case initEnded:
+			results = append(results, preflightCheckResult{Name: "ABCI in InitState", Detail: "init already ended"})
+		default:
+			results = append(results, preflightCheckResult{Name: "ABCI in InitState", Passed: true, Detail: "init not ended"})
+		}
+	} else {
+		results = append(results, preflightCheckResult{Name: "ABCI in InitState", Skipped: true, Detail: "check not supported for ABCI version " + version})
+	}
+
+	failedCount := 0
+	log.Println("===== Preflight Checks =====")
+	for _, result := range results {
+		state := "PASS"
+		if result.Skipped {
+			state = "SKIP"
+		} else if !result.Passed {
+			state = "FAIL"
+			failedCount++
+		}
+		log.Printf("[%s] %s: %s\n", state, result.Name, result.Detail)
+	}
+
+	if failedCount > 0 {
+		if force {
+			log.Printf("%d preflight check(s) failed, proceeding because of --force\n", failedCount)
+			return nil
+		}
+		return fmt.Errorf("%d preflight check(s) failed, use --force to proceed anyway", failedCount)
+	}
+
+	return nil
+}
+
+func checkChainStatus(
+	status *tm_client.ResponseStatus,
+	netInfo *tm_client.ResponseNetInfo,
+	expectedChainID string,
+	allowedPeerIDs []string,
+) (results []preflightCheckResult) {
+	chainID := status.NodeInfo.Network
+	switch {
+	case expectedChainID == "":
+		results = append(results, preflightCheckResult{Name: "chain ID", Detail: "EXPECTED_CHAIN_ID is not set (chain ID: " + chainID + ")"})
+	case chainID != expectedChainID:
+		results = append(results, preflightCheckResult{Name: "chain ID", Detail: fmt.Sprintf("expected %s, got %s", expectedChainID, chainID)})
+	default:
+		results = append(results, preflightCheckResult{Name: "chain ID", Passed: true, Detail: chainID})
+	}
+
+	latestBlockHeight, err := strconv.ParseInt(status.SyncInfo.LatestBlockHeight, 10, 64)
+	if err != nil {
+		results = append(results, preflightCheckResult{Name: "latest block height", Detail: err.Error()})
+	} else if latestBlockHeight < 1 {
+		results = append(results, preflightCheckResult{Name: "latest block height", Detail: "no block has been created yet"})
+	} else {
+		results = append(results, preflightCheckResult{Name: "latest block height", Passed: true, Detail: strconv.FormatInt(latestBlockHeight, 10)})
+	}
+
+	if status.SyncInfo.CatchingUp {
+		results = append(results, preflightCheckResult{Name: "catching up", Detail: "node is catching up"})
+	} else {
+		results = append(results, preflightCheckResult{Name: "catching up", Passed: true, Detail: "node is not catching up"})
+	}
+
+	allowed := make(map[string]bool)
+	for _, peerID := range allowedPeerIDs {
+		allowed[peerID] = true
+	}
+	unexpectedPeerIDs := make([]string, 0)
+	for _, peer := range netInfo.Peers {
+		if !allowed[peer.NodeInfo.ID] {
+			unexpectedPeerIDs = append(unexpectedPeerIDs, peer.NodeInfo.ID)
+		}
+	}
+	if len(unexpectedPeerIDs) > 0 {
+		results = append(results, preflightCheckResult{Name: "peers", Detail: "unexpected peer(s): " + strings.Join(unexpectedPeerIDs, ", ")})
+	} else {
+		results = append(results, preflightCheckResult{Name: "peers", Passed: true, Detail: fmt.Sprintf("%d peer(s)", len(netInfo.Peers))})
+	}
+
+	return results
+}
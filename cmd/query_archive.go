@@ -0,0 +1,111 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ndidplatform/migration-tools/blockarchive"
+)
+
+func queryArchive(archiveDir string, height int64, txHash string, raw bool) (err error) {
+	if (height == 0) == (txHash == "") {
+		return errors.New("either --height or --tx must be specified")
+	}
+
+	archive, err := blockarchive.Open(archiveDir)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	var result interface{}
+	if height != 0 {
+		record, err := archive.GetBlock(height)
+		if err == blockarchive.ErrNotFound {
+			info := archive.Info()
+			return fmt.Errorf("block height %d not in archive, archive range: %d-%d", height, info.BaseHeight, info.LastHeight)
+		}
+		if err != nil {
+			return err
+		}
+		if !raw {
+			record.Block = nil
+			record.ABCIResponses = nil
+		}
+		result = record
+	} else {
+		hash, err := hex.DecodeString(txHash)
+		if err != nil {
+			return err
+		}
+		txResult, err := archive.GetTx(hash)
+		if err == blockarchive.ErrNotFound {
+			return errors.New("tx not found in archive")
+		}
+		if err != nil {
+			return err
+		}
+		result = txResult
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(resultJSON))
+	return nil
+}
+
+var queryArchiveCmd = &cobra.Command{
+	Use:   "query-archive [archiveDir]",
+	Short: "Look up a block by height or a tx by hash in block archive created by export-blocks",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		height, err := cmd.Flags().GetInt64("height")
+		if err != nil {
+			return err
+		}
+		txHash, err := cmd.Flags().GetString("tx")
+		if err != nil {
+			return err
+		}
+		raw, err := cmd.Flags().GetBool("raw")
+		if err != nil {
+			return err
+		}
+		return queryArchive(args[0], height, txHash, raw)
+	},
+}
+
+func init() {
+	queryArchiveCmd.Flags().Int64("height", 0, "block height")
+	queryArchiveCmd.Flags().String("tx", "", "tx hash (hex)")
+	queryArchiveCmd.Flags().Bool("raw", false, "include encoded block and ABCI responses in block output")
+	rootCmd.AddCommand(queryArchiveCmd)
+}
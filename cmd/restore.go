@@ -47,7 +47,7 @@ func restore(toVersion string) (err error) {
 	keyDir := viper.GetString("KEY_DIR")
 	tendermintRPCHost := viper.GetString("TENDERMINT_RPC_HOST")
 	tendermintRPCPort := viper.GetString("TENDERMINT_RPC_PORT")
-	tendermintRPCAddress := "http://" + tendermintRPCHost + ":" + tendermintRPCPort
+	tendermintRPCConnectionConfig := tendermintRPCConnectionConfig()
 
 	switch toVersion {
 	case "3":
@@ -57,7 +57,9 @@ func restore(toVersion string) (err error) {
 			backupDataFileName,
 			chainHistoryFileName,
 			keyDir,
-			tendermintRPCAddress,
+			tendermintRPCHost,
+			tendermintRPCPort,
+			tendermintRPCConnectionConfig,
 		)
 	case "4":
 		err = v4.Restore(
@@ -66,7 +68,9 @@ func restore(toVersion string) (err error) {
 			backupDataFileName,
 			chainHistoryFileName,
 			keyDir,
-			tendermintRPCAddress,
+			tendermintRPCHost,
+			tendermintRPCPort,
+			tendermintRPCConnectionConfig,
 		)
 	case "5":
 		err = v5.Restore(
@@ -77,6 +81,7 @@ func restore(toVersion string) (err error) {
 			keyDir,
 			tendermintRPCHost,
 			tendermintRPCPort,
+			tendermintRPCConnectionConfig,
 		)
 	case "6":
 		err = v6.Restore(
@@ -87,6 +92,7 @@ func restore(toVersion string) (err error) {
 			keyDir,
 			tendermintRPCHost,
 			tendermintRPCPort,
+			tendermintRPCConnectionConfig,
 		)
 	case "7", "8":
 		err = v7.Restore(
@@ -97,6 +103,7 @@ func restore(toVersion string) (err error) {
 			keyDir,
 			tendermintRPCHost,
 			tendermintRPCPort,
+			tendermintRPCConnectionConfig,
 		)
 	default:
 		return errors.New("unsupported ABCI version")
@@ -125,12 +132,22 @@ var restoreCmd = &cobra.Command{
 		viper.SetDefault("KEY_DIR", "./dev_keys/")
 		viper.SetDefault("TENDERMINT_RPC_HOST", "localhost")
 		viper.SetDefault("TENDERMINT_RPC_PORT", "45000")
+		setPreflightDefaults()
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return err
+		}
+		err = preflight(args[0], force)
+		if err != nil {
+			return err
+		}
 		return restore(args[0])
 	},
 }
 
 func init() {
+	addPreflightFlags(restoreCmd)
 	rootCmd.AddCommand(restoreCmd)
 }
@@ -0,0 +1,180 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package cmd
+
+import (
+	"errors"
+	"log"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ndidplatform/migration-tools/snapshot"
+	"github.com/ndidplatform/migration-tools/tendermint"
+)
+
+const (
+	snapshotSourceTendermintData = "tendermint_data"
+	snapshotSourceTendermintDB   = "tendermint_db"
+	snapshotSourceABCIDB         = "abci_db"
+)
+
+// restore target path env var of each snapshot source
+var snapshotRestoreTargetEnvs = map[string]string{
+	snapshotSourceTendermintData: "RESTORE_TM_DATA_DIR",
+	snapshotSourceTendermintDB:   "RESTORE_TM_DB_DIR",
+	snapshotSourceABCIDB:         "RESTORE_ABCI_DB_DIR_PATH",
+}
+
+// SnapshotReference is snapshot info recorded in initial state metadata file
+type SnapshotReference struct {
+	Path           string    `json:"path"`
+	Mode           string    `json:"mode"`
+	CreatedAt      time.Time `json:"created_at"`
+	ManifestSHA256 string    `json:"manifest_sha256"`
+}
+
+// getSnapshotSources returns Tendermint data directory, Tendermint DB
+// directory (only when it is outside data directory) and ABCI DB directory
+func getSnapshotSources(tmHome string, abciDBDir string) (sources []snapshot.Source, err error) {
+	tendermintDataDir, err := filepath.Abs(path.Join(tmHome, "data"))
+	if err != nil {
+		return nil, err
+	}
+	sources = append(sources, snapshot.Source{
+		Name: snapshotSourceTendermintData,
+		Path: tendermintDataDir,
+	})
+
+	_, tendermintDBDir, err := tendermint.GetDBConfig(tmHome)
+	if err != nil {
+		return nil, err
+	}
+	tendermintDBDir, err = filepath.Abs(tendermintDBDir)
+	if err != nil {
+		return nil, err
+	}
+	relPath, err := filepath.Rel(tendermintDataDir, tendermintDBDir)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		sources = append(sources, snapshot.Source{
+			Name: snapshotSourceTendermintDB,
+			Path: tendermintDBDir,
+		})
+	}
+
+	sources = append(sources, snapshot.Source{
+		Name: snapshotSourceABCIDB,
+		Path: abciDBDir,
+	})
+	return sources, nil
+}
+
+// createSnapshot snapshots source data directories when SNAPSHOT is enabled.
+// nil is returned when snapshot is disabled.
+func createSnapshot(initialStateDataDirectoryPath string) (snapshotReference *SnapshotReference, err error) {
+	if !viper.GetBool("SNAPSHOT") {
+		return nil, nil
+	}
+	startTime := time.Now()
+
+	snapshotDir := viper.GetString("SNAPSHOT_DIR")
+	if snapshotDir == "" {
+		snapshotDir = path.Join(initialStateDataDirectoryPath, "snapshot")
+	}
+	snapshotDir, err = filepath.Abs(snapshotDir)
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := getSnapshotSources(viper.GetString("TM_HOME"), viper.GetString("ABCI_DB_DIR_PATH"))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("creating snapshot:", snapshotDir)
+	info, err := snapshot.Create(snapshotDir, sources, viper.GetString("SNAPSHOT_MODE"))
+	if err != nil {
+		return nil, err
+	}
+	log.Println("snapshot mode:", info.Mode)
+	log.Println("snapshot file count:", info.FileCount)
+	log.Println("snapshot total size:", info.TotalSize)
+	log.Println("snapshot time used:", time.Since(startTime))
+
+	return &SnapshotReference{
+		Path:           snapshotDir,
+		Mode:           info.Mode,
+		CreatedAt:      info.CreatedAt,
+		ManifestSHA256: info.ManifestSHA256,
+	}, nil
+}
+
+func restoreSnapshot(snapshotDir string) (err error) {
+	startTime := time.Now()
+
+	targetPaths := make(map[string]string)
+	for sourceName, env := range snapshotRestoreTargetEnvs {
+		targetPaths[sourceName] = viper.GetString(env)
+	}
+
+	restored, err := snapshot.Restore(snapshotDir, targetPaths)
+	if err != nil {
+		return err
+	}
+	if len(restored) == 0 {
+		return errors.New("no source in snapshot")
+	}
+
+	for _, source := range restored {
+		log.Printf("restored %s: %s\n", source.Name, source.Path)
+		if source.MovedAsidePath != "" {
+			log.Printf("existing %s moved to: %s\n", source.Name, source.MovedAsidePath)
+		}
+	}
+	log.Println("restore snapshot done")
+	log.Println("time used:", time.Since(startTime))
+
+	return nil
+}
+
+var restoreSnapshotCmd = &cobra.Command{
+	Use:   "restore-snapshot [snapshotDir]",
+	Short: "Restore source data directories from snapshot created by create-initial-state-data",
+	Args:  cobra.MinimumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.SetDefault("RESTORE_TM_DATA_DIR", "")
+		viper.SetDefault("RESTORE_TM_DB_DIR", "")
+		viper.SetDefault("RESTORE_ABCI_DB_DIR_PATH", "")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return restoreSnapshot(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restoreSnapshotCmd)
+}
@@ -0,0 +1,176 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ndidplatform/migration-tools/statedata"
+)
+
+func stateStats(version string, dataFilePath string, topN int, outputFormat string) (err error) {
+	if outputFormat != "table" && outputFormat != "json" {
+		return errors.New("unknown output format: " + outputFormat)
+	}
+	abciVersion, err := parseABCIVersion(version)
+	if err != nil {
+		return err
+	}
+	source, err := openStateDataSource(abciVersion, stateDataSourcePath(dataFilePath))
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	stats, err := statedata.CollectStats(source, abciVersion, topN)
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == "json" {
+		return printJSON(stats, true)
+	}
+	printStatsTable(os.Stdout, stats)
+	return nil
+}
+
+func printStatsTable(out io.Writer, stats *statedata.Stats) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ABCI Version\t%d\n", stats.ABCIVersion)
+	fmt.Fprintf(w, "Key Count\t%d\n", stats.KeyCount)
+	fmt.Fprintf(w, "Total Value Size\t%d\n", stats.TotalValueSize)
+	fmt.Fprintf(w, "Decode Error Count\t%d\n", stats.DecodeErrorCount)
+	w.Flush()
+
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PREFIX\tKEYS\tTOTAL SIZE\tP50\tP90\tP99\tMAX")
+	for _, prefixStats := range stats.Prefixes {
+		fmt.Fprintf(w, "%s\t%s\n", prefixStats.Prefix, distributionColumns(prefixStats.ValueSize))
+	}
+	w.Flush()
+
+	if len(stats.LargestKeys) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "LARGEST KEY\tVALUE SIZE")
+		for _, keySize := range stats.LargestKeys {
+			fmt.Fprintf(w, "%s\t%d\n", keySize.Key, keySize.ValueSize)
+		}
+		w.Flush()
+	}
+
+	if len(stats.RequestVersions) > 0 {
+		versionCounts := make([]int64, 0, len(stats.RequestVersions))
+		for versionCount := range stats.RequestVersions {
+			versionCounts = append(versionCounts, versionCount)
+		}
+		sort.Slice(versionCounts, func(i, j int) bool { return versionCounts[i] < versionCounts[j] })
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSIONS PER REQUEST\tREQUESTS")
+		for _, versionCount := range versionCounts {
+			fmt.Fprintf(w, "%d\t%d\n", versionCount, stats.RequestVersions[versionCount])
+		}
+		w.Flush()
+	}
+
+	if stats.ReferenceGroups.Count > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintf(out, "Reference Group Count: %d\n", stats.ReferenceGroups.Count)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PER REFERENCE GROUP\tGROUPS\tTOTAL\tP50\tP90\tP99\tMAX")
+		fmt.Fprintf(w, "identities\t%s\n", distributionColumns(stats.ReferenceGroups.Identities))
+		fmt.Fprintf(w, "idps\t%s\n", distributionColumns(stats.ReferenceGroups.IdPs))
+		fmt.Fprintf(w, "accessors\t%s\n", distributionColumns(stats.ReferenceGroups.Accessors))
+		w.Flush()
+	}
+
+	if len(stats.NodesByRole) > 0 {
+		roles := make([]string, 0, len(stats.NodesByRole))
+		for role := range stats.NodesByRole {
+			roles = append(roles, role)
+		}
+		sort.Strings(roles)
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NODE ROLE\tNODES")
+		for _, role := range roles {
+			fmt.Fprintf(w, "%s\t%d\n", role, stats.NodesByRole[role])
+		}
+		w.Flush()
+	}
+}
+
+func distributionColumns(distribution statedata.Distribution) string {
+	return fmt.Sprintf(
+		"%d\t%d\t%d\t%d\t%d\t%d",
+		distribution.Count,
+		distribution.Total,
+		distribution.P50,
+		distribution.P90,
+		distribution.P99,
+		distribution.Max,
+	)
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [version]",
+	Short: "Print statistics of ABCI state DB or initial state data for capacity planning",
+	Args:  cobra.ExactArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		curDir, _ := os.Getwd()
+		viper.SetDefault("ABCI_DB_TYPE", "goleveldb")
+		viper.SetDefault("ABCI_DB_DIR_PATH", path.Join(curDir, "../smart-contract/DB1"))
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dataFilePath, err := cmd.Flags().GetString("data-file")
+		if err != nil {
+			return err
+		}
+		topN, err := cmd.Flags().GetInt("top")
+		if err != nil {
+			return err
+		}
+		outputFormat, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		return stateStats(args[0], dataFilePath, topN, outputFormat)
+	},
+}
+
+func init() {
+	statsCmd.Flags().String("data-file", "", "initial state data file to read instead of ABCI state DB")
+	statsCmd.Flags().Int("top", 10, "number of largest keys to print")
+	statsCmd.Flags().StringP("output", "o", "table", "output format: table or json")
+	rootCmd.AddCommand(statsCmd)
+}
@@ -0,0 +1,44 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/viper"
+
+	"github.com/ndidplatform/migration-tools/tm_client"
+)
+
+// tendermintRPCConnectionConfig reads TLS and authentication settings of
+// Tendermint RPC connection. All settings are optional.
+func tendermintRPCConnectionConfig() tm_client.ConnectionConfig {
+	return tm_client.ConnectionConfig{
+		TLS:                viper.GetBool("TENDERMINT_RPC_TLS"),
+		CACertFile:         viper.GetString("TENDERMINT_RPC_TLS_CA_CERT_FILEPATH"),
+		ClientCertFile:     viper.GetString("TENDERMINT_RPC_TLS_CLIENT_CERT_FILEPATH"),
+		ClientKeyFile:      viper.GetString("TENDERMINT_RPC_TLS_CLIENT_KEY_FILEPATH"),
+		InsecureSkipVerify: viper.GetBool("TENDERMINT_RPC_TLS_INSECURE_SKIP_VERIFY"),
+		BearerToken:        viper.GetString("TENDERMINT_RPC_BEARER_TOKEN"),
+		BasicAuthUsername:  viper.GetString("TENDERMINT_RPC_BASIC_AUTH_USERNAME"),
+		BasicAuthPassword:  viper.GetString("TENDERMINT_RPC_BASIC_AUTH_PASSWORD"),
+	}
+}
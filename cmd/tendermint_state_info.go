@@ -23,42 +23,165 @@
 package cmd
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log"
 	"os"
 	"path"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/ndidplatform/migration-tools/tendermint"
 	tendermint_0_26_4 "github.com/ndidplatform/migration-tools/tendermint/0_26_4"
 	tendermint_0_30_2 "github.com/ndidplatform/migration-tools/tendermint/0_30_2"
 	tendermint_0_32_1 "github.com/ndidplatform/migration-tools/tendermint/0_32_1"
 	tendermint_0_33_2 "github.com/ndidplatform/migration-tools/tendermint/0_33_2"
 	tendermint_0_34_19 "github.com/ndidplatform/migration-tools/tendermint/0_34_19"
+	cometbft_0_37 "github.com/ndidplatform/migration-tools/tendermint/cometbft_0_37"
+	cometbft_0_38 "github.com/ndidplatform/migration-tools/tendermint/cometbft_0_38"
 )
 
-func loadTendermintInfo(tendermintVersion string) (err error) {
-	tmHome := viper.GetString("TM_HOME")
+// Tendermint version used by each ABCI app version
+var abciTendermintVersions = map[string]string{
+	"1":  "0.26.4",
+	"2":  "0.30.2",
+	"3":  "0.32.1",
+	"4":  "0.32.1",
+	"5":  "0.32.1",
+	"6":  "0.33.2",
+	"7":  "0.34.19",
+	"8":  "0.34.19",
+	"9":  "0.34.19",
+	"10": "0.38", // CometBFT
+}
 
+func loadValidatorsBackup(tendermintVersion string, tmHome string) (validatorsBackup *tendermint.ValidatorsBackup, err error) {
 	switch tendermintVersion {
 	case "0.26.4":
-		_, err = tendermint_0_26_4.GetTendermintInfo(tmHome)
+		return tendermint_0_26_4.GetValidatorsBackup(tmHome)
 	case "0.30.2":
-		_, err = tendermint_0_30_2.GetTendermintInfo(tmHome)
+		return tendermint_0_30_2.GetValidatorsBackup(tmHome)
 	case "0.32.1":
-		_, err = tendermint_0_32_1.GetTendermintInfo(tmHome)
+		return tendermint_0_32_1.GetValidatorsBackup(tmHome)
 	case "0.33.2":
-		_, err = tendermint_0_33_2.GetTendermintInfo(tmHome)
+		return tendermint_0_33_2.GetValidatorsBackup(tmHome)
 	case "0.34.19":
-		_, err = tendermint_0_34_19.GetTendermintInfo(tmHome)
+		return tendermint_0_34_19.GetValidatorsBackup(tmHome)
+	case "0.37":
+		return cometbft_0_37.GetValidatorsBackup(tmHome)
+	case "0.38":
+		return cometbft_0_38.GetValidatorsBackup(tmHome)
 	default:
+		return nil, errors.New("unsupported Tendermint version")
+	}
+}
+
+var tendermintStateInfoReaders = map[string]tendermint.StateInfoReader{
+	tendermint_0_26_4.TendermintVersion:  tendermint_0_26_4.StateInfoReader{},
+	tendermint_0_30_2.TendermintVersion:  tendermint_0_30_2.StateInfoReader{},
+	tendermint_0_32_1.TendermintVersion:  tendermint_0_32_1.StateInfoReader{},
+	tendermint_0_33_2.TendermintVersion:  tendermint_0_33_2.StateInfoReader{},
+	tendermint_0_34_19.TendermintVersion: tendermint_0_34_19.StateInfoReader{},
+	cometbft_0_37.TendermintVersion:      cometbft_0_37.StateInfoReader{},
+	cometbft_0_38.TendermintVersion:      cometbft_0_38.StateInfoReader{},
+}
+
+// resolveTendermintVersion detects Tendermint version of data in tmHome when
+// tendermintVersion is "auto"
+func resolveTendermintVersion(tmHome string, tendermintVersion string) (resolvedTendermintVersion string, err error) {
+	if tendermintVersion != "auto" {
+		return tendermintVersion, nil
+	}
+	detection, err := detectTendermintVersion(tmHome)
+	if err != nil {
+		return "", err
+	}
+	if detection.Confidence == confidenceLow {
+		return "", fmt.Errorf(
+			"cannot detect Tendermint version with enough confidence (candidates: %v), please specify tendermintVersion",
+			detection.Candidates,
+		)
+	}
+	log.Printf("detected Tendermint version: %s (confidence: %s)\n", detection.TendermintVersion, detection.Confidence)
+	return detection.TendermintVersion, nil
+}
+
+func loadTendermintInfo(tendermintVersion string, height int64, outputFormat string) (err error) {
+	tmHome := viper.GetString("TM_HOME")
+
+	tendermintVersion, err = resolveTendermintVersion(tmHome, tendermintVersion)
+	if err != nil {
+		return err
+	}
+
+	reader, ok := tendermintStateInfoReaders[tendermintVersion]
+	if !ok {
 		return errors.New("unsupported Tendermint version")
 	}
-	return err
+	stateInfo, err := reader.GetStateInfo(tmHome, height)
+	if err != nil {
+		return err
+	}
+
+	switch outputFormat {
+	case "json":
+		stateInfoJSON, err := json.MarshalIndent(stateInfo, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(stateInfoJSON))
+	case "table":
+		printTendermintStateInfoTable(os.Stdout, stateInfo)
+	default:
+		return errors.New("unknown output format: " + outputFormat)
+	}
+	return nil
+}
+
+func printTendermintStateInfoTable(out io.Writer, stateInfo *tendermint.StateInfo) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Tendermint Version\t%s\n", stateInfo.TendermintVersion)
+	fmt.Fprintf(w, "Chain ID\t%s\n", stateInfo.ChainID)
+	fmt.Fprintf(w, "Initial Height\t%d\n", stateInfo.InitialHeight)
+	fmt.Fprintf(w, "Last Block Height\t%d\n", stateInfo.LastBlockHeight)
+	fmt.Fprintf(w, "Last Block Hash\t%s\n", stateInfo.LastBlockHash)
+	fmt.Fprintf(w, "Last Block Time\t%s\n", stateInfo.LastBlockTime.Format(time.RFC3339Nano))
+	fmt.Fprintf(w, "App Hash\t%s\n", stateInfo.AppHash)
+	fmt.Fprintf(w, "Committed App Hash\t%s\n", stateInfo.CommittedAppHash)
+	fmt.Fprintf(w, "Last Results Hash\t%s\n", stateInfo.LastResultsHash)
+	fmt.Fprintf(w, "DB Backend\t%s\n", stateInfo.DBBackend)
+	fmt.Fprintf(w, "DB Directory\t%s\n", stateInfo.DBDir)
+	fmt.Fprintf(w, "Validator Count\t%d\n", len(stateInfo.Validators))
+	w.Flush()
+
+	if len(stateInfo.Validators) == 0 {
+		return
+	}
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tPUB KEY TYPE\tPUB KEY\tVOTING POWER\tPROPOSER PRIORITY")
+	for _, validator := range stateInfo.Validators {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%d\t%d\n",
+			validator.Address,
+			validator.PubKey.Type,
+			base64.StdEncoding.EncodeToString(validator.PubKey.Value),
+			validator.VotingPower,
+			validator.ProposerPriority,
+		)
+	}
+	w.Flush()
 }
 
 var loadTendermintInfoCmd = &cobra.Command{
-	Use:   "tendermint_state_info [tendermintVersion]",
+	Use:   "tendermint_state_info [tendermintVersion|auto]",
 	Short: "Print Tendermint state info used for migration",
 	Args:  cobra.MinimumNArgs(1),
 	PreRun: func(cmd *cobra.Command, args []string) {
@@ -66,10 +189,20 @@ var loadTendermintInfoCmd = &cobra.Command{
 		viper.SetDefault("TM_HOME", path.Join(curDir, "../smart-contract/config/tendermint/IdP"))
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return loadTendermintInfo(args[0])
+		height, err := cmd.Flags().GetInt64("height")
+		if err != nil {
+			return err
+		}
+		outputFormat, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		return loadTendermintInfo(args[0], height, outputFormat)
 	},
 }
 
 func init() {
+	loadTendermintInfoCmd.Flags().Int64("height", 0, "block height (default latest block)")
+	loadTendermintInfoCmd.Flags().StringP("output", "o", "table", "output format: table or json")
 	rootCmd.AddCommand(loadTendermintInfoCmd)
 }
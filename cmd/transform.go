@@ -0,0 +1,58 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package cmd
+
+import (
+	"log"
+	"path"
+	"strconv"
+
+	"github.com/spf13/viper"
+
+	"github.com/ndidplatform/migration-tools/transform"
+)
+
+// newTransformer returns transformer of rules in TRANSFORM_RULES_FILE for
+// conversion to target ABCI state version with audit file in initial state
+// data directory, or nil when rules file is not set
+func newTransformer(initialStateDataDirectoryPath string, targetStateVersion string) (transformer *transform.Transformer, err error) {
+	rulesFilePath := viper.GetString("TRANSFORM_RULES_FILE")
+	if rulesFilePath == "" {
+		return nil, nil
+	}
+
+	targetVersion, err := strconv.Atoi(targetStateVersion)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := transform.LoadRules(rulesFilePath)
+	if err != nil {
+		return nil, err
+	}
+	dryRun := viper.GetBool("TRANSFORM_DRY_RUN")
+	auditFilePath := path.Join(initialStateDataDirectoryPath, viper.GetString("TRANSFORM_AUDIT_FILENAME"))
+
+	log.Println("transform rules loaded:", len(rules), "dry run:", dryRun)
+	log.Println("transform audit file:", auditFilePath)
+
+	return transform.NewTransformer(rules, targetVersion, auditFilePath, dryRun)
+}
@@ -54,6 +54,7 @@ func updateNode(version string) (err error) {
 
 	tendermintRPCHost := viper.GetString("TENDERMINT_RPC_HOST")
 	tendermintRPCPort := viper.GetString("TENDERMINT_RPC_PORT")
+	tendermintRPCConnectionConfig := tendermintRPCConnectionConfig()
 
 	switch version {
 	case "7", "8":
@@ -64,6 +65,7 @@ func updateNode(version string) (err error) {
 			keyDir,
 			tendermintRPCHost,
 			tendermintRPCPort,
+			tendermintRPCConnectionConfig,
 		)
 	case "9":
 		err = v9.SetNodeKeys(
@@ -77,6 +79,7 @@ func updateNode(version string) (err error) {
 			keyDir,
 			tendermintRPCHost,
 			tendermintRPCPort,
+			tendermintRPCConnectionConfig,
 		)
 	default:
 		return errors.New("unsupported ABCI version")
@@ -0,0 +1,106 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	v9 "github.com/ndidplatform/migration-tools/did/v9"
+)
+
+func verifyRestore(version string) (err error) {
+	startTime := time.Now()
+
+	initialStateDataDir := viper.GetString("INITIAL_STATE_DATA_DIR")
+	initialStateDataFileName := viper.GetString("INITIAL_STATE_DATA_FILENAME")
+	sampleEvery := viper.GetInt64("VERIFY_SAMPLE_EVERY")
+
+	tendermintRPCHost := viper.GetString("TENDERMINT_RPC_HOST")
+	tendermintRPCPort := viper.GetString("TENDERMINT_RPC_PORT")
+	tendermintRPCConnectionConfig := tendermintRPCConnectionConfig()
+
+	var report *v9.VerifyReport
+	switch version {
+	case "9":
+		report, err = v9.VerifyRestore(
+			initialStateDataDir,
+			initialStateDataFileName,
+			tendermintRPCHost,
+			tendermintRPCPort,
+			tendermintRPCConnectionConfig,
+			sampleEvery,
+		)
+	default:
+		return errors.New("unsupported ABCI version")
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Println("===== Verify Restore Summary =====")
+	log.Println("total key count:", report.TotalKeyCount)
+	log.Println("verifiable key count:", report.VerifiableKeyCount)
+	log.Println("unverifiable key count (no ABCI query):", report.UnverifiableKeyCount)
+	log.Println("checked key count:", report.CheckedKeyCount)
+	log.Println("checked key count by prefix:", report.CheckedByPrefix)
+	log.Println("matched key count:", report.MatchedKeyCount)
+	log.Println("mismatched key count:", report.MismatchedKeyCount)
+	if report.VerifiableKeyCount > 0 {
+		log.Printf("coverage of verifiable keys: %.2f%%\n", float64(report.CheckedKeyCount)*100/float64(report.VerifiableKeyCount))
+	}
+	log.Println("time used:", time.Since(startTime))
+
+	if report.MismatchedKeyCount > 0 {
+		return fmt.Errorf("verify restore failed: %d mismatched key(s)", report.MismatchedKeyCount)
+	}
+
+	log.Println("verify restore done")
+
+	return nil
+}
+
+var verifyRestoreCmd = &cobra.Command{
+	Use:   "verify-restore [version]",
+	Short: "Verify restored chain state against initial state data by ABCI query",
+	Args:  cobra.MinimumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.SetDefault("INITIAL_STATE_DATA_DIR", "./_initial_state_data/")
+		viper.SetDefault("INITIAL_STATE_DATA_FILENAME", "data")
+		viper.SetDefault("VERIFY_SAMPLE_EVERY", 1)
+		viper.SetDefault("TENDERMINT_RPC_HOST", "localhost")
+		viper.SetDefault("TENDERMINT_RPC_PORT", "45000")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return verifyRestore(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyRestoreCmd)
+}
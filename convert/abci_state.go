@@ -0,0 +1,178 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package convert
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	abciStateKey         = []byte("stateKey")
+	abciLastBlockKey     = []byte("lastBlock")
+	abciKvPairPrefixKey  = []byte("kvPairKey:")
+	errABCIStateNotFound = errors.New("ABCI state metadata (stateKey) not found in state DB")
+)
+
+// ABCIState is ABCI app state metadata saved in state DB
+type ABCIState struct {
+	Size    int64  `json:"size"`
+	Height  int64  `json:"height"`
+	AppHash []byte `json:"app_hash"`
+
+	// LastBlock is block height set by SetLastBlock transaction. Transactions
+	// in blocks after this height are rejected by ABCI. Value <= 0 means not set.
+	LastBlock int64 `json:"-"`
+}
+
+// getBackupBlockHeight returns block height to create backup at from
+// BLOCK_NUMBER config. 0 is returned when not set (latest block).
+func getBackupBlockHeight() (height int64, err error) {
+	backupBlockNumberStr := strings.TrimSpace(viper.GetString("BLOCK_NUMBER"))
+	if backupBlockNumberStr == "" {
+		return 0, nil
+	}
+	height, err = strconv.ParseInt(backupBlockNumberStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid BLOCK_NUMBER: %s", backupBlockNumberStr)
+	}
+	if height < 1 {
+		return 0, fmt.Errorf("invalid BLOCK_NUMBER: %d, must be greater than 0", height)
+	}
+	return height, nil
+}
+
+func getABCIStateValue(
+	dbGet func(key []byte) (value []byte, err error),
+	key []byte,
+) (value []byte, err error) {
+	value, err = dbGet(key)
+	if err != nil {
+		return nil, err
+	}
+	if value != nil {
+		return value, nil
+	}
+	return dbGet(append(abciKvPairPrefixKey, key...))
+}
+
+func readABCIState(
+	dbGet func(key []byte) (value []byte, err error),
+) (abciState *ABCIState, err error) {
+	stateValue, err := getABCIStateValue(dbGet, abciStateKey)
+	if err != nil {
+		return nil, err
+	}
+	if stateValue == nil {
+		return nil, errABCIStateNotFound
+	}
+	abciState = new(ABCIState)
+	err = json.Unmarshal(stateValue, abciState)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse ABCI state metadata: %v", err)
+	}
+
+	lastBlockValue, err := getABCIStateValue(dbGet, abciLastBlockKey)
+	if err != nil {
+		return nil, err
+	}
+	if lastBlockValue != nil {
+		abciState.LastBlock, err = strconv.ParseInt(strings.TrimSpace(string(lastBlockValue)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse ABCI last block: %v", err)
+		}
+	}
+
+	return abciState, nil
+}
+
+// checkABCIState makes sure ABCI state DB is from the same node as Tendermint
+// DB and its data is the state at given block height by comparing ABCI state
+// height and app hash with Tendermint. State DB which has moved past the
+// height is accepted only when chain has been stopped by SetLastBlock at or
+// before the height since data cannot be changed after that.
+func checkABCIState(
+	dbGet func(key []byte) (value []byte, err error),
+	height int64,
+	getTendermintAppHash func(height int64) (appHash []byte, err error),
+) (err error) {
+	abciState, err := readABCIState(dbGet)
+	if err != nil {
+		return err
+	}
+
+	log.Println("===== ABCI State =====")
+	log.Printf("Height: %d\n", abciState.Height)
+	log.Printf("App Hash: %s\n", strings.ToUpper(hex.EncodeToString(abciState.AppHash)))
+	log.Printf("Last Block: %d\n", abciState.LastBlock)
+
+	var diff []string
+
+	compareHeight := height
+	if abciState.Height > height && abciState.LastBlock > 0 && abciState.LastBlock <= height {
+		log.Printf(
+			"ABCI state DB is at height %d, no state change after last block %d\n",
+			abciState.Height,
+			abciState.LastBlock,
+		)
+		compareHeight = abciState.Height
+	} else if abciState.Height != height {
+		diff = append(diff, fmt.Sprintf(
+			"height: ABCI: %d, Tendermint: %d",
+			abciState.Height,
+			height,
+		))
+	}
+
+	tendermintAppHash, err := getTendermintAppHash(compareHeight)
+	if err != nil {
+		return fmt.Errorf("cannot get Tendermint app hash at height %d: %v", compareHeight, err)
+	}
+	if !bytes.Equal(abciState.AppHash, tendermintAppHash) {
+		diff = append(diff, fmt.Sprintf(
+			"app hash at height %d: ABCI: %s, Tendermint: %s",
+			compareHeight,
+			strings.ToUpper(hex.EncodeToString(abciState.AppHash)),
+			strings.ToUpper(hex.EncodeToString(tendermintAppHash)),
+		))
+	}
+
+	if len(diff) > 0 {
+		return fmt.Errorf(
+			"ABCI state DB does not match Tendermint DB (copied from a different node or at a different time?):\n%s",
+			strings.Join(diff, "\n"),
+		)
+	}
+
+	log.Println("ABCI state DB matches Tendermint DB")
+
+	return nil
+}
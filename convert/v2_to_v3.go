@@ -35,6 +35,7 @@ import (
 	didProtoV2 "github.com/ndidplatform/migration-tools/did/v2/protos/data"
 	didProtoV3 "github.com/ndidplatform/migration-tools/did/v3/protos/data"
 	"github.com/ndidplatform/migration-tools/proto"
+	tendermint_0_30_2 "github.com/ndidplatform/migration-tools/tendermint/0_30_2"
 )
 
 func ConvertInputStateDBDataV2ToV3AndBackup(
@@ -42,16 +43,23 @@ func ConvertInputStateDBDataV2ToV3AndBackup(
 	saveKeyValue func(key []byte, value []byte) (err error),
 ) (err error) {
 	tmHome := viper.GetString("TM_HOME")
-	currentChainData, err := v2.GetLastestTendermintData(tmHome)
+	backupBlockHeight, err := getBackupBlockHeight()
+	if err != nil {
+		return err
+	}
+	currentChainData, err := v2.GetTendermintDataAtHeight(tmHome, backupBlockHeight)
 	if err != nil {
 		return err
 	}
 
 	dbType := viper.GetString("ABCI_DB_TYPE")
 	dbDir := viper.GetString("ABCI_DB_DIR_PATH")
-	// backupBlockNumberStr := viper.GetString("BLOCK_NUMBER")
 
-	v2StateDB := v2.GetStateDB(dbType, dbDir)
+	v2StateDB, err := v2.GetStateDB(dbType, dbDir)
+	if err != nil {
+		return err
+	}
+	defer v2StateDB.Close()
 	ndidNodeID, err := v2StateDB.Get([]byte("MasterNDID"))
 	if err != nil {
 		return err
@@ -61,12 +69,32 @@ func ConvertInputStateDBDataV2ToV3AndBackup(
 		return v2StateDB.Get(key)
 	}
 
+	currentBlockHeight, err := strconv.ParseInt(currentChainData.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return err
+	}
+	err = checkABCIState(
+		dbGet,
+		currentBlockHeight,
+		func(height int64) (appHash []byte, err error) {
+			tendermintStateInfo, err := tendermint_0_30_2.GetTendermintInfoAtHeight(tmHome, height)
+			if err != nil {
+				return nil, err
+			}
+			return tendermintStateInfo.CommittedAppHash, nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
 	var keysRead int64 = 0
 
 	itr, err := v2StateDB.Iterator(nil, nil)
 	if err != nil {
 		return err
 	}
+	defer itr.Close()
 	for ; itr.Valid(); itr.Next() {
 		key := itr.Key()
 		value := itr.Value()
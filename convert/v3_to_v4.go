@@ -34,6 +34,7 @@ import (
 	v3 "github.com/ndidplatform/migration-tools/did/v3"
 	didProtoV4 "github.com/ndidplatform/migration-tools/did/v4/protos/data"
 	"github.com/ndidplatform/migration-tools/proto"
+	tendermint_0_32_1 "github.com/ndidplatform/migration-tools/tendermint/0_32_1"
 )
 
 func ConvertInputStateDBDataV3ToV4AndBackup(
@@ -41,16 +42,23 @@ func ConvertInputStateDBDataV3ToV4AndBackup(
 	saveKeyValue func(key []byte, value []byte) (err error),
 ) (err error) {
 	tmHome := viper.GetString("TM_HOME")
-	currentChainData, err := v3.GetLastestTendermintData(tmHome)
+	backupBlockHeight, err := getBackupBlockHeight()
+	if err != nil {
+		return err
+	}
+	currentChainData, err := v3.GetTendermintDataAtHeight(tmHome, backupBlockHeight)
 	if err != nil {
 		return err
 	}
 
 	dbType := viper.GetString("ABCI_DB_TYPE")
 	dbDir := viper.GetString("ABCI_DB_DIR_PATH")
-	// backupBlockNumberStr := viper.GetString("BLOCK_NUMBER")
 
-	v3StateDB := v3.GetStateDB(dbType, dbDir)
+	v3StateDB, err := v3.GetStateDB(dbType, dbDir)
+	if err != nil {
+		return err
+	}
+	defer v3StateDB.Close()
 	ndidNodeID, err := v3StateDB.Get([]byte("MasterNDID"))
 	if err != nil {
 		return err
@@ -60,12 +68,32 @@ func ConvertInputStateDBDataV3ToV4AndBackup(
 		return v3StateDB.Get(key)
 	}
 
+	currentBlockHeight, err := strconv.ParseInt(currentChainData.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return err
+	}
+	err = checkABCIState(
+		dbGet,
+		currentBlockHeight,
+		func(height int64) (appHash []byte, err error) {
+			tendermintStateInfo, err := tendermint_0_32_1.GetTendermintInfoAtHeight(tmHome, height)
+			if err != nil {
+				return nil, err
+			}
+			return tendermintStateInfo.CommittedAppHash, nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
 	var keysRead int64 = 0
 
 	itr, err := v3StateDB.Iterator(nil, nil)
 	if err != nil {
 		return err
 	}
+	defer itr.Close()
 	for ; itr.Valid(); itr.Next() {
 		key := itr.Key()
 		value := itr.Value()
@@ -35,6 +35,7 @@ import (
 	didProtoV4 "github.com/ndidplatform/migration-tools/did/v4/protos/data"
 	didProtoV5 "github.com/ndidplatform/migration-tools/did/v5/protos/data"
 	"github.com/ndidplatform/migration-tools/proto"
+	tendermint_0_32_1 "github.com/ndidplatform/migration-tools/tendermint/0_32_1"
 )
 
 func ConvertInputStateDBDataV4ToV5AndBackup(
@@ -42,16 +43,23 @@ func ConvertInputStateDBDataV4ToV5AndBackup(
 	saveKeyValue func(key []byte, value []byte) (err error),
 ) (err error) {
 	tmHome := viper.GetString("TM_HOME")
-	currentChainData, err := v4.GetLastestTendermintData(tmHome)
+	backupBlockHeight, err := getBackupBlockHeight()
+	if err != nil {
+		return err
+	}
+	currentChainData, err := v4.GetTendermintDataAtHeight(tmHome, backupBlockHeight)
 	if err != nil {
 		return err
 	}
 
 	dbType := viper.GetString("ABCI_DB_TYPE")
 	dbDir := viper.GetString("ABCI_DB_DIR_PATH")
-	// backupBlockNumberStr := viper.GetString("BLOCK_NUMBER")
 
-	v4StateDB := v4.GetStateDB(dbType, dbDir)
+	v4StateDB, err := v4.GetStateDB(dbType, dbDir)
+	if err != nil {
+		return err
+	}
+	defer v4StateDB.Close()
 	ndidNodeID, err := v4StateDB.Get([]byte("MasterNDID"))
 	if err != nil {
 		return err
@@ -61,12 +69,32 @@ func ConvertInputStateDBDataV4ToV5AndBackup(
 		return v4StateDB.Get(key)
 	}
 
+	currentBlockHeight, err := strconv.ParseInt(currentChainData.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return err
+	}
+	err = checkABCIState(
+		dbGet,
+		currentBlockHeight,
+		func(height int64) (appHash []byte, err error) {
+			tendermintStateInfo, err := tendermint_0_32_1.GetTendermintInfoAtHeight(tmHome, height)
+			if err != nil {
+				return nil, err
+			}
+			return tendermintStateInfo.CommittedAppHash, nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
 	var keysRead int64 = 0
 
 	itr, err := v4StateDB.Iterator(nil, nil)
 	if err != nil {
 		return err
 	}
+	defer itr.Close()
 	for ; itr.Valid(); itr.Next() {
 		key := itr.Key()
 		value := itr.Value()
@@ -35,6 +35,7 @@ import (
 	didProtoV6 "github.com/ndidplatform/migration-tools/did/v6/protos/data"
 	didProtoV7 "github.com/ndidplatform/migration-tools/did/v7/protos/data"
 	"github.com/ndidplatform/migration-tools/proto"
+	tendermint_0_33_2 "github.com/ndidplatform/migration-tools/tendermint/0_33_2"
 )
 
 var knownKeysV6 []string = []string{
@@ -84,16 +85,23 @@ func ConvertInputStateDBDataV6ToV7AndBackup(
 	saveKeyValue func(key []byte, value []byte) (err error),
 ) (err error) {
 	tmHome := viper.GetString("TM_HOME")
-	currentChainData, err := v6.GetLastestTendermintData(tmHome)
+	backupBlockHeight, err := getBackupBlockHeight()
+	if err != nil {
+		return err
+	}
+	currentChainData, err := v6.GetTendermintDataAtHeight(tmHome, backupBlockHeight)
 	if err != nil {
 		return err
 	}
 
 	dbType := viper.GetString("ABCI_DB_TYPE")
 	dbDir := viper.GetString("ABCI_DB_DIR_PATH")
-	// backupBlockNumberStr := viper.GetString("BLOCK_NUMBER")
 
-	v6StateDB := v6.GetStateDB(dbType, dbDir)
+	v6StateDB, err := v6.GetStateDB(dbType, dbDir)
+	if err != nil {
+		return err
+	}
+	defer v6StateDB.Close()
 	ndidNodeID, err := v6StateDB.Get([]byte("MasterNDID"))
 	if err != nil {
 		return err
@@ -103,6 +111,25 @@ func ConvertInputStateDBDataV6ToV7AndBackup(
 		return v6StateDB.Get(key)
 	}
 
+	currentBlockHeight, err := strconv.ParseInt(currentChainData.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return err
+	}
+	err = checkABCIState(
+		dbGet,
+		currentBlockHeight,
+		func(height int64) (appHash []byte, err error) {
+			tendermintStateInfo, err := tendermint_0_33_2.GetTendermintInfoAtHeight(tmHome, height)
+			if err != nil {
+				return nil, err
+			}
+			return tendermintStateInfo.CommittedAppHash, nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
 	var keyTypeStats map[string]int64 = make(map[string]int64)
 
 	var keysRead int64 = 0
@@ -111,6 +138,7 @@ func ConvertInputStateDBDataV6ToV7AndBackup(
 	if err != nil {
 		return err
 	}
+	defer itr.Close()
 	for ; itr.Valid(); itr.Next() {
 		key := itr.Key()
 		value := itr.Value()
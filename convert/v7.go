@@ -34,6 +34,7 @@ import (
 	v7 "github.com/ndidplatform/migration-tools/did/v7"
 	didProtoV7 "github.com/ndidplatform/migration-tools/did/v7/protos/data"
 	"github.com/ndidplatform/migration-tools/proto"
+	tendermint_0_34_19 "github.com/ndidplatform/migration-tools/tendermint/0_34_19"
 )
 
 var knownKeysV7 []string = []string{
@@ -83,16 +84,23 @@ func ReadInputStateDBDataV7AndBackup(
 	saveKeyValue func(key []byte, value []byte) (err error),
 ) (err error) {
 	tmHome := viper.GetString("TM_HOME")
-	currentChainData, err := v7.GetLastestTendermintData(tmHome)
+	backupBlockHeight, err := getBackupBlockHeight()
+	if err != nil {
+		return err
+	}
+	currentChainData, err := v7.GetTendermintDataAtHeight(tmHome, backupBlockHeight)
 	if err != nil {
 		return err
 	}
 
 	dbType := viper.GetString("ABCI_DB_TYPE")
 	dbDir := viper.GetString("ABCI_DB_DIR_PATH")
-	// backupBlockNumberStr := viper.GetString("BLOCK_NUMBER")
 
-	v7StateDB := v7.GetStateDB(dbType, dbDir)
+	v7StateDB, err := v7.GetStateDB(dbType, dbDir)
+	if err != nil {
+		return err
+	}
+	defer v7StateDB.Close()
 	ndidNodeID, err := v7StateDB.Get([]byte("MasterNDID"))
 	if err != nil {
 		return err
@@ -102,6 +110,25 @@ func ReadInputStateDBDataV7AndBackup(
 		return v7StateDB.Get(key)
 	}
 
+	currentBlockHeight, err := strconv.ParseInt(currentChainData.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return err
+	}
+	err = checkABCIState(
+		dbGet,
+		currentBlockHeight,
+		func(height int64) (appHash []byte, err error) {
+			tendermintStateInfo, err := tendermint_0_34_19.GetTendermintInfoAtHeight(tmHome, height)
+			if err != nil {
+				return nil, err
+			}
+			return tendermintStateInfo.CommittedAppHash, nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
 	var keyTypeStats map[string]int64 = make(map[string]int64)
 
 	var keysRead int64 = 0
@@ -110,6 +137,7 @@ func ReadInputStateDBDataV7AndBackup(
 	if err != nil {
 		return err
 	}
+	defer itr.Close()
 	for ; itr.Valid(); itr.Next() {
 		key := itr.Key()
 		value := itr.Value()
@@ -26,10 +26,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"log"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/spf13/viper"
+	"google.golang.org/protobuf/runtime/protoiface"
 
 	v8 "github.com/ndidplatform/migration-tools/did/v8"
 	didProtoV8 "github.com/ndidplatform/migration-tools/did/v8/protos/data"
@@ -37,6 +39,7 @@ import (
 	didProtoV9 "github.com/ndidplatform/migration-tools/did/v9/protos/data"
 	v9types "github.com/ndidplatform/migration-tools/did/v9/types"
 	"github.com/ndidplatform/migration-tools/proto"
+	tendermint_0_34_19 "github.com/ndidplatform/migration-tools/tendermint/0_34_19"
 )
 
 var knownKeysV8 []string = []string{
@@ -89,16 +92,23 @@ func ConvertInputStateDBDataV8ToV9AndBackup(
 	saveKeyValue func(key []byte, value []byte) (err error),
 ) (err error) {
 	tmHome := viper.GetString("TM_HOME")
-	currentChainData, err := v8.GetLastestTendermintData(tmHome)
+	backupBlockHeight, err := getBackupBlockHeight()
+	if err != nil {
+		return err
+	}
+	currentChainData, err := v8.GetTendermintDataAtHeight(tmHome, backupBlockHeight)
 	if err != nil {
 		return err
 	}
 
 	dbType := viper.GetString("ABCI_DB_TYPE")
 	dbDir := viper.GetString("ABCI_DB_DIR_PATH")
-	// backupBlockNumberStr := viper.GetString("BLOCK_NUMBER")
 
-	v8StateDB := v8.GetStateDB(dbType, dbDir)
+	v8StateDB, err := v8.GetStateDB(dbType, dbDir)
+	if err != nil {
+		return err
+	}
+	defer v8StateDB.Close()
 	ndidNodeID, err := v8StateDB.Get([]byte("MasterNDID"))
 	if err != nil {
 		return err
@@ -108,6 +118,25 @@ func ConvertInputStateDBDataV8ToV9AndBackup(
 		return v8StateDB.Get(key)
 	}
 
+	currentBlockHeight, err := strconv.ParseInt(currentChainData.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return err
+	}
+	err = checkABCIState(
+		dbGet,
+		currentBlockHeight,
+		func(height int64) (appHash []byte, err error) {
+			tendermintStateInfo, err := tendermint_0_34_19.GetTendermintInfoAtHeight(tmHome, height)
+			if err != nil {
+				return nil, err
+			}
+			return tendermintStateInfo.CommittedAppHash, nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
 	var keyTypeStats map[string]int64 = make(map[string]int64)
 
 	var keysRead int64 = 0
@@ -116,6 +145,7 @@ func ConvertInputStateDBDataV8ToV9AndBackup(
 	if err != nil {
 		return err
 	}
+	defer itr.Close()
 	for ; itr.Valid(); itr.Next() {
 		key := itr.Key()
 		value := itr.Value()
@@ -250,31 +280,87 @@ func ConvertStateDBDataV8ToV9(
 		if nodeDetailV8.OnTheFlySupport {
 			supportedFeatureListV9 = append(supportedFeatureListV9, nodeSupportedFeatureOnTheFly)
 		}
-		nodeDetailV9 := didProtoV9.NodeDetail{
-			SigningPublicKey: &didProtoV9.NodeKey{
-				PublicKey:           nodeDetailV8.PublicKey,
-				Algorithm:           string(v9types.SignatureAlgorithmRSAPKCS1V15SHA256),
-				Version:             1,
-				CreationBlockHeight: 0,
-				CreationChainId:     "",
-				Active:              true,
+		// Creation block height and chain ID are backfilled from block
+		// archives or chain history
+		creationIndex, err := GetV9CreationIndex()
+		if err != nil {
+			return "", err
+		}
+		convertedNodeKeys := []struct {
+			keyType    string
+			paramField string
+			nodeKey    *didProtoV9.NodeKey
+		}{
+			{
+				nodeKeyTypeSigning,
+				"public_key",
+				&didProtoV9.NodeKey{
+					PublicKey: nodeDetailV8.PublicKey,
+					Algorithm: string(v9types.SignatureAlgorithmRSAPKCS1V15SHA256),
+					Version:   1,
+					Active:    true,
+				},
 			},
-			SigningMasterPublicKey: &didProtoV9.NodeKey{
-				PublicKey:           nodeDetailV8.MasterPublicKey,
-				Algorithm:           string(v9types.SignatureAlgorithmRSAPKCS1V15SHA256),
-				Version:             1,
-				CreationBlockHeight: 0,
-				CreationChainId:     "",
-				Active:              true,
+			{
+				nodeKeyTypeSigningMaster,
+				"master_public_key",
+				&didProtoV9.NodeKey{
+					PublicKey: nodeDetailV8.MasterPublicKey,
+					Algorithm: string(v9types.SignatureAlgorithmRSAPKCS1V15SHA256),
+					Version:   1,
+					Active:    true,
+				},
 			},
-			EncryptionPublicKey: &didProtoV9.NodeKey{
-				PublicKey:           nodeDetailV8.PublicKey,
-				Algorithm:           "RSAES_PKCS1_V1_5",
-				Version:             1,
-				CreationBlockHeight: 0,
-				CreationChainId:     "",
-				Active:              true,
+			{
+				nodeKeyTypeEncryption,
+				"public_key",
+				&didProtoV9.NodeKey{
+					PublicKey: nodeDetailV8.PublicKey,
+					Algorithm: nodeEncryptionAlgorithmRSAPKCS1V15,
+					Version:   1,
+					Active:    true,
+				},
 			},
+		}
+		for _, convertedNodeKey := range convertedNodeKeys {
+			err = creationIndex.backfillNodeKey(
+				v9.NodeKeyKeyPrefix+v9.KeySeparator+
+					convertedNodeKey.keyType+v9.KeySeparator+
+					nodeID+v9.KeySeparator+
+					strconv.FormatInt(convertedNodeKey.nodeKey.Version, 10),
+				nodeID,
+				convertedNodeKey.paramField,
+				convertedNodeKey.nodeKey,
+				dbGet,
+				currentChainData,
+			)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		// Keys in node key map are added as new active version
+		nodeKeyMap, err := GetNodeKeyMap()
+		if err != nil {
+			return "", err
+		}
+		signingKeyHistory := rotateNodeKey(
+			convertedNodeKeys[0].nodeKey,
+			nodeKeyMap.nodeKey(nodeID, nodeKeyTypeSigning),
+		)
+		signingMasterKeyHistory := rotateNodeKey(
+			convertedNodeKeys[1].nodeKey,
+			nodeKeyMap.nodeKey(nodeID, nodeKeyTypeSigningMaster),
+		)
+		encryptionKeyHistory := rotateNodeKey(
+			convertedNodeKeys[2].nodeKey,
+			nodeKeyMap.nodeKey(nodeID, nodeKeyTypeEncryption),
+		)
+
+		nodeDetailV9 := didProtoV9.NodeDetail{
+			SigningPublicKey:                       signingKeyHistory[len(signingKeyHistory)-1],
+			SigningMasterPublicKey:                 signingMasterKeyHistory[len(signingMasterKeyHistory)-1],
+			EncryptionPublicKey:                    encryptionKeyHistory[len(encryptionKeyHistory)-1],
 			NodeName:                               nodeDetailV8.NodeName,
 			Role:                                   nodeDetailV8.Role,
 			MaxIal:                                 nodeDetailV8.MaxIal,
@@ -303,46 +389,32 @@ func ConvertStateDBDataV8ToV9(
 		// key history
 		//
 
-		nodeKeyKey :=
-			v9.NodeKeyKeyPrefix + v9.KeySeparator +
-				"signing" + v9.KeySeparator +
-				nodeID + v9.KeySeparator +
-				strconv.FormatInt(nodeDetailV9.SigningPublicKey.Version, 10)
-		nodeKeyV9Byte, err := proto.DeterministicMarshal(nodeDetailV9.SigningPublicKey)
-		if err != nil {
-			panic(err)
-		}
-		err = saveKeyValue([]byte(nodeKeyKey), nodeKeyV9Byte)
-		if err != nil {
-			return "", err
-		}
-
-		nodeKeyKey =
-			v9.NodeKeyKeyPrefix + v9.KeySeparator +
-				"signing_master" + v9.KeySeparator +
-				nodeID + v9.KeySeparator +
-				strconv.FormatInt(nodeDetailV9.SigningMasterPublicKey.Version, 10)
-		nodeKeyV9Byte, err = proto.DeterministicMarshal(nodeDetailV9.SigningMasterPublicKey)
-		if err != nil {
-			panic(err)
-		}
-		err = saveKeyValue([]byte(nodeKeyKey), nodeKeyV9Byte)
-		if err != nil {
-			return "", err
-		}
-
-		nodeKeyKey =
-			v9.NodeKeyKeyPrefix + v9.KeySeparator +
-				"encryption" + v9.KeySeparator +
-				nodeID + v9.KeySeparator +
-				strconv.FormatInt(nodeDetailV9.EncryptionPublicKey.Version, 10)
-		nodeKeyV9Byte, err = proto.DeterministicMarshal(nodeDetailV9.EncryptionPublicKey)
-		if err != nil {
-			panic(err)
-		}
-		err = saveKeyValue([]byte(nodeKeyKey), nodeKeyV9Byte)
-		if err != nil {
-			return "", err
+		for _, nodeKeys := range []struct {
+			keyType string
+			history []*didProtoV9.NodeKey
+		}{
+			{nodeKeyTypeSigning, signingKeyHistory},
+			{nodeKeyTypeSigningMaster, signingMasterKeyHistory},
+			{nodeKeyTypeEncryption, encryptionKeyHistory},
+		} {
+			if len(nodeKeys.history) > 1 {
+				log.Println("node key rotated:", nodeID, nodeKeys.keyType)
+			}
+			for _, nodeKey := range nodeKeys.history {
+				nodeKeyKey :=
+					v9.NodeKeyKeyPrefix + v9.KeySeparator +
+						nodeKeys.keyType + v9.KeySeparator +
+						nodeID + v9.KeySeparator +
+						strconv.FormatInt(nodeKey.Version, 10)
+				nodeKeyV9Byte, err := proto.DeterministicMarshal(nodeKey)
+				if err != nil {
+					panic(err)
+				}
+				err = saveKeyValue([]byte(nodeKeyKey), nodeKeyV9Byte)
+				if err != nil {
+					return "", err
+				}
+			}
 		}
 	case strings.HasPrefix(string(key), "RefGroupCode"):
 		keyType = "RefGroupCode"
@@ -364,19 +436,28 @@ func ConvertStateDBDataV8ToV9(
 			})
 		}
 
+		creationIndex, err := GetV9CreationIndex()
+		if err != nil {
+			return "", err
+		}
+
 		refGroupV9Idps := make([]*didProtoV9.IdPInRefGroup, 0)
 		for _, idp := range refGroupV8.Idps {
 			accessorsV9 := make([]*didProtoV9.Accessor, 0)
 			for _, accessor := range idp.Accessors {
-				accessorsV9 = append(accessorsV9, &didProtoV9.Accessor{
-					AccessorId:          accessor.AccessorId,
-					AccessorType:        accessor.AccessorType,
-					AccessorPublicKey:   accessor.AccessorPublicKey,
-					Active:              accessor.Active,
-					Owner:               accessor.Owner,
-					CreationBlockHeight: 0,
-					CreationChainId:     "",
-				})
+				accessorV9 := &didProtoV9.Accessor{
+					AccessorId:        accessor.AccessorId,
+					AccessorType:      accessor.AccessorType,
+					AccessorPublicKey: accessor.AccessorPublicKey,
+					Active:            accessor.Active,
+					Owner:             accessor.Owner,
+				}
+				// Backfill creation block height and chain ID
+				err = creationIndex.backfillAccessor(string(key), accessorV9, dbGet, currentChainData)
+				if err != nil {
+					return "", err
+				}
+				accessorsV9 = append(accessorsV9, accessorV9)
 			}
 
 			refGroupV9Idps = append(refGroupV9Idps, &didProtoV9.IdPInRefGroup{
@@ -471,6 +552,45 @@ func ConvertStateDBDataV8ToV9(
 	// 	}
 	// AS response to Request
 	// Do not save
+	case string(key) == string(v9.AllNamespaceKeyBytes):
+		// Add namespaces from seed not already in list
+		seed, err := GetV9Seed()
+		if err != nil {
+			return "", err
+		}
+		var namespaceList didProtoV9.NamespaceList
+		if err := proto.Unmarshal([]byte(value), &namespaceList); err != nil {
+			panic(err)
+		}
+		seed.mergeNamespaceList(&namespaceList)
+		newValue, err := proto.DeterministicMarshal(&namespaceList)
+		if err != nil {
+			return "", err
+		}
+		err = saveKeyValue(key, newValue)
+		if err != nil {
+			return "", err
+		}
+	case strings.HasPrefix(string(key), v9.ErrorCodeListKeyPrefix+v9.KeySeparator):
+		// Add error codes from seed not already in list
+		seed, err := GetV9Seed()
+		if err != nil {
+			return "", err
+		}
+		errorCodeType := strings.TrimPrefix(string(key), v9.ErrorCodeListKeyPrefix+v9.KeySeparator)
+		var errorCodeList didProtoV9.ErrorCodeList
+		if err := proto.Unmarshal([]byte(value), &errorCodeList); err != nil {
+			panic(err)
+		}
+		seed.mergeErrorCodeList(errorCodeType, &errorCodeList)
+		newValue, err := proto.DeterministicMarshal(&errorCodeList)
+		if err != nil {
+			return "", err
+		}
+		err = saveKeyValue(key, newValue)
+		if err != nil {
+			return "", err
+		}
 	case strings.HasPrefix(string(key), "n") && len(value) == 0:
 		// nonce
 		// Do not save
@@ -502,25 +622,49 @@ func AddNewStateDataToV9(
 	saveNewChainHistory func(chainHistory []byte) (err error),
 	saveKeyValue func(key []byte, value []byte) (err error),
 ) (keyType string, err error) {
+	seed, err := GetV9Seed()
+	if err != nil {
+		return "", err
+	}
+
+	// saveIfNotExist saves key only when not already in source state data
+	saveIfNotExist := func(key []byte, m protoiface.MessageV1, description ...interface{}) (err error) {
+		existingValue, err := getSourceValue(dbGet, key)
+		if err != nil {
+			return err
+		}
+		if existingValue != nil {
+			log.Println(append([]interface{}{"skipping new state data (already exists):"}, description...)...)
+			return nil
+		}
+		log.Println(append([]interface{}{"adding new state data:"}, description...)...)
+		value, err := proto.DeterministicMarshal(m)
+		if err != nil {
+			return err
+		}
+		return saveKeyValue(key, value)
+	}
 
 	//
-	log.Println("adding new state data:", "node supported feature:", nodeSupportedFeatureOnTheFly)
+	for _, feature := range seed.SupportedFeatures {
+		log.Println("adding new state data:", "node supported feature:", feature)
 
-	key := v9.NodeSupportedFeatureKeyPrefix + v9.KeySeparator + nodeSupportedFeatureOnTheFly
+		key := v9.NodeSupportedFeatureKeyPrefix + v9.KeySeparator + feature
 
-	var nodeSupportedFeature didProtoV9.NodeSupportedFeature
-	value, err := proto.DeterministicMarshal(&nodeSupportedFeature)
-	if err != nil {
-		return "", err
-	}
+		var nodeSupportedFeature didProtoV9.NodeSupportedFeature
+		value, err := proto.DeterministicMarshal(&nodeSupportedFeature)
+		if err != nil {
+			return "", err
+		}
 
-	err = saveKeyValue([]byte(key), value)
-	if err != nil {
-		return "", err
+		err = saveKeyValue([]byte(key), value)
+		if err != nil {
+			return "", err
+		}
 	}
 
 	//
-	ialList := []float64{1.1, 1.2, 1.3, 2.1, 2.2, 2.3, 3}
+	ialList := seed.SupportedIALList
 	log.Println("adding new state data:", "supported IAL list:", ialList)
 
 	var supportedIALList didProtoV9.SupportedIALList
@@ -536,7 +680,7 @@ func AddNewStateDataToV9(
 	}
 
 	//
-	aalList := []float64{1, 2.1, 2.2, 3}
+	aalList := seed.SupportedAALList
 	log.Println("adding new state data:", "supported AAL list:", aalList)
 
 	var supportedAALList didProtoV9.SupportedAALList
@@ -551,6 +695,59 @@ func AddNewStateDataToV9(
 		return "", err
 	}
 
+	// Error codes (lists already in source are merged on conversion)
+	for _, errorCodeType := range seed.errorCodeTypes() {
+		for _, errorCode := range seed.ErrorCodes[errorCodeType] {
+			key := v9.ErrorCodeKeyPrefix + v9.KeySeparator + errorCodeType + v9.KeySeparator + strconv.FormatInt(int64(errorCode.ErrorCode), 10)
+			err = saveIfNotExist([]byte(key), errorCode, "error code:", errorCodeType, errorCode.ErrorCode)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		key := v9.ErrorCodeListKeyPrefix + v9.KeySeparator + errorCodeType
+		var errorCodeList didProtoV9.ErrorCodeList
+		seed.mergeErrorCodeList(errorCodeType, &errorCodeList)
+		err = saveIfNotExist([]byte(key), &errorCodeList, "error code list:", errorCodeType)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	//
+	for _, requestType := range seed.RequestTypes {
+		key := v9.RequestTypeKeyPrefix + v9.KeySeparator + requestType
+		var requestTypeValue didProtoV9.RequestType
+		err = saveIfNotExist([]byte(key), &requestTypeValue, "request type:", requestType)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Namespaces (list already in source is merged on conversion)
+	if len(seed.Namespaces) > 0 {
+		var namespaceList didProtoV9.NamespaceList
+		seed.mergeNamespaceList(&namespaceList)
+		err = saveIfNotExist(v9.AllNamespaceKeyBytes, &namespaceList, "namespace list")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	//
+	serviceIDs := make([]string, 0, len(seed.ServicePriceCeilings))
+	for serviceID := range seed.ServicePriceCeilings {
+		serviceIDs = append(serviceIDs, serviceID)
+	}
+	sort.Strings(serviceIDs)
+	for _, serviceID := range serviceIDs {
+		key := v9.ServicePriceCeilingKeyPrefix + v9.KeySeparator + serviceID
+		err = saveIfNotExist([]byte(key), seed.ServicePriceCeilings[serviceID], "service price ceiling:", serviceID)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	return "", nil
 }
 
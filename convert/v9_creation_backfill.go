@@ -0,0 +1,403 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+
+	"github.com/ndidplatform/migration-tools/blockarchive"
+	v8 "github.com/ndidplatform/migration-tools/did/v8"
+	protoTmV8 "github.com/ndidplatform/migration-tools/did/v8/protos/tendermint"
+	didProtoV9 "github.com/ndidplatform/migration-tools/did/v9/protos/data"
+	"github.com/ndidplatform/migration-tools/proto"
+)
+
+// Sources of backfilled creation block height and chain ID
+const (
+	CreationSourceBlockArchive = "block_archive" // tx found in block archive
+	CreationSourceChainHistory = "chain_history" // chain ID only, single chain in chain history
+	CreationSourceNone         = "none"
+)
+
+// Methods of txs which create accessor (with "accessor_id" param)
+var accessorCreationTxMethods = map[string]bool{
+	"RegisterIdentity":     true,
+	"AddAccessor":          true,
+	"RevokeAndAddAccessor": true,
+}
+
+// CreationInfo is block height and chain ID of tx which created data
+type CreationInfo struct {
+	BlockHeight int64
+	ChainID     string
+}
+
+type nodeKeyCreation struct {
+	PublicKey string
+	CreationInfo
+}
+
+type creationTxParam struct {
+	NodeID          string `json:"node_id"`
+	PublicKey       string `json:"public_key"`
+	MasterPublicKey string `json:"master_public_key"`
+	AccessorID      string `json:"accessor_id"`
+}
+
+// CreationBackfillIssue is a v9 NodeKey or Accessor with creation fields which
+// could not be backfilled
+type CreationBackfillIssue struct {
+	Key        string   `json:"key"`
+	AccessorID string   `json:"accessor_id,omitempty"`
+	Missing    []string `json:"missing"`
+}
+
+// CreationIndex is creation of accessors and node keys found in block archives,
+// used to backfill creation block height and chain ID of v9 Accessor and
+// NodeKey
+type CreationIndex struct {
+	accessors map[string]CreationInfo               // by accessor ID, first created
+	nodeKeys  map[string]map[string]nodeKeyCreation // by node ID and param field, last set
+
+	mutex        sync.Mutex
+	report       io.Writer
+	sourceCounts map[string]int64
+
+	// Chain ID of single chain in chain history, read once per conversion
+	singleChainID       string
+	singleChainIDLoaded bool
+}
+
+var creationIndexCache struct {
+	sync.Mutex
+	archiveDirs string
+	index       *CreationIndex
+}
+
+// BuildCreationIndex scans txs in block archives (oldest chain first)
+func BuildCreationIndex(archiveDirs []string) (index *CreationIndex, err error) {
+	index = &CreationIndex{
+		accessors:    make(map[string]CreationInfo),
+		nodeKeys:     make(map[string]map[string]nodeKeyCreation),
+		sourceCounts: make(map[string]int64),
+	}
+	for _, archiveDir := range archiveDirs {
+		err = index.scanArchive(archiveDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+func (index *CreationIndex) scanArchive(archiveDir string) (err error) {
+	archive, err := blockarchive.Open(archiveDir)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	info := archive.Info()
+	log.Println("scanning block archive:", archiveDir, "chain ID:", info.ChainID,
+		"height:", info.BaseHeight, "-", info.LastHeight)
+
+	var txCount int64 = 0
+	if info.BlockCount > 0 {
+		for height := info.BaseHeight; height <= info.LastHeight; height++ {
+			record, err := archive.GetBlock(height)
+			if err != nil {
+				if errors.Is(err, blockarchive.ErrNotFound) {
+					continue
+				}
+				return err
+			}
+			for _, txRecord := range record.Txs {
+				if txRecord.Result != nil && txRecord.Result.Code != 0 {
+					// Failed tx
+					continue
+				}
+				index.addTx(txRecord.Tx, CreationInfo{
+					BlockHeight: height,
+					ChainID:     info.ChainID,
+				})
+				txCount++
+			}
+		}
+	}
+
+	log.Println("block archive scanned:", archiveDir, "txs:", txCount,
+		"accessors:", len(index.accessors), "nodes:", len(index.nodeKeys))
+	return nil
+}
+
+func (index *CreationIndex) addTx(txBytes []byte, creation CreationInfo) {
+	var tx protoTmV8.Tx
+	if err := proto.Unmarshal(txBytes, &tx); err != nil {
+		// Not an NDID tx of supported format
+		return
+	}
+	var param creationTxParam
+	if err := json.Unmarshal(tx.Params, &param); err != nil {
+		return
+	}
+
+	switch tx.Method {
+	case "InitNDID", "RegisterNode":
+		index.setNodeKeys(param.NodeID, param, creation)
+	case "UpdateNode":
+		index.setNodeKeys(tx.NodeId, param, creation)
+	default:
+		if param.AccessorID == "" || !accessorCreationTxMethods[tx.Method] {
+			return
+		}
+		if _, exists := index.accessors[param.AccessorID]; !exists {
+			index.accessors[param.AccessorID] = creation
+		}
+	}
+}
+
+func (index *CreationIndex) setNodeKeys(nodeID string, param creationTxParam, creation CreationInfo) {
+	if nodeID == "" {
+		return
+	}
+	if index.nodeKeys[nodeID] == nil {
+		index.nodeKeys[nodeID] = make(map[string]nodeKeyCreation)
+	}
+	if param.PublicKey != "" {
+		index.nodeKeys[nodeID]["public_key"] = nodeKeyCreation{param.PublicKey, creation}
+	}
+	if param.MasterPublicKey != "" {
+		index.nodeKeys[nodeID]["master_public_key"] = nodeKeyCreation{param.MasterPublicKey, creation}
+	}
+}
+
+// GetV9CreationIndex returns creation index of block archives set in
+// BACKFILL_BLOCK_ARCHIVES (comma separated, oldest chain first), empty index
+// if not set
+func GetV9CreationIndex() (index *CreationIndex, err error) {
+	archiveDirs := viper.GetString("BACKFILL_BLOCK_ARCHIVES")
+
+	creationIndexCache.Lock()
+	defer creationIndexCache.Unlock()
+	if creationIndexCache.index != nil && creationIndexCache.archiveDirs == archiveDirs {
+		return creationIndexCache.index, nil
+	}
+
+	var archiveDirList []string
+	for _, archiveDir := range strings.Split(archiveDirs, ",") {
+		archiveDir = strings.TrimSpace(archiveDir)
+		if archiveDir != "" {
+			archiveDirList = append(archiveDirList, archiveDir)
+		}
+	}
+	index, err = BuildCreationIndex(archiveDirList)
+	if err != nil {
+		return nil, err
+	}
+	creationIndexCache.archiveDirs = archiveDirs
+	creationIndexCache.index = index
+	return index, nil
+}
+
+// SetReport sets writer of JSON line report of creation fields which could
+// not be backfilled, nil to stop writing report
+func (index *CreationIndex) SetReport(report io.Writer) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	index.report = report
+	index.sourceCounts = make(map[string]int64)
+}
+
+// StartConversion clears chain ID read from chain history of previous
+// conversion, must be called before converting other source state data
+func (index *CreationIndex) StartConversion() {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	index.singleChainID = ""
+	index.singleChainIDLoaded = false
+}
+
+// LogReportSummary logs number of backfilled NodeKey and Accessor by source
+func (index *CreationIndex) LogReportSummary() {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	log.Println("creation block height and chain ID backfilled by source:", index.sourceCounts)
+}
+
+// nodeKeyCreation returns creation of node key if key set by param field
+// ("public_key" or "master_public_key") is the same as publicKey
+func (index *CreationIndex) nodeKeyCreation(nodeID string, paramField string, publicKey string) *CreationInfo {
+	nodeKey, ok := index.nodeKeys[nodeID][paramField]
+	if !ok || strings.TrimSpace(nodeKey.PublicKey) != strings.TrimSpace(publicKey) {
+		return nil
+	}
+	return &nodeKey.CreationInfo
+}
+
+func (index *CreationIndex) accessorCreation(accessorID string) *CreationInfo {
+	accessor, ok := index.accessors[accessorID]
+	if !ok {
+		return nil
+	}
+	return &accessor
+}
+
+// backfill returns creation found in index or chain ID from chain history
+// when there is only one chain. Fields which could not be backfilled are
+// written to report.
+//
+// Creation of request or message (v8 Request/Message CreationBlockHeight and
+// ChainId) cannot be used as fallback: v8 state data has no link from accessor
+// or node key to request. Request ID of accessor is only in params of
+// RegisterIdentity/AddAccessor/RevokeAndAddAccessor tx, and when that tx is
+// in block archive its own block height and chain ID are used.
+func (index *CreationIndex) backfill(
+	key string,
+	accessorID string,
+	found *CreationInfo,
+	dbGet func(key []byte) (value []byte, err error),
+	currentChainData *v8.ChainHistoryDetail,
+) (creation CreationInfo, err error) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	source := CreationSourceBlockArchive
+	if found != nil {
+		creation = *found
+	} else {
+		if !index.singleChainIDLoaded {
+			index.singleChainID, err = singleChainID(dbGet, currentChainData)
+			if err != nil {
+				return CreationInfo{}, err
+			}
+			index.singleChainIDLoaded = true
+		}
+		creation.ChainID = index.singleChainID
+		source = CreationSourceNone
+		if creation.ChainID != "" {
+			source = CreationSourceChainHistory
+		}
+	}
+
+	index.sourceCounts[source]++
+	if index.report == nil || found != nil {
+		return creation, nil
+	}
+	issue := CreationBackfillIssue{
+		Key:        key,
+		AccessorID: accessorID,
+		Missing:    []string{"creation_block_height"},
+	}
+	if creation.ChainID == "" {
+		issue.Missing = append(issue.Missing, "creation_chain_id")
+	}
+	issueJSON, err := json.Marshal(issue)
+	if err != nil {
+		return CreationInfo{}, err
+	}
+	_, err = index.report.Write(append(issueJSON, '\n'))
+	if err != nil {
+		return CreationInfo{}, err
+	}
+	return creation, nil
+}
+
+// singleChainID returns chain ID when chain history (with current chain) has
+// only one chain (all data was created on that chain), empty string otherwise
+func singleChainID(
+	dbGet func(key []byte) (value []byte, err error),
+	currentChainData *v8.ChainHistoryDetail,
+) (chainID string, err error) {
+	chainHistoryValue, err := getSourceValue(dbGet, []byte("ChainHistoryInfo"))
+	if err != nil {
+		return "", err
+	}
+	var chainHistory v8.ChainHistory
+	if len(chainHistoryValue) > 0 {
+		err = json.Unmarshal(chainHistoryValue, &chainHistory)
+		if err != nil {
+			return "", err
+		}
+	}
+	if currentChainData != nil {
+		chainHistory.Chains = append(chainHistory.Chains, *currentChainData)
+	}
+	if len(chainHistory.Chains) != 1 {
+		return "", nil
+	}
+	return chainHistory.Chains[0].ChainID, nil
+}
+
+// backfillNodeKey sets creation of node key converted from key set by param
+// field ("public_key" or "master_public_key")
+func (index *CreationIndex) backfillNodeKey(
+	key string,
+	nodeID string,
+	paramField string,
+	nodeKey *didProtoV9.NodeKey,
+	dbGet func(key []byte) (value []byte, err error),
+	currentChainData *v8.ChainHistoryDetail,
+) (err error) {
+	creation, err := index.backfill(
+		key,
+		"",
+		index.nodeKeyCreation(nodeID, paramField, nodeKey.PublicKey),
+		dbGet,
+		currentChainData,
+	)
+	if err != nil {
+		return err
+	}
+	nodeKey.CreationBlockHeight = creation.BlockHeight
+	nodeKey.CreationChainId = creation.ChainID
+	return nil
+}
+
+// backfillAccessor sets creation of accessor in reference group
+func (index *CreationIndex) backfillAccessor(
+	key string,
+	accessor *didProtoV9.Accessor,
+	dbGet func(key []byte) (value []byte, err error),
+	currentChainData *v8.ChainHistoryDetail,
+) (err error) {
+	creation, err := index.backfill(
+		key,
+		accessor.AccessorId,
+		index.accessorCreation(accessor.AccessorId),
+		dbGet,
+		currentChainData,
+	)
+	if err != nil {
+		return err
+	}
+	accessor.CreationBlockHeight = creation.BlockHeight
+	accessor.CreationChainId = creation.ChainID
+	return nil
+}
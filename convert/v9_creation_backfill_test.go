@@ -0,0 +1,72 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package convert
+
+import (
+	"testing"
+
+	v8 "github.com/ndidplatform/migration-tools/did/v8"
+	didProtoV9 "github.com/ndidplatform/migration-tools/did/v9/protos/data"
+)
+
+func TestBackfillReadsChainHistoryOncePerConversion(t *testing.T) {
+	index, err := BuildCreationIndex(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chainHistoryReads := 0
+	dbGet := func(key []byte) (value []byte, err error) {
+		if string(key) == "ChainHistoryInfo" {
+			chainHistoryReads++
+		}
+		return nil, nil
+	}
+	currentChainData := &v8.ChainHistoryDetail{ChainID: "chain-1"}
+
+	index.StartConversion()
+	for _, accessorID := range []string{"accessor1", "accessor2", "accessor3"} {
+		accessor := &didProtoV9.Accessor{AccessorId: accessorID}
+		err = index.backfillAccessor("RefGroupCode|ref1", accessor, dbGet, currentChainData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accessor.CreationChainId != "chain-1" {
+			t.Errorf("%s: chain ID: %q, expected: chain-1", accessorID, accessor.CreationChainId)
+		}
+	}
+	// Chain history is read for first accessor only
+	if chainHistoryReads != 1 {
+		t.Errorf("chain history read: %d times, expected: 1", chainHistoryReads)
+	}
+
+	index.StartConversion()
+	accessor := &didProtoV9.Accessor{AccessorId: "accessor1"}
+	err = index.backfillAccessor("RefGroupCode|ref1", accessor, dbGet, &v8.ChainHistoryDetail{ChainID: "chain-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accessor.CreationChainId != "chain-2" {
+		t.Errorf("chain ID after new conversion: %q, expected: chain-2", accessor.CreationChainId)
+	}
+}
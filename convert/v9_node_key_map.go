@@ -0,0 +1,309 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package convert
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+
+	v9 "github.com/ndidplatform/migration-tools/did/v9"
+	didProtoV9 "github.com/ndidplatform/migration-tools/did/v9/protos/data"
+	v9types "github.com/ndidplatform/migration-tools/did/v9/types"
+)
+
+const nodeEncryptionAlgorithmRSAPKCS1V15 = "RSAES_PKCS1_V1_5"
+
+// Node key types in NodeKey history key
+const (
+	nodeKeyTypeSigning       = "signing"
+	nodeKeyTypeSigningMaster = "signing_master"
+	nodeKeyTypeEncryption    = "encryption"
+)
+
+// NodeKeyMapKey is public key (PEM) with algorithm replacing node key on
+// conversion to v9
+type NodeKeyMapKey struct {
+	PublicKey     string `yaml:"public_key"`
+	PublicKeyFile string `yaml:"public_key_file"` // relative to key map file
+	Algorithm     string `yaml:"algorithm"`
+}
+
+// NodeKeyMapNode is new keys of a node, keys not set are converted from source
+type NodeKeyMapNode struct {
+	Signing       *NodeKeyMapKey `yaml:"signing"`
+	SigningMaster *NodeKeyMapKey `yaml:"signing_master"`
+	Encryption    *NodeKeyMapKey `yaml:"encryption"`
+}
+
+// NodeKeyMap is new keys by node ID
+type NodeKeyMap map[string]*NodeKeyMapNode
+
+type nodeKeyMapFile struct {
+	Nodes NodeKeyMap `yaml:"nodes"`
+}
+
+var nodeKeyMapCache struct {
+	sync.Mutex
+	path     string
+	keyMap   NodeKeyMap
+	isLoaded bool
+}
+
+// LoadNodeKeyMap reads key map file, reads public key files and validates keys
+func LoadNodeKeyMap(path string) (keyMap NodeKeyMap, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keyMapFile nodeKeyMapFile
+	err = yaml.UnmarshalStrict(data, &keyMapFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(keyMapFile.Nodes) == 0 {
+		return nil, fmt.Errorf("%s: no nodes", path)
+	}
+
+	for _, nodeID := range keyMapFile.Nodes.nodeIDs() {
+		node := keyMapFile.Nodes[nodeID]
+		if node == nil || (node.Signing == nil && node.SigningMaster == nil && node.Encryption == nil) {
+			return nil, fmt.Errorf("%s: nodes.%s: no keys", path, nodeID)
+		}
+		for _, key := range []struct {
+			keyType string
+			key     *NodeKeyMapKey
+		}{
+			{nodeKeyTypeSigning, node.Signing},
+			{nodeKeyTypeSigningMaster, node.SigningMaster},
+			{nodeKeyTypeEncryption, node.Encryption},
+		} {
+			if key.key == nil {
+				continue
+			}
+			err = key.key.load(filepath.Dir(path))
+			if err != nil {
+				return nil, fmt.Errorf("%s: nodes.%s.%s: %w", path, nodeID, key.keyType, err)
+			}
+			err = key.key.validate(key.keyType == nodeKeyTypeEncryption)
+			if err != nil {
+				return nil, fmt.Errorf("%s: nodes.%s.%s: %w", path, nodeID, key.keyType, err)
+			}
+		}
+	}
+
+	return keyMapFile.Nodes, nil
+}
+
+// load reads public key file if set
+func (key *NodeKeyMapKey) load(baseDir string) (err error) {
+	if key.PublicKeyFile == "" {
+		if key.PublicKey == "" {
+			return errors.New("one of public_key or public_key_file is required")
+		}
+		return nil
+	}
+	if key.PublicKey != "" {
+		return errors.New("only one of public_key or public_key_file can be set")
+	}
+	publicKeyFilePath := key.PublicKeyFile
+	if !filepath.IsAbs(publicKeyFilePath) {
+		publicKeyFilePath = filepath.Join(baseDir, publicKeyFilePath)
+	}
+	publicKey, err := os.ReadFile(publicKeyFilePath)
+	if err != nil {
+		return err
+	}
+	key.PublicKey = string(publicKey)
+	key.PublicKeyFile = ""
+	return nil
+}
+
+// validate checks that public key PEM parses and key type matches algorithm
+func (key *NodeKeyMapKey) validate(isEncryptionKey bool) (err error) {
+	publicKey, err := parsePublicKeyPEM(key.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	if isEncryptionKey {
+		if key.Algorithm != nodeEncryptionAlgorithmRSAPKCS1V15 {
+			return fmt.Errorf("unsupported encryption algorithm %q", key.Algorithm)
+		}
+		if _, ok := publicKey.(*rsa.PublicKey); !ok {
+			return fmt.Errorf("%s requires RSA public key", key.Algorithm)
+		}
+		return nil
+	}
+
+	switch v9types.SignatureAlgorithm(key.Algorithm) {
+	case v9types.SignatureAlgorithmRSAPSSSHA256,
+		v9types.SignatureAlgorithmRSAPSSSHA384,
+		v9types.SignatureAlgorithmRSAPSSSHA512,
+		v9types.SignatureAlgorithmRSAPKCS1V15SHA256,
+		v9types.SignatureAlgorithmRSAPKCS1V15SHA384,
+		v9types.SignatureAlgorithmRSAPKCS1V15SHA512:
+		if _, ok := publicKey.(*rsa.PublicKey); !ok {
+			return fmt.Errorf("%s requires RSA public key", key.Algorithm)
+		}
+	case v9types.SignatureAlgorithmECDSASHA256,
+		v9types.SignatureAlgorithmECDSASHA384:
+		if _, ok := publicKey.(*ecdsa.PublicKey); !ok {
+			return fmt.Errorf("%s requires ECDSA public key", key.Algorithm)
+		}
+	case v9types.SignatureAlgorithmEd25519:
+		if _, ok := publicKey.(ed25519.PublicKey); !ok {
+			return fmt.Errorf("%s requires Ed25519 public key", key.Algorithm)
+		}
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", key.Algorithm)
+	}
+	return nil
+}
+
+func parsePublicKeyPEM(publicKeyPEM string) (publicKey interface{}, err error) {
+	block, rest := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, errors.New("invalid public key PEM")
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return nil, errors.New("unexpected data after public key PEM block")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("unsupported public key PEM type %q", block.Type)
+}
+
+func (keyMap NodeKeyMap) nodeIDs() []string {
+	nodeIDs := make([]string, 0, len(keyMap))
+	for nodeID := range keyMap {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+	return nodeIDs
+}
+
+// nodeKey returns new key of node ID and key type, nil if not in key map
+func (keyMap NodeKeyMap) nodeKey(nodeID string, keyType string) *NodeKeyMapKey {
+	node := keyMap[nodeID]
+	if node == nil {
+		return nil
+	}
+	switch keyType {
+	case nodeKeyTypeSigning:
+		return node.Signing
+	case nodeKeyTypeSigningMaster:
+		return node.SigningMaster
+	case nodeKeyTypeEncryption:
+		return node.Encryption
+	}
+	return nil
+}
+
+// GetNodeKeyMap returns key map from file set in V9_NODE_KEY_MAP_FILE or nil
+// if not set
+func GetNodeKeyMap() (keyMap NodeKeyMap, err error) {
+	path := viper.GetString("V9_NODE_KEY_MAP_FILE")
+
+	nodeKeyMapCache.Lock()
+	defer nodeKeyMapCache.Unlock()
+	if nodeKeyMapCache.isLoaded && nodeKeyMapCache.path == path {
+		return nodeKeyMapCache.keyMap, nil
+	}
+
+	if path != "" {
+		keyMap, err = LoadNodeKeyMap(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	nodeKeyMapCache.path = path
+	nodeKeyMapCache.keyMap = keyMap
+	nodeKeyMapCache.isLoaded = true
+	return keyMap, nil
+}
+
+// ValidateNodeKeyMapNodes checks that nodes in key map are in source state
+// data and are not NDID node (NDID node is not converted, set keys on restore
+// instead). dbGet returns nil value when key is not found.
+func ValidateNodeKeyMapNodes(
+	keyMap NodeKeyMap,
+	dbGet func(key []byte) (value []byte, err error),
+) (err error) {
+	if len(keyMap) == 0 {
+		return nil
+	}
+	ndidNodeID, err := getSourceValue(dbGet, []byte("MasterNDID"))
+	if err != nil {
+		return err
+	}
+	for _, nodeID := range keyMap.nodeIDs() {
+		if nodeID == string(ndidNodeID) {
+			return fmt.Errorf("node key map: NDID node %s is not converted, set keys on restore instead", nodeID)
+		}
+		nodeDetailValue, err := getSourceValue(dbGet, []byte(v9.NodeIDKeyPrefix+v9.KeySeparator+nodeID))
+		if err != nil {
+			return err
+		}
+		if nodeDetailValue == nil {
+			return fmt.Errorf("node key map: node %s not found in source state data", nodeID)
+		}
+	}
+	return nil
+}
+
+// rotateNodeKey returns key history of converted node key, with key from key
+// map added as new active version when different from converted key. New
+// version has no creation block height and chain ID (not created by a tx).
+func rotateNodeKey(nodeKey *didProtoV9.NodeKey, newKey *NodeKeyMapKey) (history []*didProtoV9.NodeKey) {
+	if newKey == nil ||
+		(strings.TrimSpace(newKey.PublicKey) == strings.TrimSpace(nodeKey.PublicKey) &&
+			newKey.Algorithm == nodeKey.Algorithm) {
+		return []*didProtoV9.NodeKey{nodeKey}
+	}
+	nodeKey.Active = false
+	rotatedNodeKey := &didProtoV9.NodeKey{
+		PublicKey: newKey.PublicKey,
+		Algorithm: newKey.Algorithm,
+		Version:   nodeKey.Version + 1,
+		Active:    true,
+	}
+	return []*didProtoV9.NodeKey{nodeKey, rotatedNodeKey}
+}
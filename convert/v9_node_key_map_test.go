@@ -0,0 +1,82 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package convert
+
+import (
+	"testing"
+
+	didProtoV9 "github.com/ndidplatform/migration-tools/did/v9/protos/data"
+)
+
+func TestValidateNodeKeyMapNodes(t *testing.T) {
+	sourceData := map[string]string{
+		"MasterNDID":           "ndid1",
+		"NodeID|ndid1":         "ndid",
+		"NodeID|idp1":          "idp",
+		"kvPairKey:NodeID|rp1": "rp",
+	}
+	dbGet := func(key []byte) (value []byte, err error) {
+		if value, ok := sourceData[string(key)]; ok {
+			return []byte(value), nil
+		}
+		return nil, nil
+	}
+	newKey := &NodeKeyMapKey{PublicKey: "-", Algorithm: "-"}
+
+	testCases := []struct {
+		nodeID  string
+		isValid bool
+	}{
+		{"idp1", true},
+		{"rp1", true},
+		{"ndid1", false},
+		{"as1", false},
+	}
+	for _, testCase := range testCases {
+		keyMap := NodeKeyMap{testCase.nodeID: &NodeKeyMapNode{Signing: newKey}}
+		err := ValidateNodeKeyMapNodes(keyMap, dbGet)
+		if testCase.isValid && err != nil {
+			t.Errorf("%s: %v", testCase.nodeID, err)
+		}
+		if !testCase.isValid && err == nil {
+			t.Errorf("%s: expected error", testCase.nodeID)
+		}
+	}
+}
+
+func TestRotateNodeKeySameKeyWithWhitespace(t *testing.T) {
+	nodeKey := &didProtoV9.NodeKey{
+		PublicKey: "-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----",
+		Algorithm: "RSASSA_PKCS1_V1_5_SHA_256",
+		Version:   1,
+		Active:    true,
+	}
+	newKey := &NodeKeyMapKey{
+		PublicKey: nodeKey.PublicKey + "\n",
+		Algorithm: nodeKey.Algorithm,
+	}
+	history := rotateNodeKey(nodeKey, newKey)
+	if len(history) != 1 || !history[0].Active {
+		t.Errorf("key history length: %d, expected: 1 (not rotated)", len(history))
+	}
+}
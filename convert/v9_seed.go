@@ -0,0 +1,349 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+	"github.com/syndtr/goleveldb/leveldb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v2"
+
+	v8 "github.com/ndidplatform/migration-tools/did/v8"
+	v9 "github.com/ndidplatform/migration-tools/did/v9"
+	didProtoV9 "github.com/ndidplatform/migration-tools/did/v9/protos/data"
+	"github.com/ndidplatform/migration-tools/utils"
+)
+
+// V9Seed is state data added on conversion to v9
+//
+// Supported IAL/AAL lists and node supported features are new in v9 and are
+// always written. Error codes, request types, namespaces and service price
+// ceilings are only added when not already in the source state data.
+type V9Seed struct {
+	SupportedIALList     []float64
+	SupportedAALList     []float64
+	SupportedFeatures    []string
+	ErrorCodes           map[string][]*didProtoV9.ErrorCode // by error code type
+	RequestTypes         []string
+	Namespaces           []*didProtoV9.Namespace
+	ServicePriceCeilings map[string]*didProtoV9.ServicePriceCeilingList // by service ID
+}
+
+type v9SeedFile struct {
+	SupportedIALList     []float64                `yaml:"supported_ial_list"`
+	SupportedAALList     []float64                `yaml:"supported_aal_list"`
+	SupportedFeatures    []string                 `yaml:"supported_features"`
+	ErrorCodes           map[string][]interface{} `yaml:"error_codes"`
+	RequestTypes         []string                 `yaml:"request_types"`
+	Namespaces           []interface{}            `yaml:"namespaces"`
+	ServicePriceCeilings map[string]interface{}   `yaml:"service_price_ceilings"`
+}
+
+var v9SeedCache struct {
+	sync.Mutex
+	path string
+	seed *V9Seed
+}
+
+// DefaultV9Seed returns seed used when no seed file is configured
+func DefaultV9Seed() *V9Seed {
+	return &V9Seed{
+		SupportedIALList:  []float64{1.1, 1.2, 1.3, 2.1, 2.2, 2.3, 3},
+		SupportedAALList:  []float64{1, 2.1, 2.2, 3},
+		SupportedFeatures: []string{nodeSupportedFeatureOnTheFly},
+	}
+}
+
+// LoadV9Seed reads and validates seed file. Supported IAL/AAL lists and
+// supported features not declared in the file are set to defaults.
+func LoadV9Seed(path string) (seed *V9Seed, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var seedFile v9SeedFile
+	err = yaml.UnmarshalStrict(data, &seedFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	seed = DefaultV9Seed()
+	if seedFile.SupportedIALList != nil {
+		seed.SupportedIALList = seedFile.SupportedIALList
+	}
+	if seedFile.SupportedAALList != nil {
+		seed.SupportedAALList = seedFile.SupportedAALList
+	}
+	if seedFile.SupportedFeatures != nil {
+		seed.SupportedFeatures = seedFile.SupportedFeatures
+	}
+	seed.RequestTypes = seedFile.RequestTypes
+
+	seed.ErrorCodes = make(map[string][]*didProtoV9.ErrorCode, len(seedFile.ErrorCodes))
+	for errorCodeType, items := range seedFile.ErrorCodes {
+		for i, item := range items {
+			var errorCode didProtoV9.ErrorCode
+			err = unmarshalSeedMessage(item, &errorCode)
+			if err != nil {
+				return nil, fmt.Errorf("%s: error_codes.%s[%d]: %w", path, errorCodeType, i, err)
+			}
+			seed.ErrorCodes[errorCodeType] = append(seed.ErrorCodes[errorCodeType], &errorCode)
+		}
+	}
+
+	for i, item := range seedFile.Namespaces {
+		var namespace didProtoV9.Namespace
+		err = unmarshalSeedMessage(item, &namespace)
+		if err != nil {
+			return nil, fmt.Errorf("%s: namespaces[%d]: %w", path, i, err)
+		}
+		seed.Namespaces = append(seed.Namespaces, &namespace)
+	}
+
+	seed.ServicePriceCeilings = make(map[string]*didProtoV9.ServicePriceCeilingList, len(seedFile.ServicePriceCeilings))
+	for serviceID, item := range seedFile.ServicePriceCeilings {
+		var servicePriceCeilingList didProtoV9.ServicePriceCeilingList
+		err = unmarshalSeedMessage(item, &servicePriceCeilingList)
+		if err != nil {
+			return nil, fmt.Errorf("%s: service_price_ceilings.%s: %w", path, serviceID, err)
+		}
+		seed.ServicePriceCeilings[serviceID] = &servicePriceCeilingList
+	}
+
+	err = seed.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return seed, nil
+}
+
+// unmarshalSeedMessage decodes value read from YAML into message, unknown
+// fields and values of wrong type are rejected
+func unmarshalSeedMessage(value interface{}, m protoreflect.ProtoMessage) (err error) {
+	jsonBytes, err := json.Marshal(utils.NormalizeYAML(value))
+	if err != nil {
+		return err
+	}
+	return protojson.Unmarshal(jsonBytes, m)
+}
+
+// Validate checks seed values that would make invalid v9 state data
+func (seed *V9Seed) Validate() (err error) {
+	err = validateLevelList("supported_ial_list", seed.SupportedIALList)
+	if err != nil {
+		return err
+	}
+	err = validateLevelList("supported_aal_list", seed.SupportedAALList)
+	if err != nil {
+		return err
+	}
+
+	err = validateNames("supported_features", seed.SupportedFeatures)
+	if err != nil {
+		return err
+	}
+	// Converted node details may refer to this feature
+	hasOnTheFly := false
+	for _, feature := range seed.SupportedFeatures {
+		if feature == nodeSupportedFeatureOnTheFly {
+			hasOnTheFly = true
+		}
+	}
+	if !hasOnTheFly {
+		return fmt.Errorf("supported_features: must include %q", nodeSupportedFeatureOnTheFly)
+	}
+
+	err = validateNames("request_types", seed.RequestTypes)
+	if err != nil {
+		return err
+	}
+
+	for errorCodeType, errorCodes := range seed.ErrorCodes {
+		if errorCodeType == "" || strings.Contains(errorCodeType, v9.KeySeparator) {
+			return fmt.Errorf("error_codes: invalid type %q", errorCodeType)
+		}
+		seen := make(map[int32]bool, len(errorCodes))
+		for _, errorCode := range errorCodes {
+			if errorCode.ErrorCode == 0 {
+				return fmt.Errorf("error_codes.%s: missing error_code", errorCodeType)
+			}
+			if seen[errorCode.ErrorCode] {
+				return fmt.Errorf("error_codes.%s: duplicate error_code %d", errorCodeType, errorCode.ErrorCode)
+			}
+			seen[errorCode.ErrorCode] = true
+		}
+	}
+
+	seenNamespaces := make(map[string]bool, len(seed.Namespaces))
+	for _, namespace := range seed.Namespaces {
+		if namespace.Namespace == "" {
+			return errors.New("namespaces: missing namespace")
+		}
+		if seenNamespaces[namespace.Namespace] {
+			return fmt.Errorf("namespaces: duplicate namespace %q", namespace.Namespace)
+		}
+		seenNamespaces[namespace.Namespace] = true
+	}
+
+	for serviceID, servicePriceCeilingList := range seed.ServicePriceCeilings {
+		if serviceID == "" || strings.Contains(serviceID, v9.KeySeparator) {
+			return fmt.Errorf("service_price_ceilings: invalid service ID %q", serviceID)
+		}
+		if len(servicePriceCeilingList.PriceCeilingByCurrencyList) == 0 {
+			return fmt.Errorf("service_price_ceilings.%s: empty price_ceiling_by_currency_list", serviceID)
+		}
+		for _, priceCeiling := range servicePriceCeilingList.PriceCeilingByCurrencyList {
+			if priceCeiling.Currency == "" {
+				return fmt.Errorf("service_price_ceilings.%s: missing currency", serviceID)
+			}
+			if priceCeiling.Price < 0 {
+				return fmt.Errorf("service_price_ceilings.%s: negative price", serviceID)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateLevelList(name string, levels []float64) (err error) {
+	if len(levels) == 0 {
+		return fmt.Errorf("%s: must not be empty", name)
+	}
+	seen := make(map[float64]bool, len(levels))
+	for _, level := range levels {
+		if level <= 0 {
+			return fmt.Errorf("%s: invalid value %v", name, level)
+		}
+		if seen[level] {
+			return fmt.Errorf("%s: duplicate value %v", name, level)
+		}
+		seen[level] = true
+	}
+	return nil
+}
+
+func validateNames(name string, values []string) (err error) {
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		if value == "" || strings.Contains(value, v9.KeySeparator) {
+			return fmt.Errorf("%s: invalid value %q", name, value)
+		}
+		if seen[value] {
+			return fmt.Errorf("%s: duplicate value %q", name, value)
+		}
+		seen[value] = true
+	}
+	return nil
+}
+
+// GetV9Seed returns seed from file set in V9_NEW_STATE_SEED_FILE or default
+// seed if not set
+func GetV9Seed() (seed *V9Seed, err error) {
+	path := viper.GetString("V9_NEW_STATE_SEED_FILE")
+
+	v9SeedCache.Lock()
+	defer v9SeedCache.Unlock()
+	if v9SeedCache.seed != nil && v9SeedCache.path == path {
+		return v9SeedCache.seed, nil
+	}
+
+	if path == "" {
+		seed = DefaultV9Seed()
+	} else {
+		seed, err = LoadV9Seed(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	v9SeedCache.path = path
+	v9SeedCache.seed = seed
+	return seed, nil
+}
+
+// errorCodeTypes returns error code types in seed sorted
+func (seed *V9Seed) errorCodeTypes() []string {
+	errorCodeTypes := make([]string, 0, len(seed.ErrorCodes))
+	for errorCodeType := range seed.ErrorCodes {
+		errorCodeTypes = append(errorCodeTypes, errorCodeType)
+	}
+	sort.Strings(errorCodeTypes)
+	return errorCodeTypes
+}
+
+// mergeErrorCodeList adds error codes of type from seed not already in list
+func (seed *V9Seed) mergeErrorCodeList(errorCodeType string, errorCodeList *didProtoV9.ErrorCodeList) {
+	existing := make(map[int32]bool, len(errorCodeList.ErrorCode))
+	for _, errorCode := range errorCodeList.ErrorCode {
+		existing[errorCode.ErrorCode] = true
+	}
+	for _, errorCode := range seed.ErrorCodes[errorCodeType] {
+		if !existing[errorCode.ErrorCode] {
+			errorCodeList.ErrorCode = append(errorCodeList.ErrorCode, errorCode)
+		}
+	}
+}
+
+// mergeNamespaceList adds namespaces from seed not already in list
+func (seed *V9Seed) mergeNamespaceList(namespaceList *didProtoV9.NamespaceList) {
+	existing := make(map[string]bool, len(namespaceList.Namespaces))
+	for _, namespace := range namespaceList.Namespaces {
+		existing[namespace.Namespace] = true
+	}
+	for _, namespace := range seed.Namespaces {
+		if !existing[namespace.Namespace] {
+			namespaceList.Namespaces = append(namespaceList.Namespaces, namespace)
+		}
+	}
+}
+
+// getSourceValue gets value of key (with or without kvPair prefix) from
+// source state data, nil if not found
+func getSourceValue(
+	dbGet func(key []byte) (value []byte, err error),
+	key []byte,
+) (value []byte, err error) {
+	prefixedKey := append(append([]byte{}, v8.KvPairPrefixKey...), key...)
+	for _, k := range [][]byte{key, prefixedKey} {
+		value, err = dbGet(k)
+		if errors.Is(err, leveldb.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			return value, nil
+		}
+	}
+	return nil, nil
+}
@@ -30,6 +30,7 @@ import (
 	dbm "github.com/tendermint/tm-db"
 
 	tendermint_0_32_1 "github.com/ndidplatform/migration-tools/tendermint/0_32_1"
+	"github.com/ndidplatform/migration-tools/utils"
 )
 
 type tomlConfig struct {
@@ -49,7 +50,13 @@ type ChainHistory struct {
 }
 
 func GetLastestTendermintData(tmHome string) (chainData *ChainHistoryDetail, err error) {
-	tendermintStateInfo, err := tendermint_0_32_1.GetTendermintInfo(tmHome)
+	return GetTendermintDataAtHeight(tmHome, 0)
+}
+
+// GetTendermintDataAtHeight gets chain data of block at given height
+// (latest block when height is 0)
+func GetTendermintDataAtHeight(tmHome string, height int64) (chainData *ChainHistoryDetail, err error) {
+	tendermintStateInfo, err := tendermint_0_32_1.GetTendermintInfoAtHeight(tmHome, height)
 	if err != nil {
 		return nil, err
 	}
@@ -69,11 +76,8 @@ var (
 
 type StateDB dbm.DB
 
-func GetStateDB(dbType string, dbDir string) StateDB {
+// GetStateDB opens ABCI state DB read-only. Caller must close the returned DB.
+func GetStateDB(dbType string, dbDir string) (stateDB StateDB, err error) {
 	dbName := "didDB"
-	db, err := dbm.NewDB(dbName, dbm.BackendType(dbType), dbDir)
-	if err != nil {
-		panic(err)
-	}
-	return db
+	return utils.OpenDBReadOnly(dbName, dbm.BackendType(dbType), dbDir)
 }
@@ -35,6 +35,7 @@ import (
 	"time"
 
 	tmRand "github.com/ndidplatform/migration-tools/rand"
+	"github.com/ndidplatform/migration-tools/tm_client"
 
 	"github.com/ndidplatform/migration-tools/utils"
 )
@@ -45,8 +46,14 @@ func Restore(
 	backupDataFileName string,
 	chainHistoryFileName string,
 	keyDir string,
-	tendermintRPCAddress string,
+	tendermintRPCHost string,
+	tendermintRPCPort string,
+	tendermintRPCConnectionConfig tm_client.ConnectionConfig,
 ) (err error) {
+	httpClient, err := tm_client.NewHTTPClient(tendermintRPCHost, tendermintRPCPort, tendermintRPCConnectionConfig)
+	if err != nil {
+		return err
+	}
 	ndidKeyFile, err := os.Open(keyDir + "ndid")
 	if err != nil {
 		return err
@@ -73,7 +80,7 @@ func Restore(
 		ndidID,
 		backupDataDir,
 		chainHistoryFileName,
-		tendermintRPCAddress,
+		httpClient,
 	)
 	if err != nil {
 		return err
@@ -104,7 +111,7 @@ func Restore(
 		size += len(kv.Key) + len(kv.Value)
 		nTx++
 		if size > maximumBytes {
-			err = setInitData(param, ndidPrivKey, ndidID, tendermintRPCAddress)
+			err = setInitData(param, ndidPrivKey, ndidID, httpClient)
 			if err != nil {
 				return err
 			}
@@ -118,7 +125,7 @@ func Restore(
 		}
 	}
 	if count > 0 {
-		err = setInitData(param, ndidPrivKey, ndidID, tendermintRPCAddress)
+		err = setInitData(param, ndidPrivKey, ndidID, httpClient)
 		if err != nil {
 			return err
 		}
@@ -127,7 +134,7 @@ func Restore(
 		fmt.Print("Total number of kv: ")
 		fmt.Println(nTx)
 	}
-	err = endInit(ndidPrivKey, ndidID, tendermintRPCAddress)
+	err = endInit(ndidPrivKey, ndidID, httpClient)
 	if err != nil {
 		return err
 	}
@@ -141,7 +148,7 @@ func initNDID(
 	ndidID string,
 	backupDataDir string,
 	chainHistoryFileName string,
-	tendermintRPCAddress string,
+	httpClient *tm_client.HTTPClient,
 ) (err error) {
 	chainHistoryData, err := ioutil.ReadFile(backupDataDir + chainHistoryFileName)
 	if err != nil {
@@ -174,7 +181,7 @@ func initNDID(
 	pssh.Write(PSSmessage)
 	hashed := pssh.Sum(nil)
 	signature, err := rsa.SignPKCS1v15(rand.Reader, ndidKey, newhash, hashed)
-	result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(initNDIDparam.NodeID))
+	result, err := CallTendermint(httpClient, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(initNDIDparam.NodeID))
 	if err != nil {
 		return err
 	}
@@ -183,7 +190,7 @@ func initNDID(
 	return nil
 }
 
-func setInitData(param SetInitDataParam, ndidKey *rsa.PrivateKey, ndidID string, tendermintRPCAddress string) (err error) {
+func setInitData(param SetInitDataParam, ndidKey *rsa.PrivateKey, ndidID string, httpClient *tm_client.HTTPClient) (err error) {
 	paramJSON, err := json.Marshal(param)
 	if err != nil {
 		return err
@@ -198,7 +205,7 @@ func setInitData(param SetInitDataParam, ndidKey *rsa.PrivateKey, ndidID string,
 	pssh.Write(PSSmessage)
 	hashed := pssh.Sum(nil)
 	signature, err := rsa.SignPKCS1v15(rand.Reader, ndidKey, newhash, hashed)
-	result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(ndidID))
+	result, err := CallTendermint(httpClient, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(ndidID))
 	if err != nil {
 		return err
 	}
@@ -209,7 +216,7 @@ func setInitData(param SetInitDataParam, ndidKey *rsa.PrivateKey, ndidID string,
 		// pause 3 sec and retry again
 		fmt.Printf("Retry ...\n")
 		time.Sleep(3 * time.Second)
-		err = setInitData(param, ndidKey, ndidID, tendermintRPCAddress)
+		err = setInitData(param, ndidKey, ndidID, httpClient)
 		if err != nil {
 			return err
 		}
@@ -218,7 +225,7 @@ func setInitData(param SetInitDataParam, ndidKey *rsa.PrivateKey, ndidID string,
 	return nil
 }
 
-func endInit(ndidKey *rsa.PrivateKey, ndidID string, tendermintRPCAddress string) (err error) {
+func endInit(ndidKey *rsa.PrivateKey, ndidID string, httpClient *tm_client.HTTPClient) (err error) {
 	var param EndInitParam
 	paramJSON, err := json.Marshal(param)
 	if err != nil {
@@ -235,7 +242,7 @@ func endInit(ndidKey *rsa.PrivateKey, ndidID string, tendermintRPCAddress string
 	pssh.Write(PSSmessage)
 	hashed := pssh.Sum(nil)
 	signature, err := rsa.SignPKCS1v15(rand.Reader, ndidKey, newhash, hashed)
-	result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(nodeID))
+	result, err := CallTendermint(httpClient, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(nodeID))
 	if err != nil {
 		return err
 	}
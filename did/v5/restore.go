@@ -24,6 +24,7 @@ package v5
 
 import (
 	"bufio"
+	"context"
 	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
@@ -35,11 +36,10 @@ import (
 	"time"
 
 	protoTm "github.com/ndidplatform/migration-tools/did/v5/protos/tendermint"
-	"github.com/ndidplatform/migration-tools/did/v5/tm_client"
 	"github.com/ndidplatform/migration-tools/proto"
 	tmRand "github.com/ndidplatform/migration-tools/rand"
+	"github.com/ndidplatform/migration-tools/tm_client"
 
-	_log "github.com/ndidplatform/migration-tools/log"
 	"github.com/ndidplatform/migration-tools/utils"
 )
 
@@ -51,6 +51,7 @@ func Restore(
 	keyDir string,
 	tendermintRPCHost string,
 	tendermintRPCPort string,
+	tendermintRPCConnectionConfig tm_client.ConnectionConfig,
 ) (err error) {
 	ndidKeyFile, err := os.Open(keyDir + "ndid")
 	if err != nil {
@@ -67,24 +68,15 @@ func Restore(
 	}
 	defer ndidMasterKeyFile.Close()
 
-	logger, err := _log.NewLogger(&_log.Configuration{
-		EnableConsole:     true,
-		ConsoleLevel:      "info",
-		ConsoleJSONFormat: false,
-		Color:             true,
-	}, _log.InstanceGoLogger)
+	tmClient, err := newTmClient(tendermintRPCConnectionConfig)
 	if err != nil {
 		return err
 	}
-	tmClient, err := tm_client.New(logger)
+	_, err = tmClient.Connect(context.Background(), tendermintRPCHost, tendermintRPCPort)
 	if err != nil {
 		return err
 	}
-	_, err = tmClient.Connect(tendermintRPCHost, tendermintRPCPort)
-	if err != nil {
-		return err
-	}
-	defer tmClient.Close()
+	defer tmClient.Close(context.Background())
 
 	dataMaster, err := ioutil.ReadAll(ndidMasterKeyFile)
 	if err != nil {
@@ -209,7 +201,7 @@ func initNDID(
 	}
 
 	// result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(initNDIDparam.NodeID))
-	result, err := tmClient.BroadcastTxCommit(txByte)
+	result, err := tmClient.BroadcastTxCommit(context.Background(), txByte)
 	if err != nil {
 		return err
 	}
@@ -253,7 +245,7 @@ func setInitData(
 	}
 
 	// result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(ndidID))
-	result, err := tmClient.BroadcastTxCommit(txByte)
+	result, err := tmClient.BroadcastTxCommit(context.Background(), txByte)
 	if err != nil {
 		return err
 	}
@@ -307,7 +299,7 @@ func endInit(
 	}
 
 	// result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(nodeID))
-	result, err := tmClient.BroadcastTxCommit(txByte)
+	result, err := tmClient.BroadcastTxCommit(context.Background(), txByte)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,72 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package v6
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	protoTm "github.com/ndidplatform/migration-tools/did/v6/protos/tendermint"
+	"github.com/ndidplatform/migration-tools/proto"
+	"github.com/ndidplatform/migration-tools/tm_client"
+)
+
+type IsInitEndedResult struct {
+	InitEnded bool `json:"init_ended"`
+}
+
+// IsInitEnded queries whether ABCI has left InitState (EndInit has been called)
+func IsInitEnded(tmClient *tm_client.TmClient) (initEnded bool, err error) {
+	paramJSON, err := json.Marshal(struct{}{})
+	if err != nil {
+		return false, err
+	}
+
+	var data protoTm.Query
+	data.Method = "IsInitEnded"
+	data.Params = string(paramJSON)
+
+	dataByte, err := proto.Marshal(&data)
+	if err != nil {
+		return false, err
+	}
+
+	queryRes, err := tmClient.Query(context.Background(), dataByte)
+	if err != nil {
+		return false, err
+	}
+
+	value, err := base64.StdEncoding.DecodeString(queryRes.Response.Value)
+	if err != nil {
+		return false, err
+	}
+
+	var result IsInitEndedResult
+	err = json.Unmarshal(value, &result)
+	if err != nil {
+		return false, err
+	}
+
+	return result.InitEnded, nil
+}
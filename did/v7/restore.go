@@ -24,6 +24,7 @@ package v7
 
 import (
 	"bufio"
+	"context"
 	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
@@ -41,11 +42,10 @@ import (
 
 	protoParam "github.com/ndidplatform/migration-tools/did/v7/protos/param"
 	protoTm "github.com/ndidplatform/migration-tools/did/v7/protos/tendermint"
-	"github.com/ndidplatform/migration-tools/did/v7/tm_client"
 	"github.com/ndidplatform/migration-tools/proto"
 	tmRand "github.com/ndidplatform/migration-tools/rand"
+	"github.com/ndidplatform/migration-tools/tm_client"
 
-	_log "github.com/ndidplatform/migration-tools/log"
 	"github.com/ndidplatform/migration-tools/utils"
 )
 
@@ -59,6 +59,7 @@ func Restore(
 	keyDir string,
 	tendermintRPCHost string,
 	tendermintRPCPort string,
+	tendermintRPCConnectionConfig tm_client.ConnectionConfig,
 ) (err error) {
 	ndidKeyFile, err := os.Open(keyDir + "ndid")
 	if err != nil {
@@ -75,33 +76,24 @@ func Restore(
 	}
 	defer ndidMasterKeyFile.Close()
 
-	logger, err := _log.NewLogger(&_log.Configuration{
-		EnableConsole:     true,
-		ConsoleLevel:      "info",
-		ConsoleJSONFormat: false,
-		Color:             true,
-	}, _log.InstanceGoLogger)
+	tmClient, err := newTmClient(tendermintRPCConnectionConfig)
 	if err != nil {
 		return err
 	}
-	tmClient, err := tm_client.New(logger)
+	_, err = tmClient.Connect(context.Background(), tendermintRPCHost, tendermintRPCPort)
 	if err != nil {
 		return err
 	}
-	_, err = tmClient.Connect(tendermintRPCHost, tendermintRPCPort)
-	if err != nil {
-		return err
-	}
-	defer tmClient.Close()
+	defer tmClient.Close(context.Background())
 
-	tmStatus, err := tmClient.Status()
+	tmStatus, err := tmClient.Status(context.Background())
 	if err != nil {
 		return err
 	}
 	currentChainID = tmStatus.NodeInfo.Network
 
 	txResultChan := make(chan tm_client.TxResult)
-	tmClient.SubscribeToNewBlockEvents(txResultChan)
+	tmClient.SubscribeToNewBlockEvents(context.Background(), txResultChan)
 
 	var deliverTxLogChanMap map[string]chan string = make(map[string]chan string)
 	var deliverTxLogChanMutex sync.RWMutex
@@ -243,6 +235,7 @@ func InitNDID(
 	keyDir string,
 	tendermintRPCHost string,
 	tendermintRPCPort string,
+	tendermintRPCConnectionConfig tm_client.ConnectionConfig,
 	backupDataDir string,
 	chainHistoryFileName string,
 ) (err error) {
@@ -265,26 +258,17 @@ func InitNDID(
 		return err
 	}
 
-	logger, err := _log.NewLogger(&_log.Configuration{
-		EnableConsole:     true,
-		ConsoleLevel:      "info",
-		ConsoleJSONFormat: false,
-		Color:             true,
-	}, _log.InstanceGoLogger)
-	if err != nil {
-		return err
-	}
-	tmClient, err := tm_client.New(logger)
+	tmClient, err := newTmClient(tendermintRPCConnectionConfig)
 	if err != nil {
 		return err
 	}
-	_, err = tmClient.Connect(tendermintRPCHost, tendermintRPCPort)
+	_, err = tmClient.Connect(context.Background(), tendermintRPCHost, tendermintRPCPort)
 	if err != nil {
 		return err
 	}
-	defer tmClient.Close()
+	defer tmClient.Close(context.Background())
 
-	tmStatus, err := tmClient.Status()
+	tmStatus, err := tmClient.Status(context.Background())
 	if err != nil {
 		return err
 	}
@@ -313,6 +297,7 @@ func EndInit(
 	keyDir string,
 	tendermintRPCHost string,
 	tendermintRPCPort string,
+	tendermintRPCConnectionConfig tm_client.ConnectionConfig,
 ) (err error) {
 	ndidKeyFile, err := os.Open(keyDir + "ndid")
 	if err != nil {
@@ -324,26 +309,17 @@ func EndInit(
 		return err
 	}
 
-	logger, err := _log.NewLogger(&_log.Configuration{
-		EnableConsole:     true,
-		ConsoleLevel:      "info",
-		ConsoleJSONFormat: false,
-		Color:             true,
-	}, _log.InstanceGoLogger)
+	tmClient, err := newTmClient(tendermintRPCConnectionConfig)
 	if err != nil {
 		return err
 	}
-	tmClient, err := tm_client.New(logger)
+	_, err = tmClient.Connect(context.Background(), tendermintRPCHost, tendermintRPCPort)
 	if err != nil {
 		return err
 	}
-	_, err = tmClient.Connect(tendermintRPCHost, tendermintRPCPort)
-	if err != nil {
-		return err
-	}
-	defer tmClient.Close()
+	defer tmClient.Close(context.Background())
 
-	tmStatus, err := tmClient.Status()
+	tmStatus, err := tmClient.Status(context.Background())
 	if err != nil {
 		return err
 	}
@@ -369,6 +345,7 @@ func SetNodeKeys(
 	keyDir string,
 	tendermintRPCHost string,
 	tendermintRPCPort string,
+	tendermintRPCConnectionConfig tm_client.ConnectionConfig,
 ) (err error) {
 	ndidMasterKeyFile, err := os.Open(keyDir + "ndid_master")
 	if err != nil {
@@ -400,26 +377,17 @@ func SetNodeKeys(
 		return err
 	}
 
-	logger, err := _log.NewLogger(&_log.Configuration{
-		EnableConsole:     true,
-		ConsoleLevel:      "info",
-		ConsoleJSONFormat: false,
-		Color:             true,
-	}, _log.InstanceGoLogger)
-	if err != nil {
-		return err
-	}
-	tmClient, err := tm_client.New(logger)
+	tmClient, err := newTmClient(tendermintRPCConnectionConfig)
 	if err != nil {
 		return err
 	}
-	_, err = tmClient.Connect(tendermintRPCHost, tendermintRPCPort)
+	_, err = tmClient.Connect(context.Background(), tendermintRPCHost, tendermintRPCPort)
 	if err != nil {
 		return err
 	}
-	defer tmClient.Close()
+	defer tmClient.Close(context.Background())
 
-	tmStatus, err := tmClient.Status()
+	tmStatus, err := tmClient.Status(context.Background())
 	if err != nil {
 		return err
 	}
@@ -497,7 +465,7 @@ func initNDID(
 	}
 
 	// result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(initNDIDparam.NodeID))
-	result, err := tmClient.BroadcastTxCommit(txByte)
+	result, err := tmClient.BroadcastTxCommit(context.Background(), txByte)
 	if err != nil {
 		return err
 	}
@@ -558,7 +526,7 @@ func setInitData(
 
 	// result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(ndidID))
 	// result, err := tmClient.BroadcastTxCommit(txByte)
-	result, err := tmClient.BroadcastTxSync(txByte)
+	result, err := tmClient.BroadcastTxSync(context.Background(), txByte)
 	if err != nil {
 		return "", err
 	}
@@ -633,7 +601,7 @@ func setInitData_pb(
 
 	// result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(ndidID))
 	// result, err := tmClient.BroadcastTxCommit(txByte)
-	result, err := tmClient.BroadcastTxSync(txByte)
+	result, err := tmClient.BroadcastTxSync(context.Background(), txByte)
 	if err != nil {
 		return "", err
 	}
@@ -695,7 +663,7 @@ func endInit(
 	}
 
 	// result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(nodeID))
-	result, err := tmClient.BroadcastTxCommit(txByte)
+	result, err := tmClient.BroadcastTxCommit(context.Background(), txByte)
 	if err != nil {
 		return err
 	}
@@ -757,7 +725,7 @@ func updateNode(
 	}
 
 	// result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(initNDIDparam.NodeID))
-	result, err := tmClient.BroadcastTxCommit(txByte)
+	result, err := tmClient.BroadcastTxCommit(context.Background(), txByte)
 	if err != nil {
 		return err
 	}
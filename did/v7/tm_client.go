@@ -0,0 +1,46 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package v7
+
+import (
+	_log "github.com/ndidplatform/migration-tools/log"
+	"github.com/ndidplatform/migration-tools/tm_client"
+)
+
+const tendermintVersion = tm_client.TendermintVersion0_34
+
+func newTmClient(connectionConfig tm_client.ConnectionConfig) (*tm_client.TmClient, error) {
+	logger, err := _log.NewLogger(&_log.Configuration{
+		EnableConsole:     true,
+		ConsoleLevel:      "info",
+		ConsoleJSONFormat: false,
+		Color:             true,
+	}, _log.InstanceGoLogger)
+	if err != nil {
+		return nil, err
+	}
+	config := tm_client.DefaultConfig()
+	config.TendermintVersion = tendermintVersion
+	config.Connection = connectionConfig
+	return tm_client.NewWithConfig(logger, config)
+}
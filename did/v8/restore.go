@@ -24,6 +24,7 @@ package v8
 
 import (
 	"bufio"
+	"context"
 	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
@@ -40,11 +41,10 @@ import (
 
 	protoParam "github.com/ndidplatform/migration-tools/did/v8/protos/param"
 	protoTm "github.com/ndidplatform/migration-tools/did/v8/protos/tendermint"
-	"github.com/ndidplatform/migration-tools/did/v8/tm_client"
 	"github.com/ndidplatform/migration-tools/proto"
 	tmRand "github.com/ndidplatform/migration-tools/rand"
+	"github.com/ndidplatform/migration-tools/tm_client"
 
-	_log "github.com/ndidplatform/migration-tools/log"
 	"github.com/ndidplatform/migration-tools/utils"
 )
 
@@ -58,6 +58,7 @@ func Restore(
 	keyDir string,
 	tendermintRPCHost string,
 	tendermintRPCPort string,
+	tendermintRPCConnectionConfig tm_client.ConnectionConfig,
 ) (err error) {
 	ndidKeyFile, err := os.Open(keyDir + "ndid")
 	if err != nil {
@@ -74,33 +75,24 @@ func Restore(
 	}
 	defer ndidMasterKeyFile.Close()
 
-	logger, err := _log.NewLogger(&_log.Configuration{
-		EnableConsole:     true,
-		ConsoleLevel:      "info",
-		ConsoleJSONFormat: false,
-		Color:             true,
-	}, _log.InstanceGoLogger)
+	tmClient, err := newTmClient(tendermintRPCConnectionConfig)
 	if err != nil {
 		return err
 	}
-	tmClient, err := tm_client.New(logger)
+	_, err = tmClient.Connect(context.Background(), tendermintRPCHost, tendermintRPCPort)
 	if err != nil {
 		return err
 	}
-	_, err = tmClient.Connect(tendermintRPCHost, tendermintRPCPort)
-	if err != nil {
-		return err
-	}
-	defer tmClient.Close()
+	defer tmClient.Close(context.Background())
 
-	tmStatus, err := tmClient.Status()
+	tmStatus, err := tmClient.Status(context.Background())
 	if err != nil {
 		return err
 	}
 	currentChainID = tmStatus.NodeInfo.Network
 
 	txResultChan := make(chan tm_client.TxResult)
-	tmClient.SubscribeToNewBlockEvents(txResultChan)
+	tmClient.SubscribeToNewBlockEvents(context.Background(), txResultChan)
 
 	var deliverTxLogChanMap map[string]chan string = make(map[string]chan string)
 	var deliverTxLogChanMutex sync.RWMutex
@@ -242,6 +234,7 @@ func InitNDID(
 	keyDir string,
 	tendermintRPCHost string,
 	tendermintRPCPort string,
+	tendermintRPCConnectionConfig tm_client.ConnectionConfig,
 	backupDataDir string,
 	chainHistoryFileName string,
 ) (err error) {
@@ -264,26 +257,17 @@ func InitNDID(
 		return err
 	}
 
-	logger, err := _log.NewLogger(&_log.Configuration{
-		EnableConsole:     true,
-		ConsoleLevel:      "info",
-		ConsoleJSONFormat: false,
-		Color:             true,
-	}, _log.InstanceGoLogger)
-	if err != nil {
-		return err
-	}
-	tmClient, err := tm_client.New(logger)
+	tmClient, err := newTmClient(tendermintRPCConnectionConfig)
 	if err != nil {
 		return err
 	}
-	_, err = tmClient.Connect(tendermintRPCHost, tendermintRPCPort)
+	_, err = tmClient.Connect(context.Background(), tendermintRPCHost, tendermintRPCPort)
 	if err != nil {
 		return err
 	}
-	defer tmClient.Close()
+	defer tmClient.Close(context.Background())
 
-	tmStatus, err := tmClient.Status()
+	tmStatus, err := tmClient.Status(context.Background())
 	if err != nil {
 		return err
 	}
@@ -312,6 +296,7 @@ func EndInit(
 	keyDir string,
 	tendermintRPCHost string,
 	tendermintRPCPort string,
+	tendermintRPCConnectionConfig tm_client.ConnectionConfig,
 ) (err error) {
 	ndidKeyFile, err := os.Open(keyDir + "ndid")
 	if err != nil {
@@ -323,26 +308,17 @@ func EndInit(
 		return err
 	}
 
-	logger, err := _log.NewLogger(&_log.Configuration{
-		EnableConsole:     true,
-		ConsoleLevel:      "info",
-		ConsoleJSONFormat: false,
-		Color:             true,
-	}, _log.InstanceGoLogger)
+	tmClient, err := newTmClient(tendermintRPCConnectionConfig)
 	if err != nil {
 		return err
 	}
-	tmClient, err := tm_client.New(logger)
+	_, err = tmClient.Connect(context.Background(), tendermintRPCHost, tendermintRPCPort)
 	if err != nil {
 		return err
 	}
-	_, err = tmClient.Connect(tendermintRPCHost, tendermintRPCPort)
-	if err != nil {
-		return err
-	}
-	defer tmClient.Close()
+	defer tmClient.Close(context.Background())
 
-	tmStatus, err := tmClient.Status()
+	tmStatus, err := tmClient.Status(context.Background())
 	if err != nil {
 		return err
 	}
@@ -368,6 +344,7 @@ func SetNodeKeys(
 	keyDir string,
 	tendermintRPCHost string,
 	tendermintRPCPort string,
+	tendermintRPCConnectionConfig tm_client.ConnectionConfig,
 ) (err error) {
 	ndidMasterKeyFile, err := os.Open(keyDir + "ndid_master")
 	if err != nil {
@@ -399,26 +376,17 @@ func SetNodeKeys(
 		return err
 	}
 
-	logger, err := _log.NewLogger(&_log.Configuration{
-		EnableConsole:     true,
-		ConsoleLevel:      "info",
-		ConsoleJSONFormat: false,
-		Color:             true,
-	}, _log.InstanceGoLogger)
-	if err != nil {
-		return err
-	}
-	tmClient, err := tm_client.New(logger)
+	tmClient, err := newTmClient(tendermintRPCConnectionConfig)
 	if err != nil {
 		return err
 	}
-	_, err = tmClient.Connect(tendermintRPCHost, tendermintRPCPort)
+	_, err = tmClient.Connect(context.Background(), tendermintRPCHost, tendermintRPCPort)
 	if err != nil {
 		return err
 	}
-	defer tmClient.Close()
+	defer tmClient.Close(context.Background())
 
-	tmStatus, err := tmClient.Status()
+	tmStatus, err := tmClient.Status(context.Background())
 	if err != nil {
 		return err
 	}
@@ -496,7 +464,7 @@ func initNDID(
 	}
 
 	// result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(initNDIDparam.NodeID))
-	result, err := tmClient.BroadcastTxCommit(txByte)
+	result, err := tmClient.BroadcastTxCommit(context.Background(), txByte)
 	if err != nil {
 		return err
 	}
@@ -557,7 +525,7 @@ func setInitData(
 
 	// result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(ndidID))
 	// result, err := tmClient.BroadcastTxCommit(txByte)
-	result, err := tmClient.BroadcastTxSync(txByte)
+	result, err := tmClient.BroadcastTxSync(context.Background(), txByte)
 	if err != nil {
 		return "", err
 	}
@@ -632,7 +600,7 @@ func setInitData_pb(
 
 	// result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(ndidID))
 	// result, err := tmClient.BroadcastTxCommit(txByte)
-	result, err := tmClient.BroadcastTxSync(txByte)
+	result, err := tmClient.BroadcastTxSync(context.Background(), txByte)
 	if err != nil {
 		return "", err
 	}
@@ -694,7 +662,7 @@ func endInit(
 	}
 
 	// result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(nodeID))
-	result, err := tmClient.BroadcastTxCommit(txByte)
+	result, err := tmClient.BroadcastTxCommit(context.Background(), txByte)
 	if err != nil {
 		return err
 	}
@@ -756,7 +724,7 @@ func updateNode(
 	}
 
 	// result, err := CallTendermint(tendermintRPCAddress, []byte(fnName), paramJSON, []byte(nonce), signature, []byte(initNDIDparam.NodeID))
-	result, err := tmClient.BroadcastTxCommit(txByte)
+	result, err := tmClient.BroadcastTxCommit(context.Background(), txByte)
 	if err != nil {
 		return err
 	}
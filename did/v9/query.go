@@ -0,0 +1,132 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package v9
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	protoTm "github.com/ndidplatform/migration-tools/did/v9/protos/tendermint"
+	"github.com/ndidplatform/migration-tools/proto"
+	"github.com/ndidplatform/migration-tools/tm_client"
+)
+
+var ErrQueryNotFound = errors.New("not found")
+
+type GetNodeInfoParam struct {
+	NodeID string `json:"node_id"`
+}
+
+type NodeKeyResult struct {
+	PublicKey string `json:"public_key"`
+	Algorithm string `json:"algorithm"`
+	Version   int64  `json:"version"`
+}
+
+type GetNodeInfoResult struct {
+	SigningPublicKey       NodeKeyResult `json:"signing_public_key"`
+	SigningMasterPublicKey NodeKeyResult `json:"signing_master_public_key"`
+	EncryptionPublicKey    NodeKeyResult `json:"encryption_public_key"`
+	NodeName               string        `json:"node_name"`
+	Role                   string        `json:"role"`
+	Active                 bool          `json:"active"`
+}
+
+type GetServiceDetailParam struct {
+	ServiceID string `json:"service_id"`
+}
+
+type GetServiceDetailResult struct {
+	ServiceID         string `json:"service_id"`
+	ServiceName       string `json:"service_name"`
+	DataSchema        string `json:"data_schema"`
+	DataSchemaVersion string `json:"data_schema_version"`
+	Active            bool   `json:"active"`
+}
+
+type GetReferenceGroupCodeParam struct {
+	Namespace      string `json:"namespace"`
+	IdentifierHash string `json:"identifier_hash"`
+}
+
+type GetReferenceGroupCodeByAccessorIDParam struct {
+	AccessorID string `json:"accessor_id"`
+}
+
+type GetReferenceGroupCodeResult struct {
+	ReferenceGroupCode string `json:"reference_group_code"`
+}
+
+type IsInitEndedResult struct {
+	InitEnded bool `json:"init_ended"`
+}
+
+// IsInitEnded queries whether ABCI has left InitState (EndInit has been called)
+func IsInitEnded(tmClient *tm_client.TmClient) (initEnded bool, err error) {
+	var result IsInitEndedResult
+	err = query(tmClient, "IsInitEnded", struct{}{}, &result)
+	if err != nil {
+		return false, err
+	}
+	return result.InitEnded, nil
+}
+
+// query calls an ABCI query function and unmarshals its JSON result into
+// result. ErrQueryNotFound is returned when the query returns no value.
+func query(
+	tmClient *tm_client.TmClient,
+	fnName string,
+	param interface{},
+	result interface{},
+) (err error) {
+	paramJSON, err := json.Marshal(param)
+	if err != nil {
+		return err
+	}
+
+	var data protoTm.Query
+	data.Method = fnName
+	data.Params = paramJSON
+
+	dataByte, err := proto.Marshal(&data)
+	if err != nil {
+		return err
+	}
+
+	queryRes, err := tmClient.Query(context.Background(), dataByte)
+	if err != nil {
+		return err
+	}
+
+	value, err := base64.StdEncoding.DecodeString(queryRes.Response.Value)
+	if err != nil {
+		return err
+	}
+	if len(value) == 0 || queryRes.Response.Log == "not found" {
+		return ErrQueryNotFound
+	}
+
+	return json.Unmarshal(value, result)
+}
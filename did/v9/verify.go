@@ -0,0 +1,258 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package v9
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	didProtoV9 "github.com/ndidplatform/migration-tools/did/v9/protos/data"
+	"github.com/ndidplatform/migration-tools/proto"
+	"github.com/ndidplatform/migration-tools/tm_client"
+)
+
+type VerifyMismatch struct {
+	Key      string `json:"key"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+type VerifyReport struct {
+	TotalKeyCount        int64            `json:"total_key_count"`
+	VerifiableKeyCount   int64            `json:"verifiable_key_count"`
+	UnverifiableKeyCount int64            `json:"unverifiable_key_count"`
+	CheckedKeyCount      int64            `json:"checked_key_count"`
+	MatchedKeyCount      int64            `json:"matched_key_count"`
+	MismatchedKeyCount   int64            `json:"mismatched_key_count"`
+	CheckedByPrefix      map[string]int64 `json:"checked_by_prefix"`
+	Mismatches           []VerifyMismatch `json:"mismatches"`
+}
+
+// VerifyRestore reads initial state data file and compares values of keys
+// that can be queried from the restored chain with ABCI query results.
+// Only every sampleEvery-th verifiable key is checked (all when sampleEvery <= 1).
+func VerifyRestore(
+	backupDataDir string,
+	backupDataFileName string,
+	tendermintRPCHost string,
+	tendermintRPCPort string,
+	tendermintRPCConnectionConfig tm_client.ConnectionConfig,
+	sampleEvery int64,
+) (report *VerifyReport, err error) {
+	tmClient, err := newTmClient(tendermintRPCConnectionConfig)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tmClient.Connect(context.Background(), tendermintRPCHost, tendermintRPCPort)
+	if err != nil {
+		return nil, err
+	}
+	defer tmClient.Close(context.Background())
+
+	file, err := os.Open(path.Join(backupDataDir, backupDataFileName))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return verifyRestore(tmClient, file, sampleEvery)
+}
+
+func verifyRestore(
+	tmClient *tm_client.TmClient,
+	data io.Reader,
+	sampleEvery int64,
+) (report *VerifyReport, err error) {
+	report = &VerifyReport{
+		CheckedByPrefix: make(map[string]int64),
+		Mismatches:      make([]VerifyMismatch, 0),
+	}
+
+	reader := bufio.NewReader(data)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			var kv KeyValue
+			if err := json.Unmarshal(line, &kv); err != nil {
+				return nil, err
+			}
+			report.TotalKeyCount++
+
+			key := bytes.TrimPrefix(kv.Key, KvPairPrefixKey)
+			keyParts := strings.Split(string(key), KeySeparator)
+			if isVerifiableKey(keyParts) {
+				report.VerifiableKeyCount++
+				if sampleEvery <= 1 || (report.VerifiableKeyCount-1)%sampleEvery == 0 {
+					mismatches, err := verifyKeyValue(tmClient, keyParts, kv.Value)
+					if err != nil {
+						return nil, err
+					}
+					report.CheckedKeyCount++
+					report.CheckedByPrefix[keyParts[0]]++
+					if len(mismatches) > 0 {
+						report.MismatchedKeyCount++
+						report.Mismatches = append(report.Mismatches, mismatches...)
+						for _, mismatch := range mismatches {
+							log.Printf(
+								"mismatch: key: %s field: %s expected: %s actual: %s\n",
+								mismatch.Key,
+								mismatch.Field,
+								mismatch.Expected,
+								mismatch.Actual,
+							)
+						}
+					} else {
+						report.MatchedKeyCount++
+					}
+				}
+			} else {
+				report.UnverifiableKeyCount++
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return report, nil
+}
+
+func isVerifiableKey(keyParts []string) bool {
+	switch keyParts[0] {
+	case NodeIDKeyPrefix, ServiceKeyPrefix, AccessorToRefCodeKeyPrefix:
+		return len(keyParts) == 2
+	case IdentityToRefCodeKeyPrefix:
+		return len(keyParts) == 3
+	}
+	return false
+}
+
+func verifyKeyValue(
+	tmClient *tm_client.TmClient,
+	keyParts []string,
+	value []byte,
+) (mismatches []VerifyMismatch, err error) {
+	key := strings.Join(keyParts, KeySeparator)
+	mismatches = make([]VerifyMismatch, 0)
+	compare := func(field string, expected interface{}, actual interface{}) {
+		expectedStr := fmt.Sprint(expected)
+		actualStr := fmt.Sprint(actual)
+		if expectedStr != actualStr {
+			mismatches = append(mismatches, VerifyMismatch{
+				Key:      key,
+				Field:    field,
+				Expected: expectedStr,
+				Actual:   actualStr,
+			})
+		}
+	}
+	notFound := func() {
+		mismatches = append(mismatches, VerifyMismatch{
+			Key:      key,
+			Expected: "(exists)",
+			Actual:   "(not found)",
+		})
+	}
+
+	switch keyParts[0] {
+	case NodeIDKeyPrefix:
+		var nodeDetail didProtoV9.NodeDetail
+		if err := proto.Unmarshal(value, &nodeDetail); err != nil {
+			return nil, err
+		}
+		var result GetNodeInfoResult
+		err = query(tmClient, "GetNodeInfo", GetNodeInfoParam{NodeID: keyParts[1]}, &result)
+		if err == ErrQueryNotFound {
+			notFound()
+			return mismatches, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		compare("node_name", nodeDetail.NodeName, result.NodeName)
+		compare("role", nodeDetail.Role, result.Role)
+		compare("active", nodeDetail.Active, result.Active)
+		compare("signing_public_key", nodeDetail.SigningPublicKey.GetPublicKey(), result.SigningPublicKey.PublicKey)
+		compare("signing_master_public_key", nodeDetail.SigningMasterPublicKey.GetPublicKey(), result.SigningMasterPublicKey.PublicKey)
+		compare("encryption_public_key", nodeDetail.EncryptionPublicKey.GetPublicKey(), result.EncryptionPublicKey.PublicKey)
+	case ServiceKeyPrefix:
+		var serviceDetail didProtoV9.ServiceDetail
+		if err := proto.Unmarshal(value, &serviceDetail); err != nil {
+			return nil, err
+		}
+		var result GetServiceDetailResult
+		err = query(tmClient, "GetServiceDetail", GetServiceDetailParam{ServiceID: keyParts[1]}, &result)
+		if err == ErrQueryNotFound {
+			notFound()
+			return mismatches, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		compare("service_name", serviceDetail.ServiceName, result.ServiceName)
+		compare("data_schema", serviceDetail.DataSchema, result.DataSchema)
+		compare("data_schema_version", serviceDetail.DataSchemaVersion, result.DataSchemaVersion)
+		compare("active", serviceDetail.Active, result.Active)
+	case IdentityToRefCodeKeyPrefix:
+		var result GetReferenceGroupCodeResult
+		err = query(tmClient, "GetReferenceGroupCode", GetReferenceGroupCodeParam{
+			Namespace:      keyParts[1],
+			IdentifierHash: keyParts[2],
+		}, &result)
+		if err == ErrQueryNotFound {
+			notFound()
+			return mismatches, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		compare("reference_group_code", string(value), result.ReferenceGroupCode)
+	case AccessorToRefCodeKeyPrefix:
+		var result GetReferenceGroupCodeResult
+		err = query(tmClient, "GetReferenceGroupCodeByAccessorID", GetReferenceGroupCodeByAccessorIDParam{
+			AccessorID: keyParts[1],
+		}, &result)
+		if err == ErrQueryNotFound {
+			notFound()
+			return mismatches, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		compare("reference_group_code", string(value), result.ReferenceGroupCode)
+	}
+
+	return mismatches, nil
+}
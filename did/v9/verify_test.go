@@ -0,0 +1,271 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package v9
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	didProtoV9 "github.com/ndidplatform/migration-tools/did/v9/protos/data"
+	protoTm "github.com/ndidplatform/migration-tools/did/v9/protos/tendermint"
+	"github.com/ndidplatform/migration-tools/proto"
+	"github.com/ndidplatform/migration-tools/tm_client"
+)
+
+// queryStub is Tendermint RPC websocket stub answering abci_query with
+// results of ABCI query functions
+type queryStub struct {
+	server *httptest.Server
+
+	mutex sync.Mutex
+	// results by query function name and JSON params, missing is not found
+	results map[string]interface{}
+	queried []string
+}
+
+func newQueryStub(t *testing.T, results map[string]interface{}) *queryStub {
+	stub := &queryStub{results: results}
+	upgrader := websocket.Upgrader{}
+	stub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		for {
+			var request tm_client.RequestJsonRPC
+			if err := conn.ReadJSON(&request); err != nil {
+				return
+			}
+			if err := conn.WriteJSON(stub.respond(t, request)); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+func (stub *queryStub) respond(t *testing.T, request tm_client.RequestJsonRPC) interface{} {
+	response := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      request.ID,
+	}
+	if request.Method != "abci_query" {
+		response["error"] = map[string]interface{}{"code": -32601, "message": "Method not found", "data": request.Method}
+		return response
+	}
+	data, err := hex.DecodeString(request.Params.Data)
+	if err != nil {
+		t.Errorf("query data: %v", err)
+	}
+	var query protoTm.Query
+	if err := proto.Unmarshal(data, &query); err != nil {
+		t.Errorf("query: %v", err)
+	}
+	queryKey := query.Method + string(query.Params)
+
+	stub.mutex.Lock()
+	stub.queried = append(stub.queried, queryKey)
+	result, ok := stub.results[queryKey]
+	stub.mutex.Unlock()
+
+	queryResponse := map[string]interface{}{"log": "not found", "value": ""}
+	if ok {
+		value, err := json.Marshal(result)
+		if err != nil {
+			t.Errorf("result: %v", err)
+		}
+		queryResponse = map[string]interface{}{"log": "success", "value": base64.StdEncoding.EncodeToString(value)}
+	}
+	response["result"] = map[string]interface{}{"response": queryResponse}
+	return response
+}
+
+func (stub *queryStub) hostPort(t *testing.T) (host string, port string) {
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(stub.server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host, port
+}
+
+func writeVerifyTestData(t *testing.T, keyValues []KeyValue) (dir string) {
+	dir = t.TempDir()
+	var data []byte
+	for _, kv := range keyValues {
+		line, err := json.Marshal(kv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+	if err := os.WriteFile(path.Join(dir, "data"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func verifyTestData(t *testing.T) []KeyValue {
+	nodeDetail, err := proto.Marshal(&didProtoV9.NodeDetail{
+		SigningPublicKey:       &didProtoV9.NodeKey{PublicKey: "signing"},
+		SigningMasterPublicKey: &didProtoV9.NodeKey{PublicKey: "signing_master"},
+		EncryptionPublicKey:    &didProtoV9.NodeKey{PublicKey: "encryption"},
+		NodeName:               "IdP 1",
+		Role:                   "IdP",
+		Active:                 true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serviceDetail, err := proto.Marshal(&didProtoV9.ServiceDetail{
+		ServiceId:   "service1",
+		ServiceName: "Service 1",
+		Active:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []KeyValue{
+		{Key: []byte("NodeID|idp1"), Value: nodeDetail},
+		{Key: []byte("RefGroupCode|rg1"), Value: []byte{}},
+		{Key: append(append([]byte{}, KvPairPrefixKey...), "Service|service1"...), Value: serviceDetail},
+		{Key: []byte("identityToRefCodeKey|citizen_id|hash1"), Value: []byte("rg1")},
+		{Key: []byte("accessorToRefCodeKey|accessor1"), Value: []byte("rg1")},
+	}
+}
+
+func verifyTestResults() map[string]interface{} {
+	return map[string]interface{}{
+		`GetNodeInfo{"node_id":"idp1"}`: GetNodeInfoResult{
+			SigningPublicKey:       NodeKeyResult{PublicKey: "signing"},
+			SigningMasterPublicKey: NodeKeyResult{PublicKey: "signing_master"},
+			EncryptionPublicKey:    NodeKeyResult{PublicKey: "encryption"},
+			NodeName:               "IdP 1",
+			Role:                   "IdP",
+			Active:                 true,
+		},
+		`GetServiceDetail{"service_id":"service1"}`: GetServiceDetailResult{
+			ServiceID:   "service1",
+			ServiceName: "Service 1",
+			Active:      true,
+		},
+		`GetReferenceGroupCode{"namespace":"citizen_id","identifier_hash":"hash1"}`: GetReferenceGroupCodeResult{
+			ReferenceGroupCode: "rg1",
+		},
+		`GetReferenceGroupCodeByAccessorID{"accessor_id":"accessor1"}`: GetReferenceGroupCodeResult{
+			ReferenceGroupCode: "rg1",
+		},
+	}
+}
+
+func TestVerifyRestoreMatched(t *testing.T) {
+	stub := newQueryStub(t, verifyTestResults())
+	host, port := stub.hostPort(t)
+	dir := writeVerifyTestData(t, verifyTestData(t))
+
+	report, err := VerifyRestore(dir, "data", host, port, tm_client.ConnectionConfig{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalKeyCount != 5 || report.VerifiableKeyCount != 4 || report.UnverifiableKeyCount != 1 {
+		t.Errorf("key counts: total %d verifiable %d unverifiable %d, expected 5 4 1",
+			report.TotalKeyCount, report.VerifiableKeyCount, report.UnverifiableKeyCount)
+	}
+	if report.CheckedKeyCount != 4 || report.MatchedKeyCount != 4 || report.MismatchedKeyCount != 0 {
+		t.Errorf("checked %d matched %d mismatched %d, expected 4 4 0",
+			report.CheckedKeyCount, report.MatchedKeyCount, report.MismatchedKeyCount)
+	}
+	if len(report.Mismatches) != 0 {
+		t.Errorf("unexpected mismatches: %+v", report.Mismatches)
+	}
+}
+
+func TestVerifyRestoreMismatched(t *testing.T) {
+	results := verifyTestResults()
+	results[`GetServiceDetail{"service_id":"service1"}`] = GetServiceDetailResult{
+		ServiceID:   "service1",
+		ServiceName: "Service 1 (renamed)",
+		Active:      true,
+	}
+	delete(results, `GetReferenceGroupCodeByAccessorID{"accessor_id":"accessor1"}`)
+	stub := newQueryStub(t, results)
+	host, port := stub.hostPort(t)
+	dir := writeVerifyTestData(t, verifyTestData(t))
+
+	report, err := VerifyRestore(dir, "data", host, port, tm_client.ConnectionConfig{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.CheckedKeyCount != 4 || report.MatchedKeyCount != 2 || report.MismatchedKeyCount != 2 {
+		t.Errorf("checked %d matched %d mismatched %d, expected 4 2 2",
+			report.CheckedKeyCount, report.MatchedKeyCount, report.MismatchedKeyCount)
+	}
+	expected := []VerifyMismatch{
+		{Key: "Service|service1", Field: "service_name", Expected: "Service 1", Actual: "Service 1 (renamed)"},
+		{Key: "accessorToRefCodeKey|accessor1", Expected: "(exists)", Actual: "(not found)"},
+	}
+	if len(report.Mismatches) != len(expected) {
+		t.Fatalf("mismatches: %+v, expected %+v", report.Mismatches, expected)
+	}
+	for i := range expected {
+		if report.Mismatches[i] != expected[i] {
+			t.Errorf("mismatch %d: %+v, expected %+v", i, report.Mismatches[i], expected[i])
+		}
+	}
+}
+
+func TestVerifyRestoreSampleEvery(t *testing.T) {
+	stub := newQueryStub(t, verifyTestResults())
+	host, port := stub.hostPort(t)
+	dir := writeVerifyTestData(t, verifyTestData(t))
+
+	report, err := VerifyRestore(dir, "data", host, port, tm_client.ConnectionConfig{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.VerifiableKeyCount != 4 || report.CheckedKeyCount != 2 {
+		t.Errorf("verifiable %d checked %d, expected 4 2", report.VerifiableKeyCount, report.CheckedKeyCount)
+	}
+	// 1st and 3rd verifiable keys
+	expectedQueried := []string{
+		`GetNodeInfo{"node_id":"idp1"}`,
+		`GetReferenceGroupCode{"namespace":"citizen_id","identifier_hash":"hash1"}`,
+	}
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	if strings.Join(stub.queried, "\n") != strings.Join(expectedQueried, "\n") {
+		t.Errorf("queried %v, expected %v", stub.queried, expectedQueried)
+	}
+}
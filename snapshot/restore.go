@@ -0,0 +1,271 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package snapshot
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type RestoredSource struct {
+	Name           string `json:"name"`
+	Path           string `json:"path"`
+	MovedAsidePath string `json:"moved_aside_path,omitempty"`
+}
+
+// ReadInfo reads snapshot info from snapshot directory
+func ReadInfo(snapshotDir string) (info *Info, err error) {
+	infoJSON, err := os.ReadFile(filepath.Join(snapshotDir, InfoFilename))
+	if err != nil {
+		return nil, err
+	}
+	info = new(Info)
+	err = json.Unmarshal(infoJSON, info)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Restore puts snapshot sources back. Each source is restored to its original
+// path unless a target path is given in targetPaths (keyed by source name).
+// Files are written to a staging directory next to the target and verified
+// against the manifest. Existing target directory is then moved aside (renamed
+// with ".before_restore_<time>" suffix) and never deleted.
+func Restore(snapshotDir string, targetPaths map[string]string) (restored []RestoredSource, err error) {
+	info, err := ReadInfo(snapshotDir)
+	if err != nil {
+		return nil, err
+	}
+	manifest, manifestChecksum, err := readManifest(filepath.Join(snapshotDir, ManifestFilename))
+	if err != nil {
+		return nil, err
+	}
+	if manifestChecksum != info.ManifestSHA256 {
+		return nil, errors.New("snapshot manifest checksum mismatch")
+	}
+
+	suffix := time.Now().Format("20060102_150405")
+	stagingPaths := make(map[string]string)
+	defer func() {
+		if err != nil {
+			for _, stagingPath := range stagingPaths {
+				os.RemoveAll(stagingPath)
+			}
+		}
+	}()
+	for _, source := range info.Sources {
+		targetPath := source.Path
+		if targetPaths[source.Name] != "" {
+			targetPath, err = filepath.Abs(targetPaths[source.Name])
+			if err != nil {
+				return nil, err
+			}
+		}
+		if _, err = os.Stat(targetPath); err == nil {
+			err = checkSourceNotLocked(targetPath)
+			if err != nil {
+				return nil, err
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		stagingPath := targetPath + ".restore_" + suffix
+		err = os.MkdirAll(filepath.Dir(targetPath), 0755)
+		if err != nil {
+			return nil, err
+		}
+		err = os.Mkdir(stagingPath, 0700)
+		if err != nil {
+			return nil, err
+		}
+		stagingPaths[source.Name] = stagingPath
+		restored = append(restored, RestoredSource{
+			Name: source.Name,
+			Path: targetPath,
+		})
+	}
+
+	restoredFiles := make(map[string]bool)
+	verifyFile := func(name string, checksum string) error {
+		expected, ok := manifest[name]
+		if !ok {
+			return errors.New("file not in snapshot manifest: " + name)
+		}
+		if checksum != expected {
+			return errors.New("snapshot file checksum mismatch: " + name)
+		}
+		restoredFiles[name] = true
+		return nil
+	}
+
+	switch info.Mode {
+	case ModeHardlink:
+		err = restoreHardlinkSnapshot(snapshotDir, info, stagingPaths, verifyFile)
+	case ModeArchive:
+		err = restoreArchiveSnapshot(snapshotDir, info, stagingPaths, verifyFile)
+	default:
+		err = errors.New("unknown snapshot mode: " + info.Mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for name := range manifest {
+		if !restoredFiles[name] {
+			err = errors.New("file missing from snapshot: " + name)
+			return nil, err
+		}
+	}
+
+	for i, source := range restored {
+		if _, err = os.Stat(source.Path); err == nil {
+			restored[i].MovedAsidePath = source.Path + ".before_restore_" + suffix
+			err = os.Rename(source.Path, restored[i].MovedAsidePath)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = os.Rename(stagingPaths[source.Name], source.Path)
+		if err != nil {
+			return nil, err
+		}
+		delete(stagingPaths, source.Name)
+	}
+
+	return restored, nil
+}
+
+func restoreHardlinkSnapshot(
+	snapshotDir string,
+	info *Info,
+	stagingPaths map[string]string,
+	verifyFile func(name string, checksum string) error,
+) (err error) {
+	for _, source := range info.Sources {
+		snapshotSource := Source{
+			Name: source.Name,
+			Path: filepath.Join(snapshotDir, source.Name),
+		}
+		err = walkSource(snapshotSource, func(filePath string, relPath string, fileInfo os.FileInfo) error {
+			destPath := filepath.Join(stagingPaths[source.Name], filepath.FromSlash(relPath))
+			if fileInfo.IsDir() {
+				return mkdir(destPath, fileInfo.Mode().Perm())
+			}
+			checksum, err := copyFile(filePath, destPath, fileInfo.Mode().Perm())
+			if err != nil {
+				return err
+			}
+			return verifyFile(source.Name+"/"+relPath, checksum)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func restoreArchiveSnapshot(
+	snapshotDir string,
+	info *Info,
+	stagingPaths map[string]string,
+	verifyFile func(name string, checksum string) error,
+) (err error) {
+	archivePath := filepath.Join(snapshotDir, ArchiveFilename)
+	archiveChecksum, err := fileSHA256(archivePath)
+	if err != nil {
+		return err
+	}
+	if archiveChecksum != info.ArchiveSHA256 {
+		return errors.New("snapshot archive checksum mismatch")
+	}
+
+	archiveFile, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archiveFile.Close()
+	zstdReader, err := zstd.NewReader(archiveFile)
+	if err != nil {
+		return err
+	}
+	defer zstdReader.Close()
+	tarReader := tar.NewReader(zstdReader)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("invalid file path in snapshot archive: %s", header.Name)
+		}
+		nameParts := strings.SplitN(name, "/", 2)
+		stagingPath, ok := stagingPaths[nameParts[0]]
+		if !ok {
+			return fmt.Errorf("unknown source in snapshot archive: %s", header.Name)
+		}
+		destPath := stagingPath
+		if len(nameParts) == 2 {
+			destPath = filepath.Join(stagingPath, filepath.FromSlash(nameParts[1]))
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = mkdir(destPath, header.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			fileHash := sha256.New()
+			err = writeFile(destPath, header.FileInfo().Mode().Perm(), io.TeeReader(tarReader, fileHash))
+			if err != nil {
+				return err
+			}
+			err = verifyFile(name, hex.EncodeToString(fileHash.Sum(nil)))
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported file type in snapshot archive: %s", header.Name)
+		}
+	}
+	return nil
+}
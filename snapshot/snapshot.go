@@ -0,0 +1,425 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package snapshot
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/ndidplatform/migration-tools/utils"
+)
+
+const (
+	ModeAuto     = "auto"
+	ModeHardlink = "hardlink"
+	ModeArchive  = "archive"
+)
+
+const (
+	InfoFilename     = "snapshot.json"
+	ManifestFilename = "manifest"
+	ArchiveFilename  = "snapshot.tar.zst"
+)
+
+// Source is a directory included in snapshot. Name is used as directory name
+// (or top level archive entry) of the source in snapshot.
+type Source struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// Info describes a snapshot. It is saved as snapshot.json in snapshot directory.
+type Info struct {
+	CreatedAt      time.Time `json:"created_at"`
+	Mode           string    `json:"mode"`
+	Sources        []Source  `json:"sources"`
+	FileCount      int64     `json:"file_count"`
+	TotalSize      int64     `json:"total_size"`
+	ManifestSHA256 string    `json:"manifest_sha256"`
+	ArchiveSHA256  string    `json:"archive_sha256,omitempty"`
+}
+
+type manifestEntry struct {
+	Name   string
+	SHA256 string
+}
+
+// Create snapshots source directories into snapshotDir and writes a SHA-256
+// checksum manifest (sha256sum format) of all snapshot files.
+//
+// Hardlink mode links immutable LevelDB table files (.ldb, .sst) and copies
+// other files, since LevelDB appends to its log and MANIFEST files in place.
+// Archive mode writes all files to a zstd compressed tar.
+// Auto mode uses hardlink mode and falls back to archive mode when snapshotDir
+// is on a different file system from a source.
+//
+// Sources must not be in use. LevelDB found in a source which is locked by
+// another process is reported as an error.
+func Create(snapshotDir string, sources []Source, mode string) (info *Info, err error) {
+	switch mode {
+	case ModeAuto, ModeHardlink, ModeArchive:
+	default:
+		return nil, errors.New("unknown snapshot mode: " + mode)
+	}
+
+	for i, source := range sources {
+		sources[i].Path, err = filepath.Abs(source.Path)
+		if err != nil {
+			return nil, err
+		}
+		err = checkSourceNotLocked(sources[i].Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := os.ReadDir(snapshotDir)
+	if err == nil && len(entries) > 0 {
+		return nil, errors.New("snapshot directory is not empty: " + snapshotDir)
+	}
+	err = os.MkdirAll(snapshotDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	info = &Info{
+		CreatedAt: time.Now(),
+		Sources:   sources,
+	}
+	var manifest []manifestEntry
+	switch mode {
+	case ModeHardlink:
+		info.Mode = ModeHardlink
+		manifest, err = createHardlinkSnapshot(snapshotDir, info)
+	case ModeArchive:
+		info.Mode = ModeArchive
+		manifest, err = createArchiveSnapshot(snapshotDir, info)
+	case ModeAuto:
+		info.Mode = ModeHardlink
+		manifest, err = createHardlinkSnapshot(snapshotDir, info)
+		if errors.Is(err, syscall.EXDEV) {
+			for _, source := range sources {
+				err = os.RemoveAll(filepath.Join(snapshotDir, source.Name))
+				if err != nil {
+					return nil, err
+				}
+			}
+			info.Mode = ModeArchive
+			info.FileCount = 0
+			info.TotalSize = 0
+			manifest, err = createArchiveSnapshot(snapshotDir, info)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info.ManifestSHA256, err = writeManifest(filepath.Join(snapshotDir, ManifestFilename), manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	infoJSON, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(filepath.Join(snapshotDir, InfoFilename), infoJSON, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// checkSourceNotLocked checks all LevelDB (directories with LOCK file) in dir
+func checkSourceNotLocked(dir string) (err error) {
+	return filepath.WalkDir(dir, func(filePath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "LOCK" {
+			return nil
+		}
+		return utils.CheckDBNotLocked(filepath.Dir(filePath))
+	})
+}
+
+func isImmutableFile(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".ldb" || ext == ".sst"
+}
+
+// walkSource calls fn with path relative to source directory (slash
+// separated) for each directory and regular file in source
+func walkSource(
+	source Source,
+	fn func(filePath string, relPath string, fileInfo os.FileInfo) error,
+) (err error) {
+	return filepath.Walk(source.Path, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(source.Path, filePath)
+		if err != nil {
+			return err
+		}
+		if !fileInfo.IsDir() && !fileInfo.Mode().IsRegular() {
+			return fmt.Errorf("unsupported file type in snapshot source: %s", filePath)
+		}
+		return fn(filePath, filepath.ToSlash(relPath), fileInfo)
+	})
+}
+
+func createHardlinkSnapshot(snapshotDir string, info *Info) (manifest []manifestEntry, err error) {
+	for _, source := range info.Sources {
+		err = walkSource(source, func(filePath string, relPath string, fileInfo os.FileInfo) error {
+			destPath := filepath.Join(snapshotDir, source.Name, filepath.FromSlash(relPath))
+			if fileInfo.IsDir() {
+				return mkdir(destPath, fileInfo.Mode().Perm())
+			}
+			var checksum string
+			var err error
+			if isImmutableFile(fileInfo.Name()) {
+				err = os.Link(filePath, destPath)
+				if err != nil {
+					return err
+				}
+				checksum, err = fileSHA256(destPath)
+				if err != nil {
+					return err
+				}
+			} else {
+				checksum, err = copyFile(filePath, destPath, fileInfo.Mode().Perm())
+				if err != nil {
+					return err
+				}
+			}
+			manifest = append(manifest, manifestEntry{
+				Name:   source.Name + "/" + relPath,
+				SHA256: checksum,
+			})
+			info.FileCount++
+			info.TotalSize += fileInfo.Size()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+func createArchiveSnapshot(snapshotDir string, info *Info) (manifest []manifestEntry, err error) {
+	archiveFile, err := os.OpenFile(filepath.Join(snapshotDir, ArchiveFilename), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer archiveFile.Close()
+
+	archiveHash := sha256.New()
+	zstdWriter, err := zstd.NewWriter(io.MultiWriter(archiveFile, archiveHash))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			// closed below on success, closing again writes another frame
+			zstdWriter.Close()
+		}
+	}()
+	tarWriter := tar.NewWriter(zstdWriter)
+
+	for _, source := range info.Sources {
+		err = walkSource(source, func(filePath string, relPath string, fileInfo os.FileInfo) error {
+			header, err := tar.FileInfoHeader(fileInfo, "")
+			if err != nil {
+				return err
+			}
+			header.Name = source.Name + "/" + relPath
+			if relPath == "." {
+				header.Name = source.Name
+			}
+			if fileInfo.IsDir() {
+				header.Name += "/"
+			}
+			err = tarWriter.WriteHeader(header)
+			if err != nil {
+				return err
+			}
+			if fileInfo.IsDir() {
+				return nil
+			}
+
+			file, err := os.Open(filePath)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			fileHash := sha256.New()
+			// file size is fixed in tar header, source must not be written to
+			written, err := io.Copy(tarWriter, io.TeeReader(io.LimitReader(file, fileInfo.Size()), fileHash))
+			if err != nil {
+				return err
+			}
+			if written != fileInfo.Size() {
+				return fmt.Errorf("file changed while creating snapshot: %s", filePath)
+			}
+			manifest = append(manifest, manifestEntry{
+				Name:   header.Name,
+				SHA256: hex.EncodeToString(fileHash.Sum(nil)),
+			})
+			info.FileCount++
+			info.TotalSize += fileInfo.Size()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = zstdWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = archiveFile.Sync()
+	if err != nil {
+		return nil, err
+	}
+	info.ArchiveSHA256 = hex.EncodeToString(archiveHash.Sum(nil))
+
+	return manifest, nil
+}
+
+func writeManifest(manifestPath string, manifest []manifestEntry) (checksum string, err error) {
+	sort.Slice(manifest, func(i, j int) bool {
+		return manifest[i].Name < manifest[j].Name
+	})
+	var builder strings.Builder
+	for _, entry := range manifest {
+		builder.WriteString(entry.SHA256)
+		builder.WriteString("  ")
+		builder.WriteString(entry.Name)
+		builder.WriteString("\n")
+	}
+	manifestBytes := []byte(builder.String())
+	err = os.WriteFile(manifestPath, manifestBytes, 0644)
+	if err != nil {
+		return "", err
+	}
+	manifestHash := sha256.Sum256(manifestBytes)
+	return hex.EncodeToString(manifestHash[:]), nil
+}
+
+func readManifest(manifestPath string) (manifest map[string]string, checksum string, err error) {
+	manifestBytes, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, "", err
+	}
+	manifestHash := sha256.Sum256(manifestBytes)
+	manifest = make(map[string]string)
+	for _, line := range strings.Split(string(manifestBytes), "\n") {
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "  ", 2)
+		if len(parts) != 2 {
+			return nil, "", errors.New("invalid manifest line: " + line)
+		}
+		manifest[parts[1]] = parts[0]
+	}
+	return manifest, hex.EncodeToString(manifestHash[:]), nil
+}
+
+func fileSHA256(filePath string) (checksum string, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	fileHash := sha256.New()
+	_, err = io.Copy(fileHash, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(fileHash.Sum(nil)), nil
+}
+
+// copyFile copies file and returns SHA-256 checksum of copied content
+func copyFile(srcPath string, destPath string, perm os.FileMode) (checksum string, err error) {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	fileHash := sha256.New()
+	err = writeFile(destPath, perm, io.TeeReader(src, fileHash))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(fileHash.Sum(nil)), nil
+}
+
+// mkdir creates directory (and parents) with perm. Owner always has full
+// access so that files can be written into it.
+func mkdir(dirPath string, perm os.FileMode) (err error) {
+	err = os.MkdirAll(dirPath, perm|0700)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(dirPath, perm|0700)
+}
+
+func writeFile(destPath string, perm os.FileMode, data io.Reader) (err error) {
+	dest, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dest, data)
+	if err != nil {
+		dest.Close()
+		return err
+	}
+	err = dest.Sync()
+	if err != nil {
+		dest.Close()
+		return err
+	}
+	return dest.Close()
+}
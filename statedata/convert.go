@@ -0,0 +1,248 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package statedata
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/ndidplatform/migration-tools/convert"
+)
+
+type convertFunc func(
+	key []byte,
+	value []byte,
+	dbGet func(key []byte) (value []byte, err error),
+	saveNewChainHistory func(chainHistory []byte) (err error),
+	saveKeyValue func(key []byte, value []byte) (err error),
+) (err error)
+
+type addNewStateDataFunc func(
+	dbGet func(key []byte) (value []byte, err error),
+	saveNewChainHistory func(chainHistory []byte) (err error),
+	saveKeyValue func(key []byte, value []byte) (err error),
+) (err error)
+
+// Conversion of state data from one ABCI version to the next one (same as in
+// create-initial-state-data between versions other than source version).
+// State data of v7 and v8 are the same.
+var convertHops = []struct {
+	FromVersion     int
+	ToVersion       int
+	Convert         convertFunc
+	AddNewStateData addNewStateDataFunc
+}{
+	{2, 3, convertV2ToV3, nil},
+	{3, 4, convertV3ToV4, nil},
+	{4, 5, convertV4ToV5, nil},
+	{6, 7, convertV6ToV7, nil},
+	{7, 9, convertV8ToV9, addNewStateDataToV9},
+	{8, 9, convertV8ToV9, addNewStateDataToV9},
+}
+
+func convertV2ToV3(
+	key []byte,
+	value []byte,
+	dbGet func(key []byte) (value []byte, err error),
+	saveNewChainHistory func(chainHistory []byte) (err error),
+	saveKeyValue func(key []byte, value []byte) (err error),
+) (err error) {
+	return convert.ConvertStateDBDataV2ToV3(key, value, "", nil, dbGet, saveNewChainHistory, saveKeyValue)
+}
+
+func convertV3ToV4(
+	key []byte,
+	value []byte,
+	dbGet func(key []byte) (value []byte, err error),
+	saveNewChainHistory func(chainHistory []byte) (err error),
+	saveKeyValue func(key []byte, value []byte) (err error),
+) (err error) {
+	return convert.ConvertStateDBDataV3ToV4(key, value, "", nil, dbGet, saveNewChainHistory, saveKeyValue)
+}
+
+func convertV4ToV5(
+	key []byte,
+	value []byte,
+	dbGet func(key []byte) (value []byte, err error),
+	saveNewChainHistory func(chainHistory []byte) (err error),
+	saveKeyValue func(key []byte, value []byte) (err error),
+) (err error) {
+	return convert.ConvertStateDBDataV4ToV5(key, value, "", nil, dbGet, saveNewChainHistory, saveKeyValue)
+}
+
+func convertV6ToV7(
+	key []byte,
+	value []byte,
+	dbGet func(key []byte) (value []byte, err error),
+	saveNewChainHistory func(chainHistory []byte) (err error),
+	saveKeyValue func(key []byte, value []byte) (err error),
+) (err error) {
+	_, err = convert.ConvertStateDBDataV6ToV7(key, value, "", nil, dbGet, saveNewChainHistory, saveKeyValue)
+	return err
+}
+
+func convertV8ToV9(
+	key []byte,
+	value []byte,
+	dbGet func(key []byte) (value []byte, err error),
+	saveNewChainHistory func(chainHistory []byte) (err error),
+	saveKeyValue func(key []byte, value []byte) (err error),
+) (err error) {
+	_, err = convert.ConvertStateDBDataV8ToV9(key, value, "", nil, dbGet, saveNewChainHistory, saveKeyValue)
+	return err
+}
+
+func addNewStateDataToV9(
+	dbGet func(key []byte) (value []byte, err error),
+	saveNewChainHistory func(chainHistory []byte) (err error),
+	saveKeyValue func(key []byte, value []byte) (err error),
+) (err error) {
+	_, err = convert.AddNewStateDataToV9(dbGet, saveNewChainHistory, saveKeyValue)
+	return err
+}
+
+// SameStateDataVersion returns true when state data of both ABCI versions
+// have the same structure.
+func SameStateDataVersion(versionA int, versionB int) bool {
+	if versionA == versionB {
+		return true
+	}
+	return (versionA == 7 || versionA == 8) && (versionB == 7 || versionB == 8)
+}
+
+// ConvertSource copies state data of source (keys without "kvPairKey:"
+// prefix) to a new LevelDB in dbDir and converts it from fromVersion to
+// toVersion with converters of each version. Caller must close returned DB.
+func ConvertSource(source Source, fromVersion int, toVersion int, dbDir string) (db *leveldb.DB, err error) {
+	db, err = leveldb.OpenFile(path.Join(dbDir, "db_version_"+strconv.Itoa(fromVersion)), nil)
+	if err != nil {
+		return nil, err
+	}
+	err = source.Iterate(nil, func(key []byte, value []byte) (stop bool, err error) {
+		return false, db.Put(key, value, nil)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	version := fromVersion
+	for !SameStateDataVersion(version, toVersion) {
+		hopIndex := -1
+		for i, hop := range convertHops {
+			if hop.FromVersion == version && hop.ToVersion <= toVersion {
+				hopIndex = i
+				break
+			}
+		}
+		if hopIndex < 0 {
+			db.Close()
+			return nil, fmt.Errorf("converting state data from ABCI version %d to %d is not supported", version, toVersion)
+		}
+		hop := convertHops[hopIndex]
+
+		log.Println("converting state data version:", hop.FromVersion, "to version:", hop.ToVersion)
+		if hop.ToVersion == 9 {
+			creationIndex, err := convert.GetV9CreationIndex()
+			if err != nil {
+				db.Close()
+				return nil, err
+			}
+			creationIndex.StartConversion()
+		}
+		nextDB, err := leveldb.OpenFile(path.Join(dbDir, "db_version_"+strconv.Itoa(hop.ToVersion)), nil)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		err = runConvertHop(db, nextDB, hop.Convert, hop.AddNewStateData)
+		db.Close()
+		if err != nil {
+			nextDB.Close()
+			return nil, err
+		}
+		err = os.RemoveAll(path.Join(dbDir, "db_version_"+strconv.Itoa(version)))
+		if err != nil {
+			nextDB.Close()
+			return nil, err
+		}
+		db = nextDB
+		version = hop.ToVersion
+	}
+
+	return db, nil
+}
+
+func runConvertHop(
+	inputDB *leveldb.DB,
+	outputDB *leveldb.DB,
+	convertKeyValue convertFunc,
+	addNewStateData addNewStateDataFunc,
+) (err error) {
+	// Converters panic on malformed data
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("convert state data: %v", r)
+		}
+	}()
+
+	dbGet := func(key []byte) (value []byte, err error) {
+		value, err = inputDB.Get(key, nil)
+		if err == leveldb.ErrNotFound {
+			return nil, nil
+		}
+		return value, err
+	}
+	saveNewChainHistory := func(chainHistory []byte) (err error) {
+		return outputDB.Put([]byte("ChainHistoryInfo"), chainHistory, nil)
+	}
+	saveKeyValue := func(key []byte, value []byte) (err error) {
+		return outputDB.Put(key, value, nil)
+	}
+
+	iter := inputDB.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		err = convertKeyValue(iter.Key(), iter.Value(), dbGet, saveNewChainHistory, saveKeyValue)
+		if err != nil {
+			return err
+		}
+	}
+	err = iter.Error()
+	if err != nil {
+		return err
+	}
+
+	if addNewStateData != nil {
+		err = addNewStateData(dbGet, saveNewChainHistory, saveKeyValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package statedata
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	// Register state data messages of every ABCI version
+	_ "github.com/ndidplatform/migration-tools/did/v1/protos/data"
+	_ "github.com/ndidplatform/migration-tools/did/v2/protos/data"
+	_ "github.com/ndidplatform/migration-tools/did/v3/protos/data"
+	_ "github.com/ndidplatform/migration-tools/did/v4/protos/data"
+	_ "github.com/ndidplatform/migration-tools/did/v5/protos/data"
+	_ "github.com/ndidplatform/migration-tools/did/v6/protos/data"
+	_ "github.com/ndidplatform/migration-tools/did/v7/protos/data"
+	_ "github.com/ndidplatform/migration-tools/did/v8/protos/data"
+	_ "github.com/ndidplatform/migration-tools/did/v9/protos/data"
+)
+
+const (
+	MinABCIVersion = 1
+	MaxABCIVersion = 9
+)
+
+const KeySeparator = "|"
+
+var KvPairPrefixKey = []byte("kvPairKey:")
+
+// Value types of DecodedValue
+const (
+	ValueTypeProto  = "proto"
+	ValueTypeJSON   = "json"
+	ValueTypeString = "string"
+	ValueTypeEmpty  = "empty"
+	ValueTypeHex    = "hex"
+)
+
+// Protobuf message (in did/vN/protos/data) of value by first part of key.
+// Message which does not exist in an ABCI version is decoded as raw value.
+var keyPrefixMessages = map[string]string{
+	"NodeID":                                "NodeDetail",
+	"NodeKey":                               "NodeKey",
+	"BehindProxyNode":                       "BehindNodeList",
+	"Token":                                 "Token",
+	"TokenPriceFunc":                        "TokenPrice",
+	"Service":                               "ServiceDetail",
+	"ServiceDestination":                    "ServiceDesList",
+	"ApproveKey":                            "ApproveService",
+	"ProvideService":                        "ServiceList",
+	"RefGroupCode":                          "ReferenceGroup",
+	"AllowedModeList":                       "AllowedModeList",
+	"Request":                               "Request",
+	"Message":                               "Message",
+	"ErrorCode":                             "ErrorCode",
+	"ErrorCodeList":                         "ErrorCodeList",
+	"ServicePriceCeiling":                   "ServicePriceCeilingList",
+	"ServicePriceListKey":                   "ServicePriceList",
+	"RequestType":                           "RequestType",
+	"NodeSupportedFeature":                  "NodeSupportedFeature",
+	"IdPList":                               "IdPList",
+	"AllNamespace":                          "NamespaceList",
+	"SupportedIALList":                      "SupportedIALList",
+	"SupportedAALList":                      "SupportedAALList",
+	"TimeOutBlockRegisterIdentity":          "TimeOutBlockRegisterIdentity",
+	"ServicePriceMinEffectiveDatetimeDelay": "ServicePriceMinEffectiveDatetimeDelay",
+	"AllowedMinIalForRegisterIdentityAtFirstIdp":     "AllowedMinIalForRegisterIdentityAtFirstIdp",
+	"SuppressedIdentityModificationNotificationNode": "SuppressedIdentityModificationNotificationNode",
+}
+
+// Keys with JSON value (not protobuf)
+var jsonValueKeyPrefixes = map[string]bool{
+	"stateKey":         true,
+	"ChainHistoryInfo": true,
+}
+
+type DecodedValue struct {
+	Key     string          `json:"key"`
+	Type    string          `json:"type"`
+	Message string          `json:"message,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// StripKvPairPrefix returns key without "kvPairKey:" prefix
+func StripKvPairPrefix(key []byte) []byte {
+	if len(key) >= len(KvPairPrefixKey) && string(key[:len(KvPairPrefixKey)]) == string(KvPairPrefixKey) {
+		return key[len(KvPairPrefixKey):]
+	}
+	return key
+}
+
+// KeyPrefix returns first part of key (without "kvPairKey:" prefix)
+func KeyPrefix(key []byte) string {
+	return strings.SplitN(string(StripKvPairPrefix(key)), KeySeparator, 2)[0]
+}
+
+// MessageName returns full name of protobuf message of value of key in given
+// ABCI version or empty string when value is not a known protobuf message.
+func MessageName(abciVersion int, key []byte) string {
+	keyParts := strings.Split(string(StripKvPairPrefix(key)), KeySeparator)
+	var messageName string
+	if len(keyParts) > 1 && keyParts[len(keyParts)-1] == "versions" {
+		messageName = "KeyVersions"
+	} else {
+		messageName = keyPrefixMessages[keyParts[0]]
+	}
+	if messageName == "" {
+		return ""
+	}
+	fullName := fmt.Sprintf("ndid_abci_state_v%d.%s", abciVersion, messageName)
+	if _, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(fullName)); err != nil {
+		return ""
+	}
+	return fullName
+}
+
+// UnmarshalValue unmarshals value of key to protobuf message of given ABCI
+// version. Returned message is nil when value is not a known protobuf message.
+func UnmarshalValue(abciVersion int, key []byte, value []byte) (message proto.Message, err error) {
+	messageName := MessageName(abciVersion, key)
+	if messageName == "" {
+		return nil, nil
+	}
+	message, err = NewMessage(messageName)
+	if err != nil {
+		return nil, err
+	}
+	err = proto.Unmarshal(value, message)
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// DecodeValue decodes value of key in given ABCI version to JSON. Value which
+// cannot be decoded is returned as hex with the decoding error.
+func DecodeValue(abciVersion int, key []byte, value []byte) *DecodedValue {
+	key = StripKvPairPrefix(key)
+	decoded := &DecodedValue{
+		Key: string(key),
+	}
+
+	if len(value) == 0 {
+		decoded.Type = ValueTypeEmpty
+		return decoded
+	}
+
+	messageName := MessageName(abciVersion, key)
+	if messageName != "" {
+		decoded.Message = messageName
+		message, err := UnmarshalValue(abciVersion, key, value)
+		if err == nil {
+			var valueJSON []byte
+			valueJSON, err = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(message)
+			if err == nil {
+				decoded.Type = ValueTypeProto
+				decoded.Value = valueJSON
+				return decoded
+			}
+		}
+		decoded.Error = err.Error()
+		decoded.Type = ValueTypeHex
+		decoded.Value = jsonString(hex.EncodeToString(value))
+		return decoded
+	}
+
+	if jsonValueKeyPrefixes[KeyPrefix(key)] && json.Valid(value) {
+		decoded.Type = ValueTypeJSON
+		decoded.Value = value
+		return decoded
+	}
+
+	if isPrintable(value) {
+		decoded.Type = ValueTypeString
+		decoded.Value = jsonString(string(value))
+		return decoded
+	}
+
+	decoded.Type = ValueTypeHex
+	decoded.Value = jsonString(hex.EncodeToString(value))
+	return decoded
+}
+
+func isPrintable(value []byte) bool {
+	if !utf8.Valid(value) {
+		return false
+	}
+	for _, r := range string(value) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonString(s string) json.RawMessage {
+	b, _ := json.Marshal(s)
+	return b
+}
+
+// NewMessage returns new empty protobuf message of full name
+func NewMessage(messageName string) (message proto.Message, err error) {
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(messageName))
+	if err != nil {
+		return nil, err
+	}
+	return messageType.New().Interface(), nil
+}
@@ -0,0 +1,286 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package statedata
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+// Changes of DiffEntry
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+type FieldDiff struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+type DiffEntry struct {
+	Key     string          `json:"key"`
+	Change  string          `json:"change"`
+	Message string          `json:"message,omitempty"`
+	Fields  []FieldDiff     `json:"fields,omitempty"`
+	Old     json.RawMessage `json:"old,omitempty"`
+	New     json.RawMessage `json:"new,omitempty"`
+}
+
+type DiffCounts struct {
+	Unchanged int64 `json:"unchanged"`
+	Added     int64 `json:"added"`
+	Removed   int64 `json:"removed"`
+	Changed   int64 `json:"changed"`
+}
+
+func (c *DiffCounts) add(change string) {
+	switch change {
+	case ChangeAdded:
+		c.Added++
+	case ChangeRemoved:
+		c.Removed++
+	case ChangeChanged:
+		c.Changed++
+	default:
+		c.Unchanged++
+	}
+}
+
+type DiffSummary struct {
+	DiffCounts
+	ByPrefix map[string]*DiffCounts `json:"by_prefix"`
+}
+
+// Diff compares state data in DB a (old) and DB b (new) of the same ABCI
+// version and calls fn with every added, removed or changed key in key order.
+// Values which are different in bytes but decode to the same JSON are
+// counted as unchanged. Keys with any of ignorePrefixes are skipped.
+func Diff(
+	a *leveldb.DB,
+	b *leveldb.DB,
+	abciVersion int,
+	ignorePrefixes []string,
+	fn func(entry *DiffEntry) error,
+) (summary *DiffSummary, err error) {
+	summary = &DiffSummary{
+		ByPrefix: make(map[string]*DiffCounts),
+	}
+
+	iterA := a.NewIterator(nil, nil)
+	defer iterA.Release()
+	iterB := b.NewIterator(nil, nil)
+	defer iterB.Release()
+
+	validA := iterA.Next()
+	validB := iterB.Next()
+	for validA || validB {
+		var key []byte
+		var entry *DiffEntry
+		switch {
+		case validA && (!validB || bytes.Compare(iterA.Key(), iterB.Key()) < 0):
+			key = append([]byte(nil), iterA.Key()...)
+			if !hasAnyPrefix(key, ignorePrefixes) {
+				entry = diffRemoved(abciVersion, key, iterA.Value())
+			}
+			validA = iterA.Next()
+		case validB && (!validA || bytes.Compare(iterB.Key(), iterA.Key()) < 0):
+			key = append([]byte(nil), iterB.Key()...)
+			if !hasAnyPrefix(key, ignorePrefixes) {
+				entry = diffAdded(abciVersion, key, iterB.Value())
+			}
+			validB = iterB.Next()
+		default:
+			key = append([]byte(nil), iterA.Key()...)
+			if !hasAnyPrefix(key, ignorePrefixes) {
+				entry = diffChanged(abciVersion, key, iterA.Value(), iterB.Value())
+				if entry == nil {
+					summary.add("")
+					summary.prefixCounts(key).add("")
+				}
+			}
+			validA = iterA.Next()
+			validB = iterB.Next()
+		}
+		if entry == nil {
+			continue
+		}
+		summary.add(entry.Change)
+		summary.prefixCounts(key).add(entry.Change)
+		err = fn(entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = firstError(iterA, iterB)
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+func (s *DiffSummary) prefixCounts(key []byte) *DiffCounts {
+	prefix := KeyPrefix(key)
+	counts, ok := s.ByPrefix[prefix]
+	if !ok {
+		counts = new(DiffCounts)
+		s.ByPrefix[prefix] = counts
+	}
+	return counts
+}
+
+func firstError(iters ...iterator.Iterator) error {
+	for _, iter := range iters {
+		if err := iter.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasAnyPrefix(key []byte, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(string(key), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func diffAdded(abciVersion int, key []byte, value []byte) *DiffEntry {
+	decoded := DecodeValue(abciVersion, key, value)
+	return &DiffEntry{
+		Key:     decoded.Key,
+		Change:  ChangeAdded,
+		Message: decoded.Message,
+		New:     decodedValueJSON(decoded),
+	}
+}
+
+func diffRemoved(abciVersion int, key []byte, value []byte) *DiffEntry {
+	decoded := DecodeValue(abciVersion, key, value)
+	return &DiffEntry{
+		Key:     decoded.Key,
+		Change:  ChangeRemoved,
+		Message: decoded.Message,
+		Old:     decodedValueJSON(decoded),
+	}
+}
+
+// diffChanged returns nil when values are the same
+func diffChanged(abciVersion int, key []byte, oldValue []byte, newValue []byte) *DiffEntry {
+	if bytes.Equal(oldValue, newValue) {
+		return nil
+	}
+	oldDecoded := DecodeValue(abciVersion, key, oldValue)
+	newDecoded := DecodeValue(abciVersion, key, newValue)
+	entry := &DiffEntry{
+		Key:     oldDecoded.Key,
+		Change:  ChangeChanged,
+		Message: oldDecoded.Message,
+		Old:     decodedValueJSON(oldDecoded),
+		New:     decodedValueJSON(newDecoded),
+	}
+	if isStructured(oldDecoded) && isStructured(newDecoded) {
+		var oldJSON, newJSON interface{}
+		if json.Unmarshal(oldDecoded.Value, &oldJSON) == nil && json.Unmarshal(newDecoded.Value, &newJSON) == nil {
+			entry.Fields = make([]FieldDiff, 0)
+			diffJSON("", oldJSON, newJSON, &entry.Fields)
+			if len(entry.Fields) == 0 {
+				return nil
+			}
+		}
+	}
+	return entry
+}
+
+func isStructured(decoded *DecodedValue) bool {
+	return decoded.Type == ValueTypeProto || decoded.Type == ValueTypeJSON
+}
+
+func decodedValueJSON(decoded *DecodedValue) json.RawMessage {
+	if decoded.Type == ValueTypeEmpty {
+		return json.RawMessage(`""`)
+	}
+	return decoded.Value
+}
+
+// diffJSON appends differences between old and new decoded JSON values.
+// Object fields are joined with "." and list items are indexed with "[i]".
+func diffJSON(field string, oldValue interface{}, newValue interface{}, fields *[]FieldDiff) {
+	oldMap, oldIsMap := oldValue.(map[string]interface{})
+	newMap, newIsMap := newValue.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		names := make([]string, 0, len(oldMap)+len(newMap))
+		for name := range oldMap {
+			names = append(names, name)
+		}
+		for name := range newMap {
+			if _, ok := oldMap[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			subField := name
+			if field != "" {
+				subField = field + "." + name
+			}
+			diffJSON(subField, oldMap[name], newMap[name], fields)
+		}
+		return
+	}
+
+	oldList, oldIsList := oldValue.([]interface{})
+	newList, newIsList := newValue.([]interface{})
+	if oldIsList && newIsList {
+		for i := 0; i < len(oldList) || i < len(newList); i++ {
+			var oldItem, newItem interface{}
+			if i < len(oldList) {
+				oldItem = oldList[i]
+			}
+			if i < len(newList) {
+				newItem = newList[i]
+			}
+			diffJSON(field+"["+strconv.Itoa(i)+"]", oldItem, newItem, fields)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(oldValue, newValue) {
+		*fields = append(*fields, FieldDiff{
+			Field: field,
+			Old:   oldValue,
+			New:   newValue,
+		})
+	}
+}
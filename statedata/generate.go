@@ -0,0 +1,593 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package statedata
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ndidplatform/migration-tools/convert"
+	didProtoV8 "github.com/ndidplatform/migration-tools/did/v8/protos/data"
+	v9 "github.com/ndidplatform/migration-tools/did/v9"
+	didProtoV9 "github.com/ndidplatform/migration-tools/did/v9/protos/data"
+	v9types "github.com/ndidplatform/migration-tools/did/v9/types"
+	tmRand "github.com/ndidplatform/migration-tools/rand"
+)
+
+const (
+	generatedNDIDNodeID = "NDID"
+	generatedNamespace  = "citizen_id"
+	generatedCurrency   = "THB"
+)
+
+type GenerateOptions struct {
+	// Nodes other than NDID, roles are assigned in turn (IdP, RP, AS)
+	NodeCount     int
+	ServiceCount  int
+	RefGroupCount int
+	// Accessors of each IdP in each reference group
+	AccessorCount       int
+	RequestCount        int
+	RequestVersionCount int
+	// Nodes with token
+	TokenCount int
+	// Prices in price list of each service of each AS
+	ServicePriceCount int
+	// Seed of generated IDs, hashes and values. Public keys are taken from a
+	// fixed pool of RSA keys.
+	Seed        int64
+	ChainID     string
+	BlockHeight int64
+}
+
+type GenerateReport struct {
+	KeyCount  int64            `json:"key_count"`
+	KeyCounts map[string]int64 `json:"key_counts"`
+}
+
+type stateGenerator struct {
+	abciVersion int
+	options     GenerateOptions
+	rand        *tmRand.Rand
+	save        func(key []byte, value []byte) (err error)
+	report      *GenerateReport
+
+	idpNodeIDs []string
+	rpNodeIDs  []string
+	asNodeIDs  []string
+	serviceIDs []string
+}
+
+// Generate generates state data of ABCI version 7, 8 or 9. Messages other
+// than NodeDetail have the same fields in v7, v8 and v9 (v9 adds fields), so
+// did/v9/protos/data messages without fields added in v9 are saved as v7 and
+// v8 values. Keys are saved with "kvPairKey:" prefix except ABCI state
+// metadata (stateKey) as in ABCI state DB.
+func Generate(
+	abciVersion int,
+	options GenerateOptions,
+	save func(key []byte, value []byte) (err error),
+) (report *GenerateReport, err error) {
+	if abciVersion < 7 || abciVersion > MaxABCIVersion {
+		return nil, fmt.Errorf("generating state data of ABCI version %d is not supported", abciVersion)
+	}
+	if options.NodeCount < 0 || options.ServiceCount < 0 || options.RefGroupCount < 0 ||
+		options.AccessorCount < 0 || options.RequestCount < 0 || options.TokenCount < 0 ||
+		options.ServicePriceCount < 0 {
+		return nil, errors.New("count must not be negative")
+	}
+	if options.RequestVersionCount < 1 {
+		return nil, errors.New("request version count must be at least 1")
+	}
+	if (options.RefGroupCount > 0 || options.RequestCount > 0) && options.NodeCount < 2 {
+		return nil, errors.New("reference groups and requests require at least 2 nodes (IdP and RP)")
+	}
+
+	g := &stateGenerator{
+		abciVersion: abciVersion,
+		options:     options,
+		rand:        tmRand.NewRand(),
+		save:        save,
+		report: &GenerateReport{
+			KeyCounts: make(map[string]int64),
+		},
+	}
+	g.rand.Seed(options.Seed)
+
+	for _, generate := range []func() error{
+		g.generateNodes,
+		g.generateServices,
+		g.generateRefGroups,
+		g.generateRequests,
+		g.generateV9StateData,
+		g.generateABCIState,
+	} {
+		err = generate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return g.report, nil
+}
+
+func (g *stateGenerator) saveValue(key string, value []byte) (err error) {
+	g.report.KeyCount++
+	g.report.KeyCounts[KeyPrefix([]byte(key))]++
+	return g.save(append(append(make([]byte, 0), KvPairPrefixKey...), key...), value)
+}
+
+func (g *stateGenerator) saveMessage(key string, message proto.Message) (err error) {
+	value, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return g.saveValue(key, value)
+}
+
+func (g *stateGenerator) publicKey() string {
+	return generatedPublicKeys[g.rand.Intn(len(generatedPublicKeys))]
+}
+
+func (g *stateGenerator) hash() string {
+	return hex.EncodeToString(g.rand.Bytes(32))
+}
+
+func (g *stateGenerator) uuid() string {
+	b := g.rand.Bytes(16)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// blockHeight returns random block height up to options.BlockHeight
+func (g *stateGenerator) blockHeight() int64 {
+	if g.options.BlockHeight < 1 {
+		return 1
+	}
+	return g.rand.Int63n(g.options.BlockHeight) + 1
+}
+
+func (g *stateGenerator) saveNodeKey(keyType string, nodeID string, publicKey string, algorithm string, creationBlockHeight int64) (nodeKey *didProtoV9.NodeKey, err error) {
+	nodeKey = &didProtoV9.NodeKey{
+		PublicKey:           publicKey,
+		Algorithm:           algorithm,
+		Version:             1,
+		CreationBlockHeight: creationBlockHeight,
+		CreationChainId:     g.options.ChainID,
+		Active:              true,
+	}
+	key := v9.NodeKeyKeyPrefix + v9.KeySeparator + keyType + v9.KeySeparator + nodeID + v9.KeySeparator + "1"
+	return nodeKey, g.saveMessage(key, nodeKey)
+}
+
+// generateNode generates node detail. Random values are generated in the same
+// order in every ABCI version so that the same seed gives the same nodes, v8
+// public key is v9 signing and encryption key as in conversion to v9.
+func (g *stateGenerator) generateNode(nodeID string, role v9types.NodeRole) (err error) {
+	publicKey := g.publicKey()
+	masterPublicKey := g.publicKey()
+	creationBlockHeight := g.blockHeight()
+
+	nodeName := fmt.Sprintf("Generated %s node %s", role, nodeID)
+	var mq []*didProtoV9.MQ
+	if role != v9types.NodeRoleNdid {
+		mq = []*didProtoV9.MQ{
+			{
+				Ip:   fmt.Sprintf("10.0.%d.%d", g.rand.Intn(256), g.rand.Intn(256)),
+				Port: 5555,
+			},
+		}
+	}
+	var maxIal, maxAal float64
+	var supportedRequestMessageDataUrlTypeList []string
+	if role == v9types.NodeRoleIdp {
+		maxIal = 3
+		maxAal = 3
+		supportedRequestMessageDataUrlTypeList = []string{"text/plain"}
+	}
+
+	if g.abciVersion < 9 {
+		nodeDetail := &didProtoV8.NodeDetail{
+			PublicKey:                              publicKey,
+			MasterPublicKey:                        masterPublicKey,
+			NodeName:                               nodeName,
+			Role:                                   string(role),
+			MaxIal:                                 maxIal,
+			MaxAal:                                 maxAal,
+			Active:                                 true,
+			SupportedRequestMessageDataUrlTypeList: supportedRequestMessageDataUrlTypeList,
+			OnTheFlySupport:                        role == v9types.NodeRoleIdp,
+		}
+		for _, mqAddress := range mq {
+			nodeDetail.Mq = append(nodeDetail.Mq, &didProtoV8.MQ{Ip: mqAddress.Ip, Port: mqAddress.Port})
+		}
+		return g.saveMessage(v9.NodeIDKeyPrefix+v9.KeySeparator+nodeID, nodeDetail)
+	}
+
+	nodeDetail := &didProtoV9.NodeDetail{
+		NodeName:                               nodeName,
+		Role:                                   string(role),
+		MaxIal:                                 maxIal,
+		MaxAal:                                 maxAal,
+		Mq:                                     mq,
+		Active:                                 true,
+		SupportedRequestMessageDataUrlTypeList: supportedRequestMessageDataUrlTypeList,
+	}
+	signatureAlgorithm := string(v9types.SignatureAlgorithmRSAPKCS1V15SHA256)
+	nodeDetail.SigningPublicKey, err = g.saveNodeKey("signing", nodeID, publicKey, signatureAlgorithm, creationBlockHeight)
+	if err != nil {
+		return err
+	}
+	nodeDetail.SigningMasterPublicKey, err = g.saveNodeKey("signing_master", nodeID, masterPublicKey, signatureAlgorithm, creationBlockHeight)
+	if err != nil {
+		return err
+	}
+	nodeDetail.EncryptionPublicKey, err = g.saveNodeKey("encryption", nodeID, publicKey, "RSAES_PKCS1_V1_5", creationBlockHeight)
+	if err != nil {
+		return err
+	}
+	if role == v9types.NodeRoleIdp {
+		nodeDetail.SupportedFeatureList = []string{"on_the_fly"}
+	}
+	return g.saveMessage(v9.NodeIDKeyPrefix+v9.KeySeparator+nodeID, nodeDetail)
+}
+
+func (g *stateGenerator) generateNodes() (err error) {
+	err = g.saveValue(string(v9.MasterNDIDKeyBytes), []byte(generatedNDIDNodeID))
+	if err != nil {
+		return err
+	}
+	err = g.saveValue(string(v9.InitStateKeyBytes), []byte("true"))
+	if err != nil {
+		return err
+	}
+	err = g.generateNode(generatedNDIDNodeID, v9types.NodeRoleNdid)
+	if err != nil {
+		return err
+	}
+
+	nodeIDs := make([]string, 0, g.options.NodeCount)
+	for i := 0; i < g.options.NodeCount; i++ {
+		var nodeID string
+		var role v9types.NodeRole
+		switch i % 3 {
+		case 0:
+			role = v9types.NodeRoleIdp
+			nodeID = "idp" + strconv.Itoa(len(g.idpNodeIDs)+1)
+			g.idpNodeIDs = append(g.idpNodeIDs, nodeID)
+		case 1:
+			role = v9types.NodeRoleRp
+			nodeID = "rp" + strconv.Itoa(len(g.rpNodeIDs)+1)
+			g.rpNodeIDs = append(g.rpNodeIDs, nodeID)
+		case 2:
+			role = v9types.NodeRoleAs
+			nodeID = "as" + strconv.Itoa(len(g.asNodeIDs)+1)
+			g.asNodeIDs = append(g.asNodeIDs, nodeID)
+		}
+		err = g.generateNode(nodeID, role)
+		if err != nil {
+			return err
+		}
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+
+	err = g.saveMessage(string(v9.IdpListKeyBytes), &didProtoV9.IdPList{
+		NodeId: g.idpNodeIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < g.options.TokenCount && i < len(nodeIDs); i++ {
+		err = g.saveMessage(v9.TokenKeyPrefix+v9.KeySeparator+nodeIDs[i], &didProtoV9.Token{
+			Amount: float64(g.rand.Intn(1000000)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return g.saveMessage(string(v9.AllNamespaceKeyBytes), &didProtoV9.NamespaceList{
+		Namespaces: []*didProtoV9.Namespace{
+			{
+				Namespace:                              generatedNamespace,
+				Description:                            "Generated namespace",
+				Active:                                 true,
+				AllowedIdentifierCountInReferenceGroup: -1,
+				AllowedActiveIdentifierCountInReferenceGroup: -1,
+			},
+		},
+	})
+}
+
+func (g *stateGenerator) generateServices() (err error) {
+	providedServices := make(map[string][]*didProtoV9.Service, len(g.asNodeIDs))
+	for i := 0; i < g.options.ServiceCount; i++ {
+		serviceID := "service" + strconv.Itoa(i+1)
+		g.serviceIDs = append(g.serviceIDs, serviceID)
+
+		err = g.saveMessage(v9.ServiceKeyPrefix+v9.KeySeparator+serviceID, &didProtoV9.ServiceDetail{
+			ServiceId:         serviceID,
+			ServiceName:       "Generated service " + serviceID,
+			DataSchema:        "n/a",
+			DataSchemaVersion: "n/a",
+			Active:            true,
+		})
+		if err != nil {
+			return err
+		}
+
+		asNodes := make([]*didProtoV9.ASNode, 0, len(g.asNodeIDs))
+		for _, asNodeID := range g.asNodeIDs {
+			asNodes = append(asNodes, &didProtoV9.ASNode{
+				NodeId:                 asNodeID,
+				MinIal:                 1.1,
+				MinAal:                 1,
+				ServiceId:              serviceID,
+				SupportedNamespaceList: []string{generatedNamespace},
+				Active:                 true,
+			})
+			providedServices[asNodeID] = append(providedServices[asNodeID], &didProtoV9.Service{
+				ServiceId:              serviceID,
+				MinIal:                 1.1,
+				MinAal:                 1,
+				Active:                 true,
+				SupportedNamespaceList: []string{generatedNamespace},
+			})
+
+			err = g.saveMessage(
+				v9.ApprovedServiceKeyPrefix+v9.KeySeparator+serviceID+v9.KeySeparator+asNodeID,
+				&didProtoV9.ApproveService{Active: true},
+			)
+			if err != nil {
+				return err
+			}
+
+			servicePrices := make([]*didProtoV9.ServicePrice, 0, g.options.ServicePriceCount)
+			for j := 0; j < g.options.ServicePriceCount; j++ {
+				minPrice := g.rand.Intn(100)
+				servicePrices = append(servicePrices, &didProtoV9.ServicePrice{
+					PriceByCurrencyList: []*didProtoV9.ServicePriceByCurrency{
+						{
+							Currency: generatedCurrency,
+							MinPrice: float64(minPrice),
+							MaxPrice: float64(minPrice + g.rand.Intn(100)),
+						},
+					},
+					EffectiveDatetime:   1600000000000 + int64(j)*86400000,
+					MoreInfoUrl:         "https://example.com/" + serviceID,
+					Detail:              "Generated price",
+					CreationBlockHeight: g.blockHeight(),
+					CreationChainId:     g.options.ChainID,
+				})
+			}
+			if len(servicePrices) > 0 {
+				err = g.saveMessage(
+					v9.ServicePriceListKeyPrefix+v9.KeySeparator+asNodeID+v9.KeySeparator+serviceID,
+					&didProtoV9.ServicePriceList{ServicePriceList: servicePrices},
+				)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if len(asNodes) > 0 {
+			err = g.saveMessage(v9.ServiceDestinationKeyPrefix+v9.KeySeparator+serviceID, &didProtoV9.ServiceDesList{
+				Node: asNodes,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, asNodeID := range g.asNodeIDs {
+		if len(providedServices[asNodeID]) == 0 {
+			continue
+		}
+		err = g.saveMessage(v9.ProvidedServicesKeyPrefix+v9.KeySeparator+asNodeID, &didProtoV9.ServiceList{
+			Services: providedServices[asNodeID],
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *stateGenerator) generateRefGroups() (err error) {
+	for i := 0; i < g.options.RefGroupCount; i++ {
+		refGroupCode := g.uuid()
+		identifierHash := g.hash()
+
+		// 1 or 2 IdPs in each reference group
+		idpCount := 1 + i%2
+		if idpCount > len(g.idpNodeIDs) {
+			idpCount = len(g.idpNodeIDs)
+		}
+		idps := make([]*didProtoV9.IdPInRefGroup, 0, idpCount)
+		for j := 0; j < idpCount; j++ {
+			idpNodeID := g.idpNodeIDs[(i+j)%len(g.idpNodeIDs)]
+			accessors := make([]*didProtoV9.Accessor, 0, g.options.AccessorCount)
+			for k := 0; k < g.options.AccessorCount; k++ {
+				accessorID := g.uuid()
+				creationBlockHeight := g.blockHeight()
+				accessor := &didProtoV9.Accessor{
+					AccessorId:        accessorID,
+					AccessorType:      "RSA",
+					AccessorPublicKey: g.publicKey(),
+					Active:            true,
+					Owner:             idpNodeID,
+				}
+				if g.abciVersion >= 9 {
+					accessor.CreationBlockHeight = creationBlockHeight
+					accessor.CreationChainId = g.options.ChainID
+				}
+				accessors = append(accessors, accessor)
+
+				err = g.saveValue(v9.AccessorToRefCodeKeyPrefix+v9.KeySeparator+accessorID, []byte(refGroupCode))
+				if err != nil {
+					return err
+				}
+			}
+			idps = append(idps, &didProtoV9.IdPInRefGroup{
+				NodeId:    idpNodeID,
+				Mode:      []int32{2, 3},
+				Accessors: accessors,
+				Ial:       2.3,
+				Active:    true,
+			})
+		}
+
+		err = g.saveMessage(v9.RefGroupCodeKeyPrefix+v9.KeySeparator+refGroupCode, &didProtoV9.ReferenceGroup{
+			Identities: []*didProtoV9.IdentityInRefGroup{
+				{
+					Namespace:      generatedNamespace,
+					IdentifierHash: identifierHash,
+					Active:         true,
+				},
+			},
+			Idps: idps,
+		})
+		if err != nil {
+			return err
+		}
+		err = g.saveValue(
+			v9.IdentityToRefCodeKeyPrefix+v9.KeySeparator+generatedNamespace+v9.KeySeparator+identifierHash,
+			[]byte(refGroupCode),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateRequests generates requests with a response of an IdP added in each
+// version, request is closed in the last version
+func (g *stateGenerator) generateRequests() (err error) {
+	for i := 0; i < g.options.RequestCount; i++ {
+		requestID := g.uuid()
+		idpNodeIDs := []string{g.idpNodeIDs[i%len(g.idpNodeIDs)]}
+		if len(g.idpNodeIDs) > 1 {
+			idpNodeIDs = append(idpNodeIDs, g.idpNodeIDs[(i+1)%len(g.idpNodeIDs)])
+		}
+		dataRequests := make([]*didProtoV9.DataRequest, 0, 1)
+		if len(g.serviceIDs) > 0 && len(g.asNodeIDs) > 0 {
+			dataRequests = append(dataRequests, &didProtoV9.DataRequest{
+				ServiceId:         g.serviceIDs[i%len(g.serviceIDs)],
+				AsIdList:          []string{g.asNodeIDs[i%len(g.asNodeIDs)]},
+				MinAs:             1,
+				RequestParamsHash: g.hash(),
+			})
+		}
+		request := &didProtoV9.Request{
+			RequestId:           requestID,
+			MinIdp:              1,
+			MinAal:              1,
+			MinIal:              1.1,
+			RequestTimeout:      86400,
+			IdpIdList:           idpNodeIDs,
+			DataRequestList:     dataRequests,
+			RequestMessageHash:  g.hash(),
+			Owner:               g.rpNodeIDs[i%len(g.rpNodeIDs)],
+			Mode:                2,
+			CreationBlockHeight: g.blockHeight(),
+			ChainId:             g.options.ChainID,
+		}
+
+		versions := make([]int64, 0, g.options.RequestVersionCount)
+		for version := 1; version <= g.options.RequestVersionCount; version++ {
+			if version > 1 {
+				request.ResponseList = append(request.ResponseList, &didProtoV9.Response{
+					Ial:       2.3,
+					Aal:       3,
+					Status:    "accept",
+					Signature: g.hash(),
+					IdpId:     idpNodeIDs[(version-2)%len(idpNodeIDs)],
+				})
+			}
+			request.Closed = version > 1 && version == g.options.RequestVersionCount
+			err = g.saveMessage(
+				v9.RequestKeyPrefix+v9.KeySeparator+requestID+v9.KeySeparator+strconv.Itoa(version),
+				request,
+			)
+			if err != nil {
+				return err
+			}
+			versions = append(versions, int64(version))
+		}
+		err = g.saveMessage(v9.RequestKeyPrefix+v9.KeySeparator+requestID+v9.KeySeparator+"versions", &didProtoV9.KeyVersions{
+			Versions: versions,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateV9StateData generates state data added in v9 with default seed
+func (g *stateGenerator) generateV9StateData() (err error) {
+	if g.abciVersion < 9 {
+		return nil
+	}
+	seed := convert.DefaultV9Seed()
+	for _, feature := range seed.SupportedFeatures {
+		err = g.saveMessage(v9.NodeSupportedFeatureKeyPrefix+v9.KeySeparator+feature, &didProtoV9.NodeSupportedFeature{})
+		if err != nil {
+			return err
+		}
+	}
+	err = g.saveMessage(string(v9.SupportedIALListKeyBytes), &didProtoV9.SupportedIALList{
+		IalList: seed.SupportedIALList,
+	})
+	if err != nil {
+		return err
+	}
+	return g.saveMessage(string(v9.SupportedAALListKeyBytes), &didProtoV9.SupportedAALList{
+		AalList: seed.SupportedAALList,
+	})
+}
+
+// generateABCIState generates ABCI state metadata at options.BlockHeight
+func (g *stateGenerator) generateABCIState() (err error) {
+	abciState := convert.ABCIState{
+		Size:    g.report.KeyCount,
+		Height:  g.options.BlockHeight,
+		AppHash: g.rand.Bytes(32),
+	}
+	abciStateJSON, err := json.Marshal(abciState)
+	if err != nil {
+		return err
+	}
+	g.report.KeyCount++
+	g.report.KeyCounts["stateKey"]++
+	return g.save([]byte("stateKey"), abciStateJSON)
+}
@@ -0,0 +1,68 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package statedata
+
+// generatedPublicKeys are fixed RSA 2048 public keys (private keys are not kept)
+// used as node and accessor public keys of generated state data, so that the
+// same seed gives the same state data
+var generatedPublicKeys = []string{
+	`-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAwMbXvHa7G8gglnKUr9d4
+/GGhdefeNidEQVyP3mM+L0BUD9OTowORK8h2JkhvNUsp5qEgGv8kqNp8Jc4hLUhX
+o1kQtjbr4CFTIQ+gJotzJXFBQ2r0gYOZ2LmQKQj31b2HDIw3PxOWLiSEGzNiDzqf
+D5leY4vvGs5zQ2yOkZXxv0MmBShxnY6/4KJJPdh9xXdjp+fK0Ufz0AXu19iCrMfE
+LfmF9tog5fK93Y6cI2OG29o9Bc+w8nD7OACR2Ef4Xs65H6QPAjAUGlFhGt9QOrKk
+ZoW4C85erD6MwqAmawCggjsX1SN7nULoyx7v/xgveGFcMdAGJzL5XtK3dBz7Dwi6
+8wIDAQAB
+-----END PUBLIC KEY-----
+`,
+	`-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAzySFwarSnovNAX0U9pio
+aj4jjh3TwAfP1mBblBdHi8lwZ6vF/IffczqNyvFBOGF5TMdMOeqw97TW8QCHNYd6
+LcgltT4Cf2ANtGYr6f6xRk2+wePZ+rcsNY+bAaZvM9IpkFXCoCiyZ8cnuxYYRl0K
+6+Z8FQS/rJrZ1lDCi0BhLEDUosSa4flB9INQQ/tehjPEp3cXeWkyEMgt6uGJdlLt
+fsFEay+kiNnrLpufnmWaxQHSd7BlgfHn+4DY78zbaK6GHx4YaRWUsnX8JIoGv8nW
+0sSZGzEK40CjEZRLtsnAwEaF//+UWIyOo1alCSuUBT9IPhcC6CsulZM3X045ZrjR
+IwIDAQAB
+-----END PUBLIC KEY-----
+`,
+	`-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAwbOhNUrPgKNRkD7FPJF+
+u2xMiLzcBCb9/WBqoycXk4qa+lp98nA0uuehxe5BvwF4X6nEDvyBsXMiQd6LWUW7
+nbwSFSuDDP8BGQe0tvjanqvvv/iwB+bV/KGt1+Ndm+AUlnZEnd4IPOVQG5dqLj0g
+XuasLK+RjMf1jUaSq7Z7qZs3P0YLQlOqd8W6u4QlBAByJQxp+Oq4gLVB2lwI42ac
+2XglpSCX3ZbpZrjiAPAkM6EX2DAHTgyPE+9rzdSDDEQ4wLwOmyvR6TCztJu1zh2m
+z8JkfVsYPj4wyWJlnWC1yrXMoNulaOxq6bGogW4aMpQVMB5tmaqnye+Xws1jsc76
+VQIDAQAB
+-----END PUBLIC KEY-----
+`,
+	`-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA2jb697Mud9e3vxbE2/V1
+exHUPtqoyTqBvLMzDUT5C2Rl/bHHRhlM9wE3Ofo0e9AaG0O2rencZPfKI2UxVRi7
+q9Ycj5krgWodQ+KIWhga/HNQMl4Xu+Hb8iFkT/a5LCF/ZlgXFqpK295Nc0jRMx3H
+PCToS+U1hw39e+22AyFpkSt8sIPLJAc0qzxwY5FV7zyec5Aow0qS6woc61ieRvxq
+bK4LwiA8Evwq9R9XwCEtJ+qrR6z86MkA0CunPgoycVbkuim9HMmn6FNckuKGeX2W
+eG7Ik6nWcrBrQR/pdgLr+hvxgW3qJONvRwvEMGjkXhvgFKeKv4+6wmmiZj+dw5m9
+pwIDAQAB
+-----END PUBLIC KEY-----
+`,
+}
@@ -0,0 +1,165 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package statedata
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ndidplatform/migration-tools/convert"
+	v9 "github.com/ndidplatform/migration-tools/did/v9"
+	didProtoV9 "github.com/ndidplatform/migration-tools/did/v9/protos/data"
+	"github.com/ndidplatform/migration-tools/proto"
+)
+
+var testGenerateOptions = GenerateOptions{
+	NodeCount:           6,
+	ServiceCount:        2,
+	RefGroupCount:       20,
+	AccessorCount:       2,
+	RequestCount:        20,
+	RequestVersionCount: 3,
+	TokenCount:          6,
+	ServicePriceCount:   2,
+	Seed:                1,
+	ChainID:             "test-chain",
+	BlockHeight:         1000,
+}
+
+func generateToMap(t testing.TB, abciVersion int, options GenerateOptions) (stateData map[string][]byte) {
+	stateData = make(map[string][]byte)
+	_, err := Generate(abciVersion, options, func(key []byte, value []byte) (err error) {
+		stateData[string(key)] = append([]byte{}, value...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stateData
+}
+
+func TestGenerateReproducible(t *testing.T) {
+	for _, abciVersion := range []int{8, 9} {
+		stateDataA := generateToMap(t, abciVersion, testGenerateOptions)
+		stateDataB := generateToMap(t, abciVersion, testGenerateOptions)
+		if len(stateDataA) != len(stateDataB) {
+			t.Fatalf("v%d: key count: %d, %d", abciVersion, len(stateDataA), len(stateDataB))
+		}
+		for key, value := range stateDataA {
+			if !bytes.Equal(value, stateDataB[key]) {
+				t.Errorf("v%d: value of key %s is different with the same seed", abciVersion, key)
+			}
+		}
+	}
+}
+
+// convertGeneratedV8ToV9 converts every key-value of v8 state data to v9,
+// keys are read without "kvPairKey:" prefix as in ConvertSource
+func convertGeneratedV8ToV9(t testing.TB, stateDataV8 map[string][]byte) (stateDataV9 map[string][]byte) {
+	stateDataV9 = make(map[string][]byte)
+	dbGet := func(key []byte) (value []byte, err error) {
+		return stateDataV8[string(KvPairPrefixKey)+string(key)], nil
+	}
+	saveNewChainHistory := func(chainHistory []byte) (err error) {
+		return nil
+	}
+	saveKeyValue := func(key []byte, value []byte) (err error) {
+		stateDataV9[string(key)] = value
+		return nil
+	}
+	for key, value := range stateDataV8 {
+		_, err := convert.ConvertStateDBDataV8ToV9([]byte(key), value, "", nil, dbGet, saveNewChainHistory, saveKeyValue)
+		if err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+	}
+	return stateDataV9
+}
+
+func TestConvertGeneratedV8ToV9(t *testing.T) {
+	stateDataV8 := generateToMap(t, 8, testGenerateOptions)
+	stateDataV9 := convertGeneratedV8ToV9(t, stateDataV8)
+	generatedV9 := generateToMap(t, 9, testGenerateOptions)
+
+	// Converted v8 has the same keys as generated v9 except state data added
+	// in v9 (added after conversion), ABCI state metadata, NDID init keys (set
+	// on restore) and request versions other than the first (request is
+	// converted to 1 version)
+	for key := range generatedV9 {
+		key = string(StripKvPairPrefix([]byte(key)))
+		prefix := KeyPrefix([]byte(key))
+		if prefix == "stateKey" || prefix == v9.NodeSupportedFeatureKeyPrefix ||
+			key == string(v9.MasterNDIDKeyBytes) || key == string(v9.InitStateKeyBytes) ||
+			key == string(v9.SupportedIALListKeyBytes) || key == string(v9.SupportedAALListKeyBytes) {
+			continue
+		}
+		if prefix == v9.RequestKeyPrefix && !strings.HasSuffix(key, v9.KeySeparator+"1") &&
+			!strings.HasSuffix(key, v9.KeySeparator+"versions") {
+			continue
+		}
+		if _, ok := stateDataV9[key]; !ok {
+			t.Errorf("key %s not found in converted state data", key)
+		}
+	}
+
+	// Request is converted to its last version
+	for key, value := range stateDataV8 {
+		key = string(StripKvPairPrefix([]byte(key)))
+		if KeyPrefix([]byte(key)) != v9.RequestKeyPrefix ||
+			!strings.HasSuffix(key, v9.KeySeparator+strconv.Itoa(testGenerateOptions.RequestVersionCount)) {
+			continue
+		}
+		requestID := strings.Split(key, v9.KeySeparator)[1]
+		if !bytes.Equal(stateDataV9[v9.RequestKeyPrefix+v9.KeySeparator+requestID+v9.KeySeparator+"1"], value) {
+			t.Errorf("request %s is not converted to its last version", requestID)
+		}
+	}
+
+	// v8 public key is converted to v9 signing and encryption key
+	var nodeDetail didProtoV9.NodeDetail
+	err := proto.Unmarshal(stateDataV9[v9.NodeIDKeyPrefix+v9.KeySeparator+"idp1"], &nodeDetail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var generatedNodeDetail didProtoV9.NodeDetail
+	err = proto.Unmarshal(generatedV9[string(KvPairPrefixKey)+v9.NodeIDKeyPrefix+v9.KeySeparator+"idp1"], &generatedNodeDetail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodeDetail.SigningPublicKey.PublicKey != generatedNodeDetail.SigningPublicKey.PublicKey ||
+		nodeDetail.EncryptionPublicKey.PublicKey != generatedNodeDetail.EncryptionPublicKey.PublicKey {
+		t.Error("converted node keys are different from generated v9 node keys")
+	}
+	if strings.Join(nodeDetail.SupportedFeatureList, ",") != strings.Join(generatedNodeDetail.SupportedFeatureList, ",") {
+		t.Errorf("supported features: %v, expected: %v", nodeDetail.SupportedFeatureList, generatedNodeDetail.SupportedFeatureList)
+	}
+}
+
+func BenchmarkConvertStateDBDataV8ToV9(b *testing.B) {
+	stateDataV8 := generateToMap(b, 8, testGenerateOptions)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		convertGeneratedV8ToV9(b, stateDataV8)
+	}
+}
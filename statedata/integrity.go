@@ -0,0 +1,317 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package statedata
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	minIntegrityCheckVersion = 7
+	maxIntegrityCheckVersion = 9
+)
+
+// Checks of IntegrityIssue
+const (
+	CheckDecode             = "decode"
+	CheckAccessorToRefGroup = "accessor_to_ref_group"
+	CheckIdentityToRefGroup = "identity_to_ref_group"
+	CheckIdPListNode        = "idp_list_node"
+	CheckProvidedService    = "provided_service"
+	CheckBehindProxyNode    = "behind_proxy_node"
+)
+
+// Repairs of IntegrityIssue
+const (
+	RepairDeleteKey      = "delete key"
+	RepairRemoveListItem = "remove list item"
+)
+
+type IntegrityIssue struct {
+	Check     string `json:"check"`
+	Key       string `json:"key"`
+	Reference string `json:"reference"`
+	Detail    string `json:"detail"`
+	Repair    string `json:"repair,omitempty"`
+}
+
+type IntegrityReport struct {
+	ABCIVersion     int              `json:"abci_version"`
+	KeyCount        int64            `json:"key_count"`
+	CheckedKeyCount int64            `json:"checked_key_count"`
+	IssueCounts     map[string]int64 `json:"issue_counts"`
+	Issues          []IntegrityIssue `json:"issues"`
+}
+
+// Keys referenced by other keys
+type integrityIndex struct {
+	// Proxy node ID by node ID
+	nodeProxyNodeIDs map[string]string
+	serviceIDs       map[string]bool
+	refGroupCodes    map[string]bool
+}
+
+// CheckIntegrity validates references between keys of state data:
+//   - accessorToRefCodeKey and identityToRefCodeKey to existing RefGroupCode
+//   - IdPList entries to existing NodeID
+//   - ProvideService entries to existing Service
+//   - BehindProxyNode entries to NodeID with the same proxy node ID
+//
+// When save is not nil, every key-value is saved with issues repaired by
+// deleting the key or removing the list item.
+func CheckIntegrity(
+	source Source,
+	abciVersion int,
+	save func(key []byte, value []byte) (err error),
+) (report *IntegrityReport, err error) {
+	if abciVersion < minIntegrityCheckVersion || abciVersion > maxIntegrityCheckVersion {
+		return nil, fmt.Errorf("integrity check of ABCI version %d is not supported", abciVersion)
+	}
+
+	report = &IntegrityReport{
+		ABCIVersion: abciVersion,
+		IssueCounts: make(map[string]int64),
+		Issues:      make([]IntegrityIssue, 0),
+	}
+	addIssue := func(issue IntegrityIssue) {
+		if save == nil {
+			issue.Repair = ""
+		}
+		report.IssueCounts[issue.Check]++
+		report.Issues = append(report.Issues, issue)
+	}
+
+	index, err := buildIntegrityIndex(source, abciVersion, addIssue)
+	if err != nil {
+		return nil, err
+	}
+
+	err = source.Iterate(nil, func(key []byte, value []byte) (stop bool, err error) {
+		report.KeyCount++
+		newValue, checked, err := checkKeyIntegrity(index, abciVersion, key, value, addIssue)
+		if err != nil {
+			return false, err
+		}
+		if checked {
+			report.CheckedKeyCount++
+		}
+		if save != nil && newValue != nil {
+			err = save(key, newValue)
+			if err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func buildIntegrityIndex(source Source, abciVersion int, addIssue func(issue IntegrityIssue)) (index *integrityIndex, err error) {
+	index = &integrityIndex{
+		nodeProxyNodeIDs: make(map[string]string),
+		serviceIDs:       make(map[string]bool),
+		refGroupCodes:    make(map[string]bool),
+	}
+	err = source.Iterate(nil, func(key []byte, value []byte) (stop bool, err error) {
+		keyParts := strings.Split(string(key), KeySeparator)
+		if len(keyParts) != 2 {
+			return false, nil
+		}
+		switch keyParts[0] {
+		case "NodeID":
+			message, err := UnmarshalValue(abciVersion, key, value)
+			if err != nil {
+				addIssue(IntegrityIssue{Check: CheckDecode, Key: string(key), Detail: err.Error()})
+				return false, nil
+			}
+			index.nodeProxyNodeIDs[keyParts[1]] = stringField(message, "proxy_node_id")
+		case "Service":
+			index.serviceIDs[keyParts[1]] = true
+		case "RefGroupCode":
+			index.refGroupCodes[keyParts[1]] = true
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// checkKeyIntegrity returns value to save (nil to delete key) and whether key
+// is checked
+func checkKeyIntegrity(
+	index *integrityIndex,
+	abciVersion int,
+	key []byte,
+	value []byte,
+	addIssue func(issue IntegrityIssue),
+) (newValue []byte, checked bool, err error) {
+	keyParts := strings.Split(string(key), KeySeparator)
+	switch {
+	case keyParts[0] == "accessorToRefCodeKey" || keyParts[0] == "identityToRefCodeKey":
+		check := CheckAccessorToRefGroup
+		if keyParts[0] == "identityToRefCodeKey" {
+			check = CheckIdentityToRefGroup
+		}
+		if !index.refGroupCodes[string(value)] {
+			addIssue(IntegrityIssue{
+				Check:     check,
+				Key:       string(key),
+				Reference: "RefGroupCode" + KeySeparator + string(value),
+				Detail:    "reference group does not exist",
+				Repair:    RepairDeleteKey,
+			})
+			return nil, true, nil
+		}
+		return value, true, nil
+	case string(key) == "IdPList":
+		return filterStringList(abciVersion, key, value, "node_id", addIssue, func(nodeID string) *IntegrityIssue {
+			if _, ok := index.nodeProxyNodeIDs[nodeID]; ok {
+				return nil
+			}
+			return &IntegrityIssue{
+				Check:     CheckIdPListNode,
+				Reference: "NodeID" + KeySeparator + nodeID,
+				Detail:    "node detail does not exist",
+			}
+		})
+	case keyParts[0] == "BehindProxyNode" && len(keyParts) == 2:
+		proxyNodeID := keyParts[1]
+		return filterStringList(abciVersion, key, value, "nodes", addIssue, func(nodeID string) *IntegrityIssue {
+			nodeProxyNodeID, ok := index.nodeProxyNodeIDs[nodeID]
+			if !ok {
+				return &IntegrityIssue{
+					Check:     CheckBehindProxyNode,
+					Reference: "NodeID" + KeySeparator + nodeID,
+					Detail:    "node detail does not exist",
+				}
+			}
+			if nodeProxyNodeID != proxyNodeID {
+				return &IntegrityIssue{
+					Check:     CheckBehindProxyNode,
+					Reference: "NodeID" + KeySeparator + nodeID,
+					Detail:    fmt.Sprintf("proxy node ID of node is %q", nodeProxyNodeID),
+				}
+			}
+			return nil
+		})
+	case keyParts[0] == "ProvideService" && len(keyParts) == 2:
+		message, err := UnmarshalValue(abciVersion, key, value)
+		if err != nil || message == nil {
+			return decodeIssue(key, value, err, addIssue)
+		}
+		servicesField := message.ProtoReflect().Descriptor().Fields().ByName("services")
+		services := message.ProtoReflect().Mutable(servicesField).List()
+		kept := 0
+		for i := 0; i < services.Len(); i++ {
+			service := services.Get(i)
+			serviceID := stringField(service.Message().Interface(), "service_id")
+			if !index.serviceIDs[serviceID] {
+				addIssue(IntegrityIssue{
+					Check:     CheckProvidedService,
+					Key:       string(key),
+					Reference: "Service" + KeySeparator + serviceID,
+					Detail:    "service does not exist",
+					Repair:    RepairRemoveListItem,
+				})
+				continue
+			}
+			services.Set(kept, service)
+			kept++
+		}
+		if kept == services.Len() {
+			return value, true, nil
+		}
+		services.Truncate(kept)
+		newValue, err = proto.MarshalOptions{Deterministic: true}.Marshal(message)
+		return newValue, true, err
+	}
+	return value, false, nil
+}
+
+// filterStringList removes items of string list field for which check
+// returns an issue
+func filterStringList(
+	abciVersion int,
+	key []byte,
+	value []byte,
+	fieldName protoreflect.Name,
+	addIssue func(issue IntegrityIssue),
+	check func(item string) *IntegrityIssue,
+) (newValue []byte, checked bool, err error) {
+	message, err := UnmarshalValue(abciVersion, key, value)
+	if err != nil || message == nil {
+		return decodeIssue(key, value, err, addIssue)
+	}
+	field := message.ProtoReflect().Descriptor().Fields().ByName(fieldName)
+	list := message.ProtoReflect().Mutable(field).List()
+	kept := 0
+	for i := 0; i < list.Len(); i++ {
+		item := list.Get(i)
+		if issue := check(item.String()); issue != nil {
+			issue.Key = string(key)
+			issue.Repair = RepairRemoveListItem
+			addIssue(*issue)
+			continue
+		}
+		list.Set(kept, item)
+		kept++
+	}
+	if kept == list.Len() {
+		return value, true, nil
+	}
+	list.Truncate(kept)
+	newValue, err = proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	return newValue, true, err
+}
+
+// decodeIssue reports value which cannot be decoded. Value is kept as is.
+func decodeIssue(
+	key []byte,
+	value []byte,
+	err error,
+	addIssue func(issue IntegrityIssue),
+) (newValue []byte, checked bool, _ error) {
+	detail := "unknown message"
+	if err != nil {
+		detail = err.Error()
+	}
+	addIssue(IntegrityIssue{Check: CheckDecode, Key: string(key), Detail: detail})
+	return value, true, nil
+}
+
+func stringField(message proto.Message, fieldName protoreflect.Name) string {
+	field := message.ProtoReflect().Descriptor().Fields().ByName(fieldName)
+	if field == nil {
+		return ""
+	}
+	return message.ProtoReflect().Get(field).String()
+}
@@ -0,0 +1,154 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package statedata
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// pseudonymizer replaces values with pseudonyms derived from HMAC-SHA256 with
+// salt, the same value of the same kind is always replaced with the same
+// pseudonym
+type pseudonymizer struct {
+	salt []byte
+}
+
+func (p *pseudonymizer) sum(kind string, value string) []byte {
+	mac := hmac.New(sha256.New, p.salt)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// bytes returns n pseudonymous bytes
+func (p *pseudonymizer) bytes(kind string, value string, n int) []byte {
+	result := make([]byte, 0, n+sha256.Size)
+	for counter := 0; len(result) < n; counter++ {
+		result = append(result, p.sum(kind+":"+strconv.Itoa(counter), value)...)
+	}
+	return result[:n]
+}
+
+// uuid returns pseudonym formatted as UUID (version 4)
+func (p *pseudonymizer) uuid(kind string, value string) string {
+	b := p.bytes(kind, value, 16)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// publicKeyPEM returns public key PEM of the same algorithm and PEM type as
+// public key in block, which is always the same for the same value: RSA key
+// from pool of generated public keys, ECDSA key of the same curve or Ed25519
+// key derived from pseudonym. ok is false when key type is not supported.
+func (p *pseudonymizer) publicKeyPEM(kind string, value string, block *pem.Block) (publicKeyPEM string, ok bool) {
+	var publicKey interface{}
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		publicKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		publicKey, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return "", false
+	}
+	if err != nil {
+		return "", false
+	}
+
+	var newPublicKey interface{}
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		// Generating RSA key from pseudonym is not deterministic
+		index := binary.BigEndian.Uint32(p.sum(kind, value)) % uint32(len(generatedPublicKeys))
+		poolBlock, _ := pem.Decode([]byte(generatedPublicKeys[index]))
+		newPublicKey, err = x509.ParsePKIXPublicKey(poolBlock.Bytes)
+		if err != nil {
+			return "", false
+		}
+	case *ecdsa.PublicKey:
+		// Private key in [1, N-1]
+		curveOrder := publicKey.Curve.Params().N
+		d := new(big.Int).SetBytes(p.bytes(kind, value, curveOrder.BitLen()/8+8))
+		d.Mod(d, new(big.Int).Sub(curveOrder, big.NewInt(1)))
+		d.Add(d, big.NewInt(1))
+		x, y := publicKey.Curve.ScalarBaseMult(d.Bytes())
+		newPublicKey = &ecdsa.PublicKey{Curve: publicKey.Curve, X: x, Y: y}
+	case ed25519.PublicKey:
+		newPublicKey = ed25519.NewKeyFromSeed(p.bytes(kind, value, ed25519.SeedSize)).Public()
+	default:
+		return "", false
+	}
+
+	var publicKeyBytes []byte
+	if block.Type == "RSA PUBLIC KEY" {
+		publicKeyBytes = x509.MarshalPKCS1PublicKey(newPublicKey.(*rsa.PublicKey))
+	} else {
+		publicKeyBytes, err = x509.MarshalPKIXPublicKey(newPublicKey)
+		if err != nil {
+			return "", false
+		}
+	}
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  block.Type,
+		Bytes: publicKeyBytes,
+	})), true
+}
+
+// like returns pseudonym in the same encoding as value: public key PEM of the
+// same algorithm for public key PEM, PEM block of the same type, hex or base64
+// of the same length, hex of the same length otherwise
+func (p *pseudonymizer) like(kind string, value string) string {
+	if value == "" {
+		return ""
+	}
+	if block, rest := pem.Decode([]byte(value)); block != nil && len(bytes.TrimSpace(rest)) == 0 {
+		if publicKeyPEM, ok := p.publicKeyPEM(kind, value, block); ok {
+			return publicKeyPEM
+		}
+		return string(pem.EncodeToMemory(&pem.Block{
+			Type:  block.Type,
+			Bytes: p.bytes(kind, value, len(block.Bytes)),
+		}))
+	}
+	if _, err := hex.DecodeString(value); err == nil {
+		return hex.EncodeToString(p.bytes(kind, value, len(value)/2))
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+		return base64.StdEncoding.EncodeToString(p.bytes(kind, value, len(decoded)))
+	}
+	return hex.EncodeToString(p.bytes(kind, value, (len(value)+1)/2))[:len(value)]
+}
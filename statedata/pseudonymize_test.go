@@ -0,0 +1,156 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package statedata
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	didProtoV9 "github.com/ndidplatform/migration-tools/did/v9/protos/data"
+)
+
+func testPublicKeyPEM(t *testing.T, blockType string, publicKey interface{}) string {
+	var publicKeyBytes []byte
+	var err error
+	if blockType == "RSA PUBLIC KEY" {
+		publicKeyBytes = x509.MarshalPKCS1PublicKey(publicKey.(*rsa.PublicKey))
+	} else {
+		publicKeyBytes, err = x509.MarshalPKIXPublicKey(publicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: publicKeyBytes}))
+}
+
+func parseTestPublicKeyPEM(t *testing.T, publicKeyPEM string) (blockType string, publicKey interface{}) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		t.Fatalf("invalid public key PEM: %s", publicKeyPEM)
+	}
+	var err error
+	if block.Type == "RSA PUBLIC KEY" {
+		publicKey, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	} else {
+		publicKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return block.Type, publicKey
+}
+
+func TestPseudonymizePublicKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519PublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &pseudonymizer{salt: []byte("salt")}
+	for _, testCase := range []struct {
+		blockType string
+		publicKey interface{}
+	}{
+		{"PUBLIC KEY", &rsaKey.PublicKey},
+		{"RSA PUBLIC KEY", &rsaKey.PublicKey},
+		{"PUBLIC KEY", &ecdsaKey.PublicKey},
+		{"PUBLIC KEY", ed25519PublicKey},
+	} {
+		publicKeyPEM := testPublicKeyPEM(t, testCase.blockType, testCase.publicKey)
+		pseudonym := p.like("accessor_public_key", publicKeyPEM)
+		if pseudonym != p.like("accessor_public_key", publicKeyPEM) {
+			t.Errorf("%T: pseudonym of the same public key is different", testCase.publicKey)
+		}
+		if pseudonym == publicKeyPEM {
+			t.Errorf("%T: public key is not replaced", testCase.publicKey)
+		}
+
+		blockType, publicKey := parseTestPublicKeyPEM(t, pseudonym)
+		if blockType != testCase.blockType {
+			t.Errorf("PEM type: %s, expected: %s", blockType, testCase.blockType)
+		}
+		if fmt.Sprintf("%T", publicKey) != fmt.Sprintf("%T", testCase.publicKey) {
+			t.Errorf("public key type: %T, expected: %T", publicKey, testCase.publicKey)
+		}
+		if ecdsaPublicKey, ok := publicKey.(*ecdsa.PublicKey); ok {
+			if ecdsaPublicKey.Curve != ecdsaKey.Curve || !ecdsaPublicKey.Curve.IsOnCurve(ecdsaPublicKey.X, ecdsaPublicKey.Y) {
+				t.Error("ECDSA public key is not a point of the same curve")
+			}
+		}
+	}
+}
+
+func TestPseudonymizeRefGroupAccessorKeyType(t *testing.T) {
+	refGroup := &didProtoV9.ReferenceGroup{
+		Idps: []*didProtoV9.IdPInRefGroup{
+			{
+				NodeId: "idp1",
+				Accessors: []*didProtoV9.Accessor{
+					{
+						AccessorId:        "accessor1",
+						AccessorType:      "RSA",
+						AccessorPublicKey: generatedPublicKeys[0],
+					},
+				},
+			},
+		},
+	}
+	value, err := proto.Marshal(refGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newValue, err := pseudonymizeRefGroup(&pseudonymizer{salt: []byte("salt")}, value, &SampleReport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var newRefGroup didProtoV9.ReferenceGroup
+	err = proto.Unmarshal(newValue, &newRefGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessor := newRefGroup.Idps[0].Accessors[0]
+	if accessor.AccessorType != "RSA" {
+		t.Errorf("accessor type: %s, expected: RSA", accessor.AccessorType)
+	}
+	_, publicKey := parseTestPublicKeyPEM(t, accessor.AccessorPublicKey)
+	if _, ok := publicKey.(*rsa.PublicKey); !ok {
+		t.Errorf("public key type of RSA accessor: %T", publicKey)
+	}
+}
@@ -0,0 +1,292 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package statedata
+
+import (
+	"bytes"
+	"container/heap"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	didProtoV9 "github.com/ndidplatform/migration-tools/did/v9/protos/data"
+)
+
+const sampleVersion = 9
+
+// Keys not written to sample (as in create-initial-state-data), messages and
+// data signatures are not sampled
+var sampleSkippedKeyPrefixes = map[string]bool{
+	"stateKey":   true,
+	"lastBlock":  true,
+	"MasterNDID": true,
+	"InitState":  true,
+	"Validator":  true,
+	"Message":    true,
+	"SignData":   true,
+}
+
+type SampleOptions struct {
+	RefGroupCount int
+	RequestCount  int
+	// Salt of pseudonyms and sampling, same salt gives the same sample
+	Salt string
+}
+
+type SampleReport struct {
+	KeyCount        int64 `json:"key_count"`
+	WrittenKeyCount int64 `json:"written_key_count"`
+	RefGroupCount   int64 `json:"ref_group_count"`
+	IdentityCount   int64 `json:"identity_count"`
+	AccessorCount   int64 `json:"accessor_count"`
+	RequestCount    int64 `json:"request_count"`
+}
+
+type sampleItem struct {
+	rank    []byte
+	id      string
+	version int64
+}
+
+// sampleHeap is max-heap of sampleItem by rank
+type sampleHeap []sampleItem
+
+func (h sampleHeap) Len() int            { return len(h) }
+func (h sampleHeap) Less(i, j int) bool  { return bytes.Compare(h[i].rank, h[j].rank) > 0 }
+func (h sampleHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *sampleHeap) Push(x interface{}) { *h = append(*h, x.(sampleItem)) }
+func (h *sampleHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// add keeps count items with lowest rank
+func (h *sampleHeap) add(item sampleItem, count int) {
+	heap.Push(h, item)
+	if h.Len() > count {
+		heap.Pop(h)
+	}
+}
+
+// Sample writes state data of ABCI v9 source with sampled reference groups
+// (with their identityToRefCodeKey and accessorToRefCodeKey) and requests
+// (latest version only, as version 1). Identifier hashes, accessor IDs,
+// accessor public keys, reference group codes, request message hashes and
+// response signatures are pseudonymised. Other keys are written as is.
+func Sample(
+	source Source,
+	abciVersion int,
+	options SampleOptions,
+	save func(key []byte, value []byte) (err error),
+	saveChainHistory func(chainHistory []byte) (err error),
+) (report *SampleReport, err error) {
+	if abciVersion != sampleVersion {
+		return nil, fmt.Errorf("sampling of ABCI version %d is not supported", abciVersion)
+	}
+	if options.Salt == "" {
+		return nil, errors.New("salt is required")
+	}
+
+	p := &pseudonymizer{salt: []byte(options.Salt)}
+
+	// Select reference groups and requests with lowest rank
+	refGroups := make(sampleHeap, 0, options.RefGroupCount+1)
+	err = source.Iterate([]byte("RefGroupCode"+KeySeparator), func(key []byte, value []byte) (stop bool, err error) {
+		refGroupCode := strings.TrimPrefix(string(key), "RefGroupCode"+KeySeparator)
+		refGroups.add(sampleItem{
+			rank: p.sum("sample_ref_group", refGroupCode),
+			id:   refGroupCode,
+		}, options.RefGroupCount)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sampledRefGroups := make(map[string]bool, len(refGroups))
+	for _, item := range refGroups {
+		sampledRefGroups[item.id] = true
+	}
+
+	requests := make(sampleHeap, 0, options.RequestCount+1)
+	err = source.Iterate([]byte("Request"+KeySeparator), func(key []byte, value []byte) (stop bool, err error) {
+		keyParts := strings.Split(string(key), KeySeparator)
+		if len(keyParts) != 3 || keyParts[2] != "versions" {
+			return false, nil
+		}
+		var keyVersions didProtoV9.KeyVersions
+		err = proto.Unmarshal(value, &keyVersions)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", key, err)
+		}
+		if len(keyVersions.Versions) == 0 {
+			return false, nil
+		}
+		requests.add(sampleItem{
+			rank:    p.sum("sample_request", keyParts[1]),
+			id:      keyParts[1],
+			version: keyVersions.Versions[len(keyVersions.Versions)-1],
+		}, options.RequestCount)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sampledRequestVersions := make(map[string]int64, len(requests))
+	for _, item := range requests {
+		sampledRequestVersions[item.id] = item.version
+	}
+
+	ndidNodeID, err := source.Get([]byte("MasterNDID"))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	report = new(SampleReport)
+	write := func(key []byte, value []byte) (err error) {
+		report.WrittenKeyCount++
+		return save(key, value)
+	}
+	err = source.Iterate(nil, func(key []byte, value []byte) (stop bool, err error) {
+		report.KeyCount++
+		keyParts := strings.Split(string(key), KeySeparator)
+
+		switch {
+		case keyParts[0] == "ChainHistoryInfo":
+			err = saveChainHistory(value)
+		case sampleSkippedKeyPrefixes[keyParts[0]]:
+			// Do not save
+		case len(ndidNodeID) > 0 && bytes.Contains(key, ndidNodeID):
+			// NDID node detail
+			// Do not save
+		case strings.HasPrefix(string(key), "n") && len(value) == 0:
+			// nonce
+			// Do not save
+		case keyParts[0] == "RefGroupCode":
+			if !sampledRefGroups[keyParts[1]] {
+				break
+			}
+			newValue, err := pseudonymizeRefGroup(p, value, report)
+			if err != nil {
+				return false, fmt.Errorf("%s: %w", key, err)
+			}
+			err = write([]byte("RefGroupCode"+KeySeparator+p.uuid("ref_group_code", keyParts[1])), newValue)
+			if err != nil {
+				return false, err
+			}
+			report.RefGroupCount++
+		case keyParts[0] == "identityToRefCodeKey" && len(keyParts) == 3:
+			if !sampledRefGroups[string(value)] {
+				break
+			}
+			err = write(
+				[]byte("identityToRefCodeKey"+KeySeparator+keyParts[1]+KeySeparator+p.like("identifier_hash", keyParts[2])),
+				[]byte(p.uuid("ref_group_code", string(value))),
+			)
+		case keyParts[0] == "accessorToRefCodeKey" && len(keyParts) == 2:
+			if !sampledRefGroups[string(value)] {
+				break
+			}
+			err = write(
+				[]byte("accessorToRefCodeKey"+KeySeparator+p.uuid("accessor_id", keyParts[1])),
+				[]byte(p.uuid("ref_group_code", string(value))),
+			)
+		case keyParts[0] == "Request" && len(keyParts) == 3:
+			latestVersion, ok := sampledRequestVersions[keyParts[1]]
+			if !ok {
+				break
+			}
+			if keyParts[2] == "versions" {
+				// Set to 1 version
+				newValue, err := proto.MarshalOptions{Deterministic: true}.Marshal(&didProtoV9.KeyVersions{
+					Versions: []int64{1},
+				})
+				if err != nil {
+					return false, err
+				}
+				err = write(key, newValue)
+				if err != nil {
+					return false, err
+				}
+				break
+			}
+			if keyParts[2] != strconv.FormatInt(latestVersion, 10) {
+				break
+			}
+			newValue, err := pseudonymizeRequest(p, value)
+			if err != nil {
+				return false, fmt.Errorf("%s: %w", key, err)
+			}
+			err = write([]byte("Request"+KeySeparator+keyParts[1]+KeySeparator+"1"), newValue)
+			if err != nil {
+				return false, err
+			}
+			report.RequestCount++
+		default:
+			err = write(key, value)
+		}
+		return false, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func pseudonymizeRefGroup(p *pseudonymizer, value []byte, report *SampleReport) (newValue []byte, err error) {
+	var refGroup didProtoV9.ReferenceGroup
+	err = proto.Unmarshal(value, &refGroup)
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range refGroup.Identities {
+		identity.IdentifierHash = p.like("identifier_hash", identity.IdentifierHash)
+		report.IdentityCount++
+	}
+	for _, idp := range refGroup.Idps {
+		for _, accessor := range idp.Accessors {
+			accessor.AccessorId = p.uuid("accessor_id", accessor.AccessorId)
+			accessor.AccessorPublicKey = p.like("accessor_public_key", accessor.AccessorPublicKey)
+			report.AccessorCount++
+		}
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(&refGroup)
+}
+
+func pseudonymizeRequest(p *pseudonymizer, value []byte) (newValue []byte, err error) {
+	var request didProtoV9.Request
+	err = proto.Unmarshal(value, &request)
+	if err != nil {
+		return nil, err
+	}
+	request.RequestMessageHash = p.like("request_message_hash", request.RequestMessageHash)
+	for _, response := range request.ResponseList {
+		response.Signature = p.like("signature", response.Signature)
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(&request)
+}
@@ -0,0 +1,180 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package statedata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+
+	dbm "github.com/tendermint/tm-db"
+)
+
+var ErrNotFound = errors.New("key not found")
+
+// Source is ABCI state data which is either a state DB or an initial state
+// data file. Keys given to and returned from Source are without
+// "kvPairKey:" prefix.
+type Source interface {
+	Get(key []byte) (value []byte, err error)
+	// Iterate calls fn with every key-value which key starts with prefix
+	// until fn returns stop or error.
+	Iterate(prefix []byte, fn func(key []byte, value []byte) (stop bool, err error)) error
+	Close() error
+}
+
+type dbSource struct {
+	db dbm.DB
+}
+
+// NewDBSource returns Source of state DB. Keys with "kvPairKey:" prefix are
+// iterated before keys without it. Closing Source closes the DB.
+func NewDBSource(db dbm.DB) Source {
+	return &dbSource{db: db}
+}
+
+func (s *dbSource) Get(key []byte) (value []byte, err error) {
+	value, err = s.db.Get(append(append(make([]byte, 0), KvPairPrefixKey...), key...))
+	if err != nil {
+		return nil, err
+	}
+	if value != nil {
+		return value, nil
+	}
+	value, err = s.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *dbSource) Iterate(prefix []byte, fn func(key []byte, value []byte) (stop bool, err error)) error {
+	stop, err := s.iterate(append(append(make([]byte, 0), KvPairPrefixKey...), prefix...), fn)
+	if err != nil || stop {
+		return err
+	}
+	_, err = s.iterate(prefix, fn)
+	return err
+}
+
+func (s *dbSource) iterate(prefix []byte, fn func(key []byte, value []byte) (stop bool, err error)) (stop bool, err error) {
+	itr, err := dbm.IteratePrefix(s.db, prefix)
+	if err != nil {
+		return false, err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		key := itr.Key()
+		if len(prefix) < len(KvPairPrefixKey) && bytes.HasPrefix(key, KvPairPrefixKey) {
+			// Iterated in pass of keys with "kvPairKey:" prefix
+			continue
+		}
+		stop, err = fn(StripKvPairPrefix(key), itr.Value())
+		if err != nil || stop {
+			return stop, err
+		}
+	}
+	return false, itr.Error()
+}
+
+func (s *dbSource) Close() error {
+	return s.db.Close()
+}
+
+type keyValue struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type fileSource struct {
+	file *os.File
+}
+
+// OpenFileSource opens initial state data file (JSON of key-value per line)
+// as Source. Every Get and Iterate reads the file from the beginning.
+func OpenFileSource(filePath string) (Source, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSource{file: file}, nil
+}
+
+func (s *fileSource) Get(key []byte) (value []byte, err error) {
+	found := false
+	err = s.Iterate(key, func(k []byte, v []byte) (stop bool, err error) {
+		if bytes.Equal(k, key) {
+			found = true
+			value = v
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *fileSource) Iterate(prefix []byte, fn func(key []byte, value []byte) (stop bool, err error)) error {
+	_, err := s.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(s.file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			var kv keyValue
+			if err := json.Unmarshal(line, &kv); err != nil {
+				return err
+			}
+			key := StripKvPairPrefix(kv.Key)
+			if bytes.HasPrefix(key, prefix) {
+				stop, err := fn(key, kv.Value)
+				if err != nil || stop {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *fileSource) Close() error {
+	return s.file.Close()
+}
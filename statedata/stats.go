@@ -0,0 +1,201 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package statedata
+
+import (
+	"container/heap"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type Distribution struct {
+	Count int64 `json:"count"`
+	Total int64 `json:"total"`
+	P50   int64 `json:"p50"`
+	P90   int64 `json:"p90"`
+	P99   int64 `json:"p99"`
+	Max   int64 `json:"max"`
+}
+
+func newDistribution(values []int64) Distribution {
+	var distribution Distribution
+	if len(values) == 0 {
+		return distribution
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	distribution.Count = int64(len(values))
+	for _, value := range values {
+		distribution.Total += value
+	}
+	percentile := func(p int) int64 {
+		return values[(len(values)-1)*p/100]
+	}
+	distribution.P50 = percentile(50)
+	distribution.P90 = percentile(90)
+	distribution.P99 = percentile(99)
+	distribution.Max = values[len(values)-1]
+	return distribution
+}
+
+type PrefixStats struct {
+	Prefix    string       `json:"prefix"`
+	ValueSize Distribution `json:"value_size"`
+}
+
+type KeySize struct {
+	Key       string `json:"key"`
+	ValueSize int64  `json:"value_size"`
+}
+
+type ReferenceGroupStats struct {
+	Count      int64        `json:"count"`
+	Identities Distribution `json:"identities"`
+	IdPs       Distribution `json:"idps"`
+	Accessors  Distribution `json:"accessors"`
+}
+
+type Stats struct {
+	ABCIVersion    int   `json:"abci_version"`
+	KeyCount       int64 `json:"key_count"`
+	TotalValueSize int64 `json:"total_value_size"`
+	// Sorted by prefix
+	Prefixes []PrefixStats `json:"prefixes"`
+	// Sorted by value size, largest first
+	LargestKeys []KeySize `json:"largest_keys"`
+	// Number of requests by number of versions of request
+	RequestVersions map[int64]int64     `json:"request_versions"`
+	ReferenceGroups ReferenceGroupStats `json:"reference_groups"`
+	NodesByRole     map[string]int64    `json:"nodes_by_role"`
+	// Values of known message which cannot be unmarshaled
+	DecodeErrorCount int64 `json:"decode_error_count"`
+}
+
+// CollectStats reads all state data of source and collects statistics of
+// value sizes by key prefix and of requests, reference groups and nodes.
+// topN is number of largest keys to keep.
+func CollectStats(source Source, abciVersion int, topN int) (stats *Stats, err error) {
+	stats = &Stats{
+		ABCIVersion:     abciVersion,
+		Prefixes:        make([]PrefixStats, 0),
+		RequestVersions: make(map[int64]int64),
+		NodesByRole:     make(map[string]int64),
+	}
+
+	valueSizesByPrefix := make(map[string][]int64)
+	largestKeys := make(keySizeHeap, 0, topN+1)
+	var refGroupIdentities, refGroupIdPs, refGroupAccessors []int64
+
+	err = source.Iterate(nil, func(key []byte, value []byte) (stop bool, err error) {
+		valueSize := int64(len(value))
+		stats.KeyCount++
+		stats.TotalValueSize += valueSize
+		prefix := KeyPrefix(key)
+		valueSizesByPrefix[prefix] = append(valueSizesByPrefix[prefix], valueSize)
+
+		if topN > 0 {
+			heap.Push(&largestKeys, KeySize{Key: string(key), ValueSize: valueSize})
+			if len(largestKeys) > topN {
+				heap.Pop(&largestKeys)
+			}
+		}
+
+		message, err := UnmarshalValue(abciVersion, key, value)
+		if err != nil {
+			stats.DecodeErrorCount++
+			return false, nil
+		}
+		if message == nil {
+			return false, nil
+		}
+		fields := message.ProtoReflect().Descriptor().Fields()
+		switch message.ProtoReflect().Descriptor().Name() {
+		case "KeyVersions":
+			if prefix == "Request" {
+				stats.RequestVersions[int64(listLen(message, "versions"))]++
+			}
+		case "ReferenceGroup":
+			stats.ReferenceGroups.Count++
+			refGroupIdentities = append(refGroupIdentities, int64(listLen(message, "identities")))
+			refGroupIdPs = append(refGroupIdPs, int64(listLen(message, "idps")))
+			var accessorCount int64
+			if idpsField := fields.ByName("idps"); idpsField != nil && idpsField.IsList() {
+				idps := message.ProtoReflect().Get(idpsField).List()
+				for i := 0; i < idps.Len(); i++ {
+					accessorCount += int64(listLen(idps.Get(i).Message().Interface(), "accessors"))
+				}
+			}
+			refGroupAccessors = append(refGroupAccessors, accessorCount)
+		case "NodeDetail":
+			if roleField := fields.ByName("role"); roleField != nil {
+				stats.NodesByRole[message.ProtoReflect().Get(roleField).String()]++
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for prefix, valueSizes := range valueSizesByPrefix {
+		stats.Prefixes = append(stats.Prefixes, PrefixStats{
+			Prefix:    prefix,
+			ValueSize: newDistribution(valueSizes),
+		})
+	}
+	sort.Slice(stats.Prefixes, func(i, j int) bool { return stats.Prefixes[i].Prefix < stats.Prefixes[j].Prefix })
+
+	stats.LargestKeys = make([]KeySize, len(largestKeys))
+	for i := len(largestKeys) - 1; i >= 0; i-- {
+		stats.LargestKeys[i] = heap.Pop(&largestKeys).(KeySize)
+	}
+
+	stats.ReferenceGroups.Identities = newDistribution(refGroupIdentities)
+	stats.ReferenceGroups.IdPs = newDistribution(refGroupIdPs)
+	stats.ReferenceGroups.Accessors = newDistribution(refGroupAccessors)
+
+	return stats, nil
+}
+
+func listLen(message proto.Message, fieldName protoreflect.Name) int {
+	field := message.ProtoReflect().Descriptor().Fields().ByName(fieldName)
+	if field == nil || !field.IsList() {
+		return 0
+	}
+	return message.ProtoReflect().Get(field).List().Len()
+}
+
+// keySizeHeap is min-heap of KeySize by value size
+type keySizeHeap []KeySize
+
+func (h keySizeHeap) Len() int            { return len(h) }
+func (h keySizeHeap) Less(i, j int) bool  { return h[i].ValueSize < h[j].ValueSize }
+func (h keySizeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *keySizeHeap) Push(x interface{}) { *h = append(*h, x.(KeySize)) }
+func (h *keySizeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,55 @@
+package tendermint_0_26_4
+
+import (
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/ndidplatform/migration-tools/tendermint"
+	"github.com/ndidplatform/migration-tools/tendermint/0_26_4/block"
+)
+
+// BlockCodec loads block store data of Tendermint 0.26.4
+type BlockCodec struct{}
+
+func (BlockCodec) TendermintVersion() string {
+	return TendermintVersion
+}
+
+func (BlockCodec) BlockEncoding() string {
+	return tendermint.BlockEncodingAmino
+}
+
+func (BlockCodec) LoadBlockStoreRange(blockDB dbm.DB) (base int64, height int64, err error) {
+	return tendermint.LoadBlockStoreRangeJSON(blockDB)
+}
+
+func (BlockCodec) LoadBlockMeta(blockDB dbm.DB, height int64) (blockMeta *tendermint.BlockMeta, err error) {
+	meta, err := block.LoadBlockMeta(blockDB, height)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+	return &tendermint.BlockMeta{
+		ChainID:         meta.Header.ChainID,
+		Height:          meta.Header.Height,
+		Time:            meta.Header.Time,
+		BlockHash:       tendermint.HexBytes(meta.BlockID.Hash),
+		PartSetTotal:    meta.BlockID.PartsHeader.Total,
+		LastBlockHash:   tendermint.HexBytes(meta.Header.LastBlockID.Hash),
+		DataHash:        tendermint.HexBytes(meta.Header.DataHash),
+		AppHash:         tendermint.HexBytes(meta.Header.AppHash),
+		LastResultsHash: tendermint.HexBytes(meta.Header.LastResultsHash),
+		ProposerAddress: tendermint.HexBytes(meta.Header.ProposerAddress),
+		NumTxs:          meta.Header.NumTxs,
+	}, nil
+}
+
+func (BlockCodec) DecodeTxResults(abciResponses []byte, txCount int) (txResults []tendermint.TxResult, err error) {
+	return tendermint.DecodeTxResults(abciResponses)
+}
+
+// OpenBlockReader opens Tendermint DBs in tmHome for reading blocks
+func OpenBlockReader(tmHome string) (blockReader *tendermint.BlockReader, err error) {
+	return tendermint.OpenBlockReader(tmHome, BlockCodec{})
+}
@@ -27,6 +27,11 @@ func loadState(db dbm.DB, key []byte) (state *State, err error) {
 		return state, nil
 	}
 
+	return DecodeState(buf)
+}
+
+// DecodeState decodes amino encoded State
+func DecodeState(buf []byte) (state *State, err error) {
 	err = cdc.UnmarshalBinaryBare(buf, &state)
 	if err != nil {
 		// DATA HAS BEEN CORRUPTED OR THE SPEC HAS CHANGED
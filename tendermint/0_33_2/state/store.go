@@ -21,7 +21,7 @@ func loadState(db dbm.DB, key []byte) (state State) {
 		return state
 	}
 
-	err = cdc.UnmarshalBinaryBare(buf, &state)
+	state, err = DecodeState(buf)
 	if err != nil {
 		// DATA HAS BEEN CORRUPTED OR THE SPEC HAS CHANGED
 		tmos.Exit(fmt.Sprintf(`LoadState: Data has been corrupted or its spec has changed:
@@ -31,3 +31,9 @@ func loadState(db dbm.DB, key []byte) (state State) {
 
 	return state
 }
+
+// DecodeState decodes amino encoded State
+func DecodeState(buf []byte) (state State, err error) {
+	err = cdc.UnmarshalBinaryBare(buf, &state)
+	return state, err
+}
@@ -2,6 +2,7 @@ package tendermint_0_33_2
 
 import (
 	"encoding/hex"
+	"fmt"
 	"log"
 	"path"
 	"strings"
@@ -9,10 +10,20 @@ import (
 	"github.com/BurntSushi/toml"
 	dbm "github.com/tendermint/tm-db"
 
+	"github.com/ndidplatform/migration-tools/tendermint"
+	"github.com/ndidplatform/migration-tools/tendermint/0_33_2/crypto"
+	"github.com/ndidplatform/migration-tools/tendermint/0_33_2/crypto/ed25519"
+	"github.com/ndidplatform/migration-tools/tendermint/0_33_2/crypto/secp256k1"
+	"github.com/ndidplatform/migration-tools/tendermint/0_33_2/crypto/sr25519"
 	"github.com/ndidplatform/migration-tools/tendermint/0_33_2/state"
 	"github.com/ndidplatform/migration-tools/tendermint/0_33_2/store"
+	"github.com/ndidplatform/migration-tools/tendermint/0_33_2/types"
+	"github.com/ndidplatform/migration-tools/tendermint/0_33_2/version"
+	"github.com/ndidplatform/migration-tools/utils"
 )
 
+const TendermintVersion = "0.33.2"
+
 type tomlConfig struct {
 	DBBackend string `toml:"db_backend"`
 	DBPath    string `toml:"db_dir"`
@@ -23,9 +34,18 @@ type TendermintStateInfo struct {
 	LatestBlockHeight int64
 	LatestBlockHash   []byte
 	LatestAppHash     []byte
+	// CommittedAppHash is app hash returned by ABCI Commit of the block
+	// (app hash in header of the next block)
+	CommittedAppHash []byte
 }
 
 func GetTendermintInfo(tmHome string) (tendermintStateInfo *TendermintStateInfo, err error) {
+	return GetTendermintInfoAtHeight(tmHome, 0)
+}
+
+// GetStateInfo loads chain info of block at given height from state DB and
+// block store. Latest block height is used when height is 0.
+func GetStateInfo(tmHome string, height int64) (stateInfo *tendermint.StateInfo, err error) {
 	configFile := path.Join(tmHome, "config/config.toml")
 	var config tomlConfig
 	if _, err = toml.DecodeFile(configFile, &config); err != nil {
@@ -33,33 +53,187 @@ func GetTendermintInfo(tmHome string) (tendermintStateInfo *TendermintStateInfo,
 	}
 	dbDir := path.Join(tmHome, config.DBPath)
 	dbType := dbm.BackendType(config.DBBackend)
-	stateDB, err := dbm.NewDB("state", dbType, dbDir)
+	stateDB, err := utils.OpenDBReadOnly("state", dbType, dbDir)
 	if err != nil {
 		return nil, err
 	}
+	defer stateDB.Close()
 	state := state.LoadState(stateDB)
 
 	// fmt.Printf("state: %+v\n", state)
 
-	blockDB, err := dbm.NewDB("blockstore", dbType, dbDir)
+	if height == 0 {
+		height = state.LastBlockHeight
+	}
+	if height < 1 || height > state.LastBlockHeight {
+		return nil, fmt.Errorf("block height %d out of range, latest block height: %d", height, state.LastBlockHeight)
+	}
+
+	blockDB, err := utils.OpenDBReadOnly("blockstore", dbType, dbDir)
 	if err != nil {
 		return nil, err
 	}
-	blockMeta := store.LoadBlockMeta(blockDB, state.LastBlockHeight)
+	defer blockDB.Close()
+	blockMeta := store.LoadBlockMeta(blockDB, height)
+	if blockMeta == nil {
+		return nil, fmt.Errorf("block meta at height %d not found", height)
+	}
+	committedAppHash := state.AppHash
+	lastResultsHash := state.LastResultsHash
+	if height < state.LastBlockHeight {
+		nextBlockMeta := store.LoadBlockMeta(blockDB, height+1)
+		if nextBlockMeta == nil {
+			return nil, fmt.Errorf("block meta at height %d not found", height+1)
+		}
+		committedAppHash = nextBlockMeta.Header.AppHash
+		lastResultsHash = nextBlockMeta.Header.LastResultsHash
+	}
 
 	// fmt.Printf("blockMeta: %+v\n", blockMeta)
 
+	stateInfo = &tendermint.StateInfo{
+		TendermintVersion: TendermintVersion,
+		ChainID:           blockMeta.Header.ChainID,
+		InitialHeight:     1,
+		LastBlockHeight:   height,
+		LastBlockHash:     tendermint.HexBytes(blockMeta.BlockID.Hash),
+		LastBlockTime:     blockMeta.Header.Time,
+		AppHash:           tendermint.HexBytes(blockMeta.Header.AppHash),
+		CommittedAppHash:  committedAppHash,
+		LastResultsHash:   lastResultsHash,
+		Validators:        convertValidatorSet(state.Validators),
+		DBBackend:         config.DBBackend,
+		DBDir:             dbDir,
+	}
+
+	return stateInfo, nil
+}
+
+// GetTendermintInfoAtHeight loads chain info of block at given height.
+// Latest block height is used when height is 0.
+func GetTendermintInfoAtHeight(tmHome string, height int64) (tendermintStateInfo *TendermintStateInfo, err error) {
+	stateInfo, err := GetStateInfo(tmHome, height)
+	if err != nil {
+		return nil, err
+	}
+
 	tendermintStateInfo = new(TendermintStateInfo)
-	tendermintStateInfo.ChainID = blockMeta.Header.ChainID
-	tendermintStateInfo.LatestBlockHeight = state.LastBlockHeight
-	tendermintStateInfo.LatestBlockHash = blockMeta.BlockID.Hash
-	tendermintStateInfo.LatestAppHash = blockMeta.Header.AppHash
+	tendermintStateInfo.ChainID = stateInfo.ChainID
+	tendermintStateInfo.LatestBlockHeight = stateInfo.LastBlockHeight
+	tendermintStateInfo.LatestBlockHash = stateInfo.LastBlockHash
+	tendermintStateInfo.LatestAppHash = stateInfo.AppHash
+	tendermintStateInfo.CommittedAppHash = stateInfo.CommittedAppHash
 
 	log.Println("===== Tendermint State Info =====")
 	log.Printf("Chain ID: %s\n", tendermintStateInfo.ChainID)
 	log.Printf("Latest Block Height: %d\n", tendermintStateInfo.LatestBlockHeight)
 	log.Printf("Latest Block Hash: %s\n", strings.ToUpper(hex.EncodeToString(tendermintStateInfo.LatestBlockHash)))
 	log.Printf("Latest App Hash: %s\n", strings.ToUpper(hex.EncodeToString(tendermintStateInfo.LatestAppHash)))
+	log.Printf("Committed App Hash: %s\n", strings.ToUpper(hex.EncodeToString(tendermintStateInfo.CommittedAppHash)))
 
 	return tendermintStateInfo, nil
 }
+
+// StateInfoReader implements tendermint.StateInfoReader
+type StateInfoReader struct{}
+
+func (StateInfoReader) TendermintVersion() string {
+	return TendermintVersion
+}
+
+func (StateInfoReader) GetStateInfo(tmHome string, height int64) (stateInfo *tendermint.StateInfo, err error) {
+	return GetStateInfo(tmHome, height)
+}
+
+// GetValidatorsBackup loads current validator set and consensus params from
+// Tendermint state DB
+func GetValidatorsBackup(tmHome string) (validatorsBackup *tendermint.ValidatorsBackup, err error) {
+	configFile := path.Join(tmHome, "config/config.toml")
+	var config tomlConfig
+	if _, err = toml.DecodeFile(configFile, &config); err != nil {
+		return nil, err
+	}
+	dbDir := path.Join(tmHome, config.DBPath)
+	dbType := dbm.BackendType(config.DBBackend)
+	stateDB, err := utils.OpenDBReadOnly("state", dbType, dbDir)
+	if err != nil {
+		return nil, err
+	}
+	defer stateDB.Close()
+	state := state.LoadState(stateDB)
+
+	validatorsBackup = &tendermint.ValidatorsBackup{
+		TendermintVersion: TendermintVersion,
+		ChainID:           state.ChainID,
+		LastBlockHeight:   state.LastBlockHeight,
+		Validators:        convertValidatorSet(state.Validators),
+		NextValidators:    convertValidatorSet(state.NextValidators),
+		ConsensusParams: tendermint.ConsensusParams{
+			Block: tendermint.BlockParams{
+				MaxBytes:   state.ConsensusParams.Block.MaxBytes,
+				MaxGas:     state.ConsensusParams.Block.MaxGas,
+				TimeIotaMs: state.ConsensusParams.Block.TimeIotaMs,
+			},
+			Evidence: tendermint.EvidenceParams{
+				MaxAgeNumBlocks: state.ConsensusParams.Evidence.MaxAgeNumBlocks,
+				MaxAgeDuration:  state.ConsensusParams.Evidence.MaxAgeDuration,
+			},
+			Validator: tendermint.ValidatorParams{
+				PubKeyTypes: state.ConsensusParams.Validator.PubKeyTypes,
+			},
+			Version: tendermint.VersionParams{
+				AppVersion: uint64(state.Version.Consensus.App),
+			},
+		},
+	}
+
+	return validatorsBackup, nil
+}
+
+func convertValidatorSet(validatorSet *types.ValidatorSet) (validators []tendermint.Validator) {
+	validators = make([]tendermint.Validator, 0)
+	if validatorSet == nil {
+		return validators
+	}
+	for _, validator := range validatorSet.Validators {
+		validators = append(validators, tendermint.Validator{
+			Address:          strings.ToUpper(hex.EncodeToString(validator.Address)),
+			PubKey:           convertPubKey(validator.PubKey),
+			VotingPower:      validator.VotingPower,
+			ProposerPriority: validator.ProposerPriority,
+		})
+	}
+	return validators
+}
+
+func convertPubKey(pubKey crypto.PubKey) (convertedPubKey tendermint.PubKey) {
+	switch key := pubKey.(type) {
+	case ed25519.PubKeyEd25519:
+		convertedPubKey.Type = tendermint.PubKeyTypeEd25519
+		convertedPubKey.Value = key[:]
+	case secp256k1.PubKeySecp256k1:
+		convertedPubKey.Type = tendermint.PubKeyTypeSecp256k1
+		convertedPubKey.Value = key[:]
+	case sr25519.PubKeySr25519:
+		convertedPubKey.Type = tendermint.PubKeyTypeSr25519
+		convertedPubKey.Value = key[:]
+	}
+	return convertedPubKey
+}
+
+// DecodeStateVersion decodes version info from raw Tendermint state with
+// state decoder of this Tendermint version
+func DecodeStateVersion(stateBytes []byte) (stateVersion *tendermint.StateVersion, err error) {
+	state, err := state.DecodeState(stateBytes)
+	if err != nil {
+		return nil, err
+	}
+	stateVersion = &tendermint.StateVersion{
+		Software:             state.Version.Software,
+		BlockProtocol:        uint64(state.Version.Consensus.Block),
+		BlockProtocolMatched: state.Version.Consensus.Block == version.BlockProtocol,
+		ChainID:              state.ChainID,
+		LastBlockHeight:      state.LastBlockHeight,
+	}
+	return stateVersion, nil
+}
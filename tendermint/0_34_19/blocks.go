@@ -0,0 +1,82 @@
+package tendermint_0_34_19
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/ndidplatform/migration-tools/tendermint"
+	tmstore "github.com/ndidplatform/migration-tools/tendermint/0_34_19/proto/tendermint/store"
+	tmproto "github.com/ndidplatform/migration-tools/tendermint/0_34_19/proto/tendermint/types"
+)
+
+// BlockCodec loads block store data of Tendermint 0.34.19
+type BlockCodec struct{}
+
+func (BlockCodec) TendermintVersion() string {
+	return TendermintVersion
+}
+
+func (BlockCodec) BlockEncoding() string {
+	return tendermint.BlockEncodingProtobuf
+}
+
+func (BlockCodec) LoadBlockStoreRange(blockDB dbm.DB) (base int64, height int64, err error) {
+	bz, err := blockDB.Get([]byte("blockStore"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(bz) == 0 {
+		return 0, 0, nil
+	}
+	var blockStoreState tmstore.BlockStoreState
+	err = proto.Unmarshal(bz, &blockStoreState)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Backwards compatibility with persisted data from before Base existed.
+	if blockStoreState.Height > 0 && blockStoreState.Base == 0 {
+		blockStoreState.Base = 1
+	}
+	return blockStoreState.Base, blockStoreState.Height, nil
+}
+
+// LoadBlockMeta decodes block meta protobuf directly since vendored
+// types.BlockID does not keep part set header
+func (BlockCodec) LoadBlockMeta(blockDB dbm.DB, height int64) (blockMeta *tendermint.BlockMeta, err error) {
+	bz, err := blockDB.Get([]byte(fmt.Sprintf("H:%v", height)))
+	if err != nil {
+		return nil, err
+	}
+	if len(bz) == 0 {
+		return nil, nil
+	}
+	var meta tmproto.BlockMeta
+	err = proto.Unmarshal(bz, &meta)
+	if err != nil {
+		return nil, fmt.Errorf("block meta at height %d: %w", height, err)
+	}
+	return &tendermint.BlockMeta{
+		ChainID:         meta.Header.ChainID,
+		Height:          meta.Header.Height,
+		Time:            meta.Header.Time,
+		BlockHash:       tendermint.HexBytes(meta.BlockID.Hash),
+		PartSetTotal:    int(meta.BlockID.PartSetHeader.Total),
+		LastBlockHash:   tendermint.HexBytes(meta.Header.LastBlockId.Hash),
+		DataHash:        tendermint.HexBytes(meta.Header.DataHash),
+		AppHash:         tendermint.HexBytes(meta.Header.AppHash),
+		LastResultsHash: tendermint.HexBytes(meta.Header.LastResultsHash),
+		ProposerAddress: tendermint.HexBytes(meta.Header.ProposerAddress),
+		NumTxs:          meta.NumTxs,
+	}, nil
+}
+
+func (BlockCodec) DecodeTxResults(abciResponses []byte, txCount int) (txResults []tendermint.TxResult, err error) {
+	return tendermint.DecodeTxResults(abciResponses)
+}
+
+// OpenBlockReader opens Tendermint DBs in tmHome for reading blocks
+func OpenBlockReader(tmHome string) (blockReader *tendermint.BlockReader, err error) {
+	return tendermint.OpenBlockReader(tmHome, BlockCodec{})
+}
@@ -0,0 +1,145 @@
+package tendermint_0_34_19
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/ndidplatform/migration-tools/tendermint"
+	"github.com/ndidplatform/migration-tools/tendermint/0_34_19/crypto"
+	"github.com/ndidplatform/migration-tools/tendermint/0_34_19/crypto/ed25519"
+	"github.com/ndidplatform/migration-tools/tendermint/0_34_19/crypto/secp256k1"
+	tmproto "github.com/ndidplatform/migration-tools/tendermint/0_34_19/proto/tendermint/types"
+	"github.com/ndidplatform/migration-tools/tendermint/0_34_19/types"
+)
+
+// GenerateGenesis creates genesis doc of a new Tendermint 0.34 chain from
+// validators backup of old chain. Consensus params not available in Tendermint
+// version of old chain are filled with defaults. Genesis doc is validated
+// with ValidateAndComplete before being returned. App hash is left empty,
+// Tendermint takes app hash returned by ABCI InitChain.
+func GenerateGenesis(
+	validatorsBackup *tendermint.ValidatorsBackup,
+	chainID string,
+	genesisTime time.Time,
+) (genesisDoc *types.GenesisDoc, err error) {
+	// Next validators is the latest validator set known to old chain
+	// (includes updates not yet effective at last block height)
+	validators := validatorsBackup.NextValidators
+	if len(validators) == 0 {
+		validators = validatorsBackup.Validators
+	}
+	if len(validators) == 0 {
+		return nil, fmt.Errorf("no validator in validators backup")
+	}
+
+	genesisValidators := make([]types.GenesisValidator, 0, len(validators))
+	for _, validator := range validators {
+		pubKey, err := convertToPubKey(validator.PubKey)
+		if err != nil {
+			return nil, err
+		}
+		address, err := hex.DecodeString(validator.Address)
+		if err != nil {
+			return nil, err
+		}
+		genesisValidators = append(genesisValidators, types.GenesisValidator{
+			Address: address,
+			PubKey:  pubKey,
+			Power:   validator.VotingPower,
+		})
+	}
+
+	genesisDoc = &types.GenesisDoc{
+		GenesisTime:     genesisTime,
+		ChainID:         chainID,
+		InitialHeight:   1,
+		ConsensusParams: convertToConsensusParams(validatorsBackup.ConsensusParams),
+		Validators:      genesisValidators,
+	}
+
+	err = genesisDoc.ValidateAndComplete()
+	if err != nil {
+		return nil, err
+	}
+
+	return genesisDoc, nil
+}
+
+func convertToPubKey(pubKey tendermint.PubKey) (crypto.PubKey, error) {
+	switch pubKey.Type {
+	case tendermint.PubKeyTypeEd25519:
+		if len(pubKey.Value) != ed25519.PubKeySize {
+			return nil, fmt.Errorf("invalid ed25519 public key size: %d", len(pubKey.Value))
+		}
+		return ed25519.PubKey(pubKey.Value), nil
+	case tendermint.PubKeyTypeSecp256k1:
+		if len(pubKey.Value) != secp256k1.PubKeySize {
+			return nil, fmt.Errorf("invalid secp256k1 public key size: %d", len(pubKey.Value))
+		}
+		return secp256k1.PubKey(pubKey.Value), nil
+	default:
+		return nil, fmt.Errorf("public key type %q is not supported by Tendermint 0.34", pubKey.Type)
+	}
+}
+
+func convertToConsensusParams(consensusParams tendermint.ConsensusParams) *tmproto.ConsensusParams {
+	defaultParams := types.DefaultConsensusParams()
+	params := &tmproto.ConsensusParams{
+		Block: tmproto.BlockParams{
+			MaxBytes:   consensusParams.Block.MaxBytes,
+			MaxGas:     consensusParams.Block.MaxGas,
+			TimeIotaMs: consensusParams.Block.TimeIotaMs,
+		},
+		Evidence: tmproto.EvidenceParams{
+			MaxAgeNumBlocks: consensusParams.Evidence.MaxAgeNumBlocks,
+			MaxAgeDuration:  consensusParams.Evidence.MaxAgeDuration,
+			MaxBytes:        consensusParams.Evidence.MaxBytes,
+		},
+		Validator: tmproto.ValidatorParams{
+			PubKeyTypes: consensusParams.Validator.PubKeyTypes,
+		},
+		Version: tmproto.VersionParams{
+			AppVersion: consensusParams.Version.AppVersion,
+		},
+	}
+
+	if params.Block.MaxBytes <= 0 {
+		log.Println("consensus params: block.max_bytes not set, using default")
+		params.Block.MaxBytes = defaultParams.Block.MaxBytes
+	}
+	if params.Block.TimeIotaMs <= 0 {
+		log.Println("consensus params: block.time_iota_ms not set, using default")
+		params.Block.TimeIotaMs = defaultParams.Block.TimeIotaMs
+	}
+	if params.Evidence.MaxAgeNumBlocks <= 0 {
+		log.Println("consensus params: evidence.max_age_num_blocks not set, using default")
+		params.Evidence.MaxAgeNumBlocks = defaultParams.Evidence.MaxAgeNumBlocks
+	}
+	if params.Evidence.MaxAgeDuration <= 0 {
+		log.Println("consensus params: evidence.max_age_duration not set, using default")
+		params.Evidence.MaxAgeDuration = defaultParams.Evidence.MaxAgeDuration
+	}
+	if len(params.Validator.PubKeyTypes) == 0 {
+		log.Println("consensus params: validator.pub_key_types not set, using default")
+		params.Validator.PubKeyTypes = defaultParams.Validator.PubKeyTypes
+	}
+
+	return params
+}
+
+// DeriveChainID returns new chain ID derived from old chain ID by
+// incrementing its numeric suffix or appending "-2" if there is none
+func DeriveChainID(oldChainID string) string {
+	numberIndex := len(oldChainID)
+	for numberIndex > 0 && oldChainID[numberIndex-1] >= '0' && oldChainID[numberIndex-1] <= '9' {
+		numberIndex--
+	}
+	number, err := strconv.ParseInt(oldChainID[numberIndex:], 10, 64)
+	if numberIndex == len(oldChainID) || err != nil {
+		return oldChainID + "-2"
+	}
+	return oldChainID[:numberIndex] + strconv.FormatInt(number+1, 10)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	tmstate "github.com/ndidplatform/migration-tools/tendermint/0_34_19/proto/tendermint/state"
+	tmproto "github.com/ndidplatform/migration-tools/tendermint/0_34_19/proto/tendermint/types"
 	tmversion "github.com/ndidplatform/migration-tools/tendermint/0_34_19/proto/tendermint/version"
 	"github.com/ndidplatform/migration-tools/tendermint/0_34_19/types"
 	"github.com/ndidplatform/migration-tools/tendermint/0_34_19/version"
@@ -60,10 +61,13 @@ type State struct {
 	// Validators                  *types.ValidatorSet
 	// LastValidators              *types.ValidatorSet
 	// LastHeightValidatorsChanged int64
+	// Validator sets are kept in proto form (not converted to types.ValidatorSet)
+	NextValidators *tmproto.ValidatorSet
+	Validators     *tmproto.ValidatorSet
 
 	// Consensus parameters used for validating blocks.
 	// Changes returned by EndBlock and updated after Commit.
-	// ConsensusParams                  tmproto.ConsensusParams
+	ConsensusParams tmproto.ConsensusParams
 	// LastHeightConsensusParamsChanged int64
 
 	// Merkle root of the results from executing prev block
@@ -115,8 +119,11 @@ func FromProto(pb *tmstate.State) (*State, error) { //nolint:golint
 	// 	state.LastValidators = types.NewValidatorSet(nil)
 	// }
 
+	state.NextValidators = pb.NextValidators
+	state.Validators = pb.Validators
+
 	// state.LastHeightValidatorsChanged = pb.LastHeightValidatorsChanged
-	// state.ConsensusParams = pb.ConsensusParams
+	state.ConsensusParams = pb.ConsensusParams
 	// state.LastHeightConsensusParamsChanged = pb.LastHeightConsensusParamsChanged
 	state.LastResultsHash = pb.LastResultsHash
 	state.AppHash = pb.AppHash
@@ -0,0 +1,133 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ndidplatform/migration-tools/tendermint/0_34_19/crypto/ed25519"
+	"github.com/ndidplatform/migration-tools/tendermint/0_34_19/crypto/secp256k1"
+	tmproto "github.com/ndidplatform/migration-tools/tendermint/0_34_19/proto/tendermint/types"
+)
+
+const (
+	// MaxBlockSizeBytes is the maximum permitted size of the blocks.
+	MaxBlockSizeBytes = 104857600 // 100MB
+
+	// BlockPartSizeBytes is the size of one block part.
+	BlockPartSizeBytes uint32 = 65536 // 64kB
+
+	// MaxBlockPartsCount is the maximum number of block parts.
+	MaxBlockPartsCount = (MaxBlockSizeBytes / BlockPartSizeBytes) + 1
+)
+
+const (
+	ABCIPubKeyTypeEd25519   = ed25519.KeyType
+	ABCIPubKeyTypeSecp256k1 = secp256k1.KeyType
+)
+
+// TODO: Make non-global by allowing for registration of more pubkey types
+var ABCIPubKeyTypesToNames = map[string]string{
+	ABCIPubKeyTypeEd25519:   ed25519.PubKeyName,
+	ABCIPubKeyTypeSecp256k1: secp256k1.PubKeyName,
+}
+
+// DefaultConsensusParams returns a default ConsensusParams.
+func DefaultConsensusParams() *tmproto.ConsensusParams {
+	return &tmproto.ConsensusParams{
+		Block:     DefaultBlockParams(),
+		Evidence:  DefaultEvidenceParams(),
+		Validator: DefaultValidatorParams(),
+		Version:   DefaultVersionParams(),
+	}
+}
+
+// DefaultBlockParams returns a default BlockParams.
+func DefaultBlockParams() tmproto.BlockParams {
+	return tmproto.BlockParams{
+		MaxBytes:   22020096, // 21MB
+		MaxGas:     -1,
+		TimeIotaMs: 1000, // 1s
+	}
+}
+
+// DefaultEvidenceParams returns a default EvidenceParams.
+func DefaultEvidenceParams() tmproto.EvidenceParams {
+	return tmproto.EvidenceParams{
+		MaxAgeNumBlocks: 100000, // 27.8 hrs at 1block/s
+		MaxAgeDuration:  48 * time.Hour,
+		MaxBytes:        1048576, // 1MB
+	}
+}
+
+// DefaultValidatorParams returns a default ValidatorParams, which allows
+// only ed25519 pubkeys.
+func DefaultValidatorParams() tmproto.ValidatorParams {
+	return tmproto.ValidatorParams{
+		PubKeyTypes: []string{ABCIPubKeyTypeEd25519},
+	}
+}
+
+func DefaultVersionParams() tmproto.VersionParams {
+	return tmproto.VersionParams{
+		AppVersion: 0,
+	}
+}
+
+// Validate validates the ConsensusParams to ensure all values are within their
+// allowed limits, and returns an error if they are not.
+func ValidateConsensusParams(params tmproto.ConsensusParams) error {
+	if params.Block.MaxBytes <= 0 {
+		return fmt.Errorf("block.MaxBytes must be greater than 0. Got %d",
+			params.Block.MaxBytes)
+	}
+	if params.Block.MaxBytes > MaxBlockSizeBytes {
+		return fmt.Errorf("block.MaxBytes is too big. %d > %d",
+			params.Block.MaxBytes, MaxBlockSizeBytes)
+	}
+
+	if params.Block.MaxGas < -1 {
+		return fmt.Errorf("block.MaxGas must be greater or equal to -1. Got %d",
+			params.Block.MaxGas)
+	}
+
+	if params.Block.TimeIotaMs <= 0 {
+		return fmt.Errorf("block.TimeIotaMs must be greater than 0. Got %v",
+			params.Block.TimeIotaMs)
+	}
+
+	if params.Evidence.MaxAgeNumBlocks <= 0 {
+		return fmt.Errorf("evidence.MaxAgeNumBlocks must be greater than 0. Got %d",
+			params.Evidence.MaxAgeNumBlocks)
+	}
+
+	if params.Evidence.MaxAgeDuration <= 0 {
+		return fmt.Errorf("evidence.MaxAgeDuration must be grater than 0 if provided, Got %v",
+			params.Evidence.MaxAgeDuration)
+	}
+
+	if params.Evidence.MaxBytes > params.Block.MaxBytes {
+		return fmt.Errorf("evidence.MaxBytesEvidence is greater than upper bound, %d > %d",
+			params.Evidence.MaxBytes, params.Block.MaxBytes)
+	}
+
+	if params.Evidence.MaxBytes < 0 {
+		return fmt.Errorf("evidence.MaxBytes must be non negative. Got: %d",
+			params.Evidence.MaxBytes)
+	}
+
+	if len(params.Validator.PubKeyTypes) == 0 {
+		return errors.New("len(Validator.PubKeyTypes) must be greater than 0")
+	}
+
+	// Check if keyType is a known ABCIPubKeyType
+	for i := 0; i < len(params.Validator.PubKeyTypes); i++ {
+		keyType := params.Validator.PubKeyTypes[i]
+		if _, ok := ABCIPubKeyTypesToNames[keyType]; !ok {
+			return fmt.Errorf("params.Validator.PubKeyTypes[%d], %s, is an unknown pubkey type",
+				i, keyType)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,348 @@
+package tendermint
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	dbm "github.com/tendermint/tm-db"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/ndidplatform/migration-tools/utils"
+)
+
+const (
+	// BlockEncodingAmino is amino binary with length prefix (Tendermint < 0.34)
+	BlockEncodingAmino = "amino"
+	// BlockEncodingProtobuf is protobuf (Tendermint >= 0.34)
+	BlockEncodingProtobuf = "protobuf"
+)
+
+// BlockMeta is version-neutral block meta loaded from Tendermint block store
+type BlockMeta struct {
+	ChainID         string    `json:"chain_id"`
+	Height          int64     `json:"height"`
+	Time            time.Time `json:"time"`
+	BlockHash       HexBytes  `json:"block_hash"`
+	PartSetTotal    int       `json:"part_set_total"`
+	LastBlockHash   HexBytes  `json:"last_block_hash"`
+	DataHash        HexBytes  `json:"data_hash"`
+	AppHash         HexBytes  `json:"app_hash"`
+	LastResultsHash HexBytes  `json:"last_results_hash"`
+	ProposerAddress HexBytes  `json:"proposer_address"`
+	NumTxs          int64     `json:"num_txs"`
+}
+
+// Block is a block loaded from Tendermint block store
+type Block struct {
+	Meta BlockMeta
+	// Data is encoded block as stored in block store (block parts joined)
+	Data []byte
+	// ABCIResponses is encoded ABCI responses of the block saved in state DB.
+	// nil when not stored.
+	ABCIResponses []byte
+	Txs           [][]byte
+	// TxResults is results of Txs decoded from ABCIResponses. nil when ABCI
+	// responses are not stored.
+	TxResults []TxResult
+}
+
+// TxResult is result of a tx from ABCI DeliverTx response
+type TxResult struct {
+	Code uint32 `json:"code"`
+	Log  string `json:"log"`
+}
+
+// BlockCodec loads version specific data from Tendermint block store
+type BlockCodec interface {
+	TendermintVersion() string
+	// BlockEncoding is BlockEncodingAmino or BlockEncodingProtobuf
+	BlockEncoding() string
+	// LoadBlockStoreRange returns lowest and highest block height in block store
+	LoadBlockStoreRange(blockDB dbm.DB) (base int64, height int64, err error)
+	// LoadBlockMeta returns nil when block meta at height is not found
+	LoadBlockMeta(blockDB dbm.DB, height int64) (blockMeta *BlockMeta, err error)
+	// DecodeTxResults decodes results of txs from ABCI responses saved in
+	// state DB. txCount is number of txs in the block.
+	DecodeTxResults(abciResponses []byte, txCount int) (txResults []TxResult, err error)
+}
+
+// BlockReader reads blocks from block store and ABCI responses from state DB
+// of Tendermint home directory. DBs are opened read-only.
+type BlockReader struct {
+	codec   BlockCodec
+	stateDB dbm.DB
+	blockDB dbm.DB
+	base    int64
+	height  int64
+}
+
+// OpenBlockReader opens Tendermint DBs for reading blocks with codec of
+// Tendermint version of the data. Caller must close the returned reader.
+func OpenBlockReader(tmHome string, codec BlockCodec) (blockReader *BlockReader, err error) {
+	dbType, dbDir, err := GetDBConfig(tmHome)
+	if err != nil {
+		return nil, err
+	}
+	stateDB, err := utils.OpenDBReadOnly("state", dbType, dbDir)
+	if err != nil {
+		return nil, err
+	}
+	blockDB, err := utils.OpenDBReadOnly("blockstore", dbType, dbDir)
+	if err != nil {
+		stateDB.Close()
+		return nil, err
+	}
+	base, height, err := codec.LoadBlockStoreRange(blockDB)
+	if err != nil {
+		blockDB.Close()
+		stateDB.Close()
+		return nil, err
+	}
+	return &BlockReader{
+		codec:   codec,
+		stateDB: stateDB,
+		blockDB: blockDB,
+		base:    base,
+		height:  height,
+	}, nil
+}
+
+func (r *BlockReader) TendermintVersion() string {
+	return r.codec.TendermintVersion()
+}
+
+func (r *BlockReader) BlockEncoding() string {
+	return r.codec.BlockEncoding()
+}
+
+// Base is the lowest block height in block store
+func (r *BlockReader) Base() int64 {
+	return r.base
+}
+
+// Height is the highest block height in block store
+func (r *BlockReader) Height() int64 {
+	return r.height
+}
+
+func (r *BlockReader) Close() (err error) {
+	err = r.blockDB.Close()
+	stateDBCloseErr := r.stateDB.Close()
+	if err != nil {
+		return err
+	}
+	return stateDBCloseErr
+}
+
+// LoadBlock loads block at height with its txs and ABCI responses
+func (r *BlockReader) LoadBlock(height int64) (block *Block, err error) {
+	if height < r.base || height > r.height {
+		return nil, fmt.Errorf("block height %d out of range, block store range: %d-%d", height, r.base, r.height)
+	}
+	blockMeta, err := r.codec.LoadBlockMeta(r.blockDB, height)
+	if err != nil {
+		return nil, err
+	}
+	if blockMeta == nil {
+		return nil, fmt.Errorf("block meta at height %d not found", height)
+	}
+
+	var data []byte
+	for index := 0; index < blockMeta.PartSetTotal; index++ {
+		partBytes, err := r.blockDB.Get([]byte(fmt.Sprintf("P:%v:%v", height, index)))
+		if err != nil {
+			return nil, err
+		}
+		if len(partBytes) == 0 {
+			return nil, fmt.Errorf("block part %d at height %d not found", index, height)
+		}
+		// Part: index = 1, bytes = 2, proof = 3
+		partData, err := wireBytesField(partBytes, 2)
+		if err != nil {
+			return nil, fmt.Errorf("block part %d at height %d: %w", index, height, err)
+		}
+		data = append(data, partData...)
+	}
+
+	txs, err := DecodeBlockTxs(data, r.codec.BlockEncoding())
+	if err != nil {
+		return nil, fmt.Errorf("block at height %d: %w", height, err)
+	}
+	if int64(len(txs)) != blockMeta.NumTxs {
+		return nil, fmt.Errorf(
+			"block at height %d: decoded tx count %d does not match block meta tx count %d",
+			height,
+			len(txs),
+			blockMeta.NumTxs,
+		)
+	}
+
+	abciResponses, err := r.stateDB.Get([]byte("abciResponsesKey:" + strconv.FormatInt(height, 10)))
+	if err != nil {
+		return nil, err
+	}
+	var txResults []TxResult
+	if len(abciResponses) == 0 {
+		abciResponses = nil
+	} else {
+		txResults, err = r.codec.DecodeTxResults(abciResponses, len(txs))
+		if err != nil {
+			return nil, fmt.Errorf("ABCI responses at height %d: %w", height, err)
+		}
+		if len(txResults) != len(txs) {
+			return nil, fmt.Errorf(
+				"ABCI responses at height %d: tx result count %d does not match tx count %d",
+				height,
+				len(txResults),
+				len(txs),
+			)
+		}
+	}
+
+	return &Block{
+		Meta:          *blockMeta,
+		Data:          data,
+		ABCIResponses: abciResponses,
+		Txs:           txs,
+		TxResults:     txResults,
+	}, nil
+}
+
+// DecodeBlockTxs returns txs in encoded block
+func DecodeBlockTxs(data []byte, blockEncoding string) (txs [][]byte, err error) {
+	switch blockEncoding {
+	case BlockEncodingAmino:
+		data, err = trimLengthPrefix(data)
+		if err != nil {
+			return nil, err
+		}
+	case BlockEncodingProtobuf:
+	default:
+		return nil, errors.New("unknown block encoding: " + blockEncoding)
+	}
+	// Block: header = 1, data = 2, evidence = 3, last_commit = 4
+	blockData, err := wireBytesField(data, 2)
+	if err != nil {
+		return nil, err
+	}
+	// Data: txs = 1
+	txs = make([][]byte, 0)
+	err = wireFields(blockData, func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error {
+		if num == 1 && typ == protowire.BytesType {
+			txs = append(txs, value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// DecodeTxResults returns results of txs in encoded ABCIResponses
+// (Tendermint < CometBFT 0.38)
+func DecodeTxResults(abciResponses []byte) (txResults []TxResult, err error) {
+	// ABCIResponses: deliver_txs = 1, end_block = 2, begin_block = 3
+	return decodeTxResults(abciResponses, 1)
+}
+
+// DecodeFinalizeBlockTxResults returns results of txs in encoded
+// ResponseFinalizeBlock (CometBFT >= 0.38)
+func DecodeFinalizeBlockTxResults(finalizeBlockResponse []byte) (txResults []TxResult, err error) {
+	// ResponseFinalizeBlock: events = 1, tx_results = 2
+	return decodeTxResults(finalizeBlockResponse, 2)
+}
+
+// IsFinalizeBlockResponse reports whether encoded ABCI responses has fields
+// which exist only in ResponseFinalizeBlock (consensus_param_updates = 4,
+// app_hash = 5) and not in legacy ABCIResponses
+func IsFinalizeBlockResponse(abciResponses []byte) bool {
+	var found bool
+	err := wireFields(abciResponses, func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error {
+		if num == 4 || num == 5 {
+			found = true
+		}
+		return nil
+	})
+	return err == nil && found
+}
+
+func decodeTxResults(msg []byte, txResultsField protowire.Number) (txResults []TxResult, err error) {
+	txResults = make([]TxResult, 0)
+	err = wireFields(msg, func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error {
+		if num != txResultsField || typ != protowire.BytesType {
+			return nil
+		}
+		// ResponseDeliverTx / ExecTxResult: code = 1, data = 2, log = 3
+		var txResult TxResult
+		err := wireFields(value, func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error {
+			switch {
+			case num == 1 && typ == protowire.VarintType:
+				txResult.Code = uint32(varint)
+			case num == 3 && typ == protowire.BytesType:
+				txResult.Log = string(value)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		txResults = append(txResults, txResult)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return txResults, nil
+}
+
+// TxHash is hash of tx used by Tendermint (SHA-256)
+func TxHash(tx []byte) []byte {
+	hash := sha256.Sum256(tx)
+	return hash[:]
+}
+
+// jsonInt64 is int64 which can be unmarshaled from JSON number or string
+// (amino JSON encodes int64 as string)
+type jsonInt64 int64
+
+func (i *jsonInt64) UnmarshalJSON(data []byte) (err error) {
+	value, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = jsonInt64(value)
+	return nil
+}
+
+// LoadBlockStoreRangeJSON loads block store state saved as (amino) JSON by
+// Tendermint < 0.34
+func LoadBlockStoreRangeJSON(blockDB dbm.DB) (base int64, height int64, err error) {
+	bz, err := blockDB.Get([]byte("blockStore"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(bz) == 0 {
+		return 0, 0, nil
+	}
+	var blockStoreState struct {
+		Base   jsonInt64 `json:"base"`
+		Height jsonInt64 `json:"height"`
+	}
+	err = json.Unmarshal(bz, &blockStoreState)
+	if err != nil {
+		return 0, 0, err
+	}
+	base = int64(blockStoreState.Base)
+	height = int64(blockStoreState.Height)
+	// base is not saved before Tendermint 0.33
+	if height > 0 && base == 0 {
+		base = 1
+	}
+	return base, height, nil
+}
@@ -0,0 +1,20 @@
+package cometbft_0_37
+
+import (
+	"github.com/ndidplatform/migration-tools/tendermint"
+	tendermint_0_34_19 "github.com/ndidplatform/migration-tools/tendermint/0_34_19"
+)
+
+// BlockCodec loads block store data of CometBFT 0.37
+type BlockCodec struct {
+	tendermint_0_34_19.BlockCodec
+}
+
+func (BlockCodec) TendermintVersion() string {
+	return TendermintVersion
+}
+
+// OpenBlockReader opens CometBFT DBs in tmHome for reading blocks
+func OpenBlockReader(tmHome string) (blockReader *tendermint.BlockReader, err error) {
+	return tendermint.OpenBlockReader(tmHome, BlockCodec{})
+}
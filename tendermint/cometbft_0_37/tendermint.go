@@ -0,0 +1,63 @@
+package cometbft_0_37
+
+// State store and block store of CometBFT 0.37 are wire compatible with
+// Tendermint 0.34 (same protobuf messages and field numbers, block protocol
+// 11). BlockParams.TimeIotaMs is removed and is read as 0. Decoders of
+// tendermint/0_34_19 are used.
+
+import (
+	"github.com/ndidplatform/migration-tools/tendermint"
+	tendermint_0_34_19 "github.com/ndidplatform/migration-tools/tendermint/0_34_19"
+)
+
+const TendermintVersion = "0.37"
+
+type TendermintStateInfo = tendermint_0_34_19.TendermintStateInfo
+
+// GetStateInfo loads chain info of block at given height from state DB and
+// block store. Latest block height is used when height is 0.
+func GetStateInfo(tmHome string, height int64) (stateInfo *tendermint.StateInfo, err error) {
+	stateInfo, err = tendermint_0_34_19.GetStateInfo(tmHome, height)
+	if err != nil {
+		return nil, err
+	}
+	stateInfo.TendermintVersion = TendermintVersion
+	return stateInfo, nil
+}
+
+func GetTendermintInfo(tmHome string) (tendermintStateInfo *TendermintStateInfo, err error) {
+	return GetTendermintInfoAtHeight(tmHome, 0)
+}
+
+// GetTendermintInfoAtHeight loads chain info of block at given height.
+// Latest block height is used when height is 0.
+func GetTendermintInfoAtHeight(tmHome string, height int64) (tendermintStateInfo *TendermintStateInfo, err error) {
+	return tendermint_0_34_19.GetTendermintInfoAtHeight(tmHome, height)
+}
+
+// GetValidatorsBackup loads current validator set and consensus params from
+// CometBFT state DB
+func GetValidatorsBackup(tmHome string) (validatorsBackup *tendermint.ValidatorsBackup, err error) {
+	validatorsBackup, err = tendermint_0_34_19.GetValidatorsBackup(tmHome)
+	if err != nil {
+		return nil, err
+	}
+	validatorsBackup.TendermintVersion = TendermintVersion
+	return validatorsBackup, nil
+}
+
+// DecodeStateVersion decodes version info from raw CometBFT state
+func DecodeStateVersion(stateBytes []byte) (stateVersion *tendermint.StateVersion, err error) {
+	return tendermint_0_34_19.DecodeStateVersion(stateBytes)
+}
+
+// StateInfoReader implements tendermint.StateInfoReader
+type StateInfoReader struct{}
+
+func (StateInfoReader) TendermintVersion() string {
+	return TendermintVersion
+}
+
+func (StateInfoReader) GetStateInfo(tmHome string, height int64) (stateInfo *tendermint.StateInfo, err error) {
+	return GetStateInfo(tmHome, height)
+}
@@ -0,0 +1,34 @@
+package cometbft_0_38
+
+import (
+	"github.com/ndidplatform/migration-tools/tendermint"
+	tendermint_0_34_19 "github.com/ndidplatform/migration-tools/tendermint/0_34_19"
+)
+
+// BlockCodec loads block store data of CometBFT 0.38
+type BlockCodec struct {
+	tendermint_0_34_19.BlockCodec
+}
+
+func (BlockCodec) TendermintVersion() string {
+	return TendermintVersion
+}
+
+// DecodeTxResults decodes FinalizeBlock response saved by CometBFT 0.38.
+// Legacy ABCI responses (saved before upgrading to 0.38) are decoded when
+// response has no FinalizeBlock only fields and tx count does not match.
+func (BlockCodec) DecodeTxResults(abciResponses []byte, txCount int) (txResults []tendermint.TxResult, err error) {
+	if tendermint.IsFinalizeBlockResponse(abciResponses) {
+		return tendermint.DecodeFinalizeBlockTxResults(abciResponses)
+	}
+	txResults, err = tendermint.DecodeTxResults(abciResponses)
+	if err == nil && len(txResults) == txCount {
+		return txResults, nil
+	}
+	return tendermint.DecodeFinalizeBlockTxResults(abciResponses)
+}
+
+// OpenBlockReader opens CometBFT DBs in tmHome for reading blocks
+func OpenBlockReader(tmHome string) (blockReader *tendermint.BlockReader, err error) {
+	return tendermint.OpenBlockReader(tmHome, BlockCodec{})
+}
@@ -0,0 +1,59 @@
+package tendermint
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// HexBytes is bytes encoded as upper case hex string in JSON
+type HexBytes []byte
+
+func (bz HexBytes) String() string {
+	return strings.ToUpper(hex.EncodeToString(bz))
+}
+
+func (bz HexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bz.String())
+}
+
+func (bz *HexBytes) UnmarshalJSON(data []byte) (err error) {
+	var hexString string
+	err = json.Unmarshal(data, &hexString)
+	if err != nil {
+		return err
+	}
+	*bz, err = hex.DecodeString(hexString)
+	return err
+}
+
+// StateInfo is version-neutral chain info of a block loaded from Tendermint
+// state DB and block store
+type StateInfo struct {
+	TendermintVersion string    `json:"tendermint_version"`
+	ChainID           string    `json:"chain_id"`
+	InitialHeight     int64     `json:"initial_height"`
+	LastBlockHeight   int64     `json:"last_block_height"`
+	LastBlockHash     HexBytes  `json:"last_block_hash"`
+	LastBlockTime     time.Time `json:"last_block_time"`
+	// AppHash is app hash in header of the block (state after previous block)
+	AppHash HexBytes `json:"app_hash"`
+	// CommittedAppHash is app hash returned by ABCI Commit of the block
+	CommittedAppHash HexBytes `json:"committed_app_hash"`
+	// LastResultsHash is hash of results of txs in the block
+	LastResultsHash HexBytes `json:"last_results_hash"`
+	// Validators is validator set of latest state
+	Validators []Validator `json:"validators"`
+	DBBackend  string      `json:"db_backend"`
+	DBDir      string      `json:"db_dir"`
+}
+
+// StateInfoReader reads StateInfo from Tendermint home directory of a
+// Tendermint version
+type StateInfoReader interface {
+	TendermintVersion() string
+	// GetStateInfo loads info of block at given height (latest block when
+	// height is 0)
+	GetStateInfo(tmHome string, height int64) (stateInfo *StateInfo, err error)
+}
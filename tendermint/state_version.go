@@ -0,0 +1,61 @@
+package tendermint
+
+import (
+	"errors"
+	"path"
+
+	"github.com/BurntSushi/toml"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/ndidplatform/migration-tools/utils"
+)
+
+// StateVersion is version info decoded from raw Tendermint state with state
+// decoder of a Tendermint version. Used for detecting Tendermint version of
+// on-disk data.
+type StateVersion struct {
+	Software             string `json:"software"`
+	BlockProtocol        uint64 `json:"block_protocol"`
+	BlockProtocolMatched bool   `json:"block_protocol_matched"`
+	ChainID              string `json:"chain_id"`
+	LastBlockHeight      int64  `json:"last_block_height"`
+}
+
+type tomlConfig struct {
+	DBBackend string `toml:"db_backend"`
+	DBPath    string `toml:"db_dir"`
+}
+
+var stateKey = []byte("stateKey")
+
+// GetDBConfig reads DB backend and DB directory path from Tendermint config
+func GetDBConfig(tmHome string) (dbType dbm.BackendType, dbDir string, err error) {
+	configFile := path.Join(tmHome, "config/config.toml")
+	var config tomlConfig
+	if _, err = toml.DecodeFile(configFile, &config); err != nil {
+		return "", "", err
+	}
+	return dbm.BackendType(config.DBBackend), path.Join(tmHome, config.DBPath), nil
+}
+
+// LoadStateBytes reads raw (not decoded) state from Tendermint state DB
+func LoadStateBytes(tmHome string) (stateBytes []byte, err error) {
+	dbType, dbDir, err := GetDBConfig(tmHome)
+	if err != nil {
+		return nil, err
+	}
+	stateDB, err := utils.OpenDBReadOnly("state", dbType, dbDir)
+	if err != nil {
+		return nil, err
+	}
+	defer stateDB.Close()
+
+	stateBytes, err = stateDB.Get(stateKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(stateBytes) == 0 {
+		return nil, errors.New("Tendermint state not found")
+	}
+	return stateBytes, nil
+}
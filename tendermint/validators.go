@@ -0,0 +1,89 @@
+package tendermint
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Version-neutral representation of validator set and consensus params
+// loaded from Tendermint state DB of any supported Tendermint version.
+// Used for generating genesis of a new chain.
+
+const (
+	PubKeyTypeEd25519   = "ed25519"
+	PubKeyTypeSecp256k1 = "secp256k1"
+	PubKeyTypeSr25519   = "sr25519"
+)
+
+type ValidatorsBackup struct {
+	TendermintVersion string          `json:"tendermint_version"`
+	ChainID           string          `json:"chain_id"`
+	LastBlockHeight   int64           `json:"last_block_height"`
+	Validators        []Validator     `json:"validators"`
+	NextValidators    []Validator     `json:"next_validators"`
+	ConsensusParams   ConsensusParams `json:"consensus_params"`
+}
+
+type Validator struct {
+	// Upper case hex
+	Address          string `json:"address"`
+	PubKey           PubKey `json:"pub_key"`
+	VotingPower      int64  `json:"voting_power"`
+	ProposerPriority int64  `json:"proposer_priority"`
+}
+
+type PubKey struct {
+	// ABCI public key type name e.g. "ed25519"
+	Type  string `json:"type"`
+	Value []byte `json:"value"`
+}
+
+type ConsensusParams struct {
+	Block     BlockParams     `json:"block"`
+	Evidence  EvidenceParams  `json:"evidence"`
+	Validator ValidatorParams `json:"validator"`
+	Version   VersionParams   `json:"version"`
+}
+
+type BlockParams struct {
+	MaxBytes   int64 `json:"max_bytes"`
+	MaxGas     int64 `json:"max_gas"`
+	TimeIotaMs int64 `json:"time_iota_ms"`
+}
+
+type EvidenceParams struct {
+	MaxAgeNumBlocks int64 `json:"max_age_num_blocks"`
+	// 0 if not available in source Tendermint version (before 0.33)
+	MaxAgeDuration time.Duration `json:"max_age_duration"`
+	// 0 if not available in source Tendermint version (before 0.34)
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+type ValidatorParams struct {
+	PubKeyTypes []string `json:"pub_key_types"`
+}
+
+type VersionParams struct {
+	AppVersion uint64 `json:"app_version"`
+}
+
+func WriteValidatorsBackup(filePath string, validatorsBackup *ValidatorsBackup) (err error) {
+	validatorsBackupJSON, err := json.MarshalIndent(validatorsBackup, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, validatorsBackupJSON, 0644)
+}
+
+func ReadValidatorsBackup(filePath string) (validatorsBackup *ValidatorsBackup, err error) {
+	validatorsBackupJSON, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(validatorsBackupJSON, &validatorsBackup)
+	if err != nil {
+		return nil, err
+	}
+	return validatorsBackup, nil
+}
@@ -0,0 +1,70 @@
+package tendermint
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Minimal protobuf wire format reader. Amino binary encoding of structs used
+// in block store and state DB is compatible with protobuf wire format, so
+// fields which are the same in all Tendermint versions (e.g. txs of block) can
+// be read without version specific types.
+
+// wireFields calls fn for each field in msg. value is field content for
+// length-delimited fields and nil for other wire types.
+func wireFields(
+	msg []byte,
+	fn func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error,
+) (err error) {
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		var value []byte
+		var varint uint64
+		switch typ {
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(msg)
+		case protowire.VarintType:
+			varint, n = protowire.ConsumeVarint(msg)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, msg)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		err = fn(num, typ, value, varint)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wireBytesField returns last value of length-delimited field num in msg
+func wireBytesField(msg []byte, num protowire.Number) (value []byte, err error) {
+	err = wireFields(msg, func(fieldNum protowire.Number, typ protowire.Type, fieldValue []byte, varint uint64) error {
+		if fieldNum == num && typ == protowire.BytesType {
+			value = fieldValue
+		}
+		return nil
+	})
+	return value, err
+}
+
+// trimLengthPrefix removes uvarint length prefix of amino length-prefixed
+// encoding
+func trimLengthPrefix(bz []byte) (msg []byte, err error) {
+	length, n := protowire.ConsumeVarint(bz)
+	if n < 0 {
+		return nil, protowire.ParseError(n)
+	}
+	if length != uint64(len(bz)-n) {
+		return nil, fmt.Errorf("invalid length prefix: %d, actual length: %d", length, len(bz)-n)
+	}
+	return bz[n:], nil
+}
@@ -0,0 +1,120 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package tm_client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ConnectionConfig holds transport security and authentication settings of
+// Tendermint RPC connections. Zero value means plain http/ws without auth.
+type ConnectionConfig struct {
+	// Use https/wss instead of http/ws
+	TLS bool
+	// PEM encoded CA bundle used to verify server certificate.
+	// System CA pool is used if empty.
+	CACertFile string
+	// PEM encoded client certificate and key for mutual TLS. Both or neither must be set.
+	ClientCertFile string
+	ClientKeyFile  string
+	// Skip server certificate verification. For testing only.
+	InsecureSkipVerify bool
+
+	// Sent as "Authorization: Bearer <token>". Takes precedence over basic auth.
+	BearerToken string
+	// Sent as "Authorization: Basic ..." if username is set
+	BasicAuthUsername string
+	BasicAuthPassword string
+}
+
+func (connectionConfig ConnectionConfig) httpScheme() string {
+	if connectionConfig.TLS {
+		return "https"
+	}
+	return "http"
+}
+
+func (connectionConfig ConnectionConfig) websocketScheme() string {
+	if connectionConfig.TLS {
+		return "wss"
+	}
+	return "ws"
+}
+
+// HTTPAddress returns base URL of Tendermint RPC HTTP endpoint
+func (connectionConfig ConnectionConfig) HTTPAddress(rpcHost string, rpcPort string) string {
+	return connectionConfig.httpScheme() + "://" + rpcHost + ":" + rpcPort
+}
+
+func (connectionConfig ConnectionConfig) tlsConfig() (*tls.Config, error) {
+	if !connectionConfig.TLS {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: connectionConfig.InsecureSkipVerify,
+	}
+
+	if connectionConfig.CACertFile != "" {
+		caCert, err := ioutil.ReadFile(connectionConfig.CACertFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read CA certificate file")
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caCert) {
+			return nil, errors.Errorf("no certificate found in CA certificate file %s", connectionConfig.CACertFile)
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	if connectionConfig.ClientCertFile != "" || connectionConfig.ClientKeyFile != "" {
+		if connectionConfig.ClientCertFile == "" || connectionConfig.ClientKeyFile == "" {
+			return nil, errors.New("both client certificate file and client key file must be set")
+		}
+		clientCert, err := tls.LoadX509KeyPair(connectionConfig.ClientCertFile, connectionConfig.ClientKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot load client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return tlsConfig, nil
+}
+
+func (connectionConfig ConnectionConfig) header() http.Header {
+	header := http.Header{}
+	if connectionConfig.BearerToken != "" {
+		header.Set("Authorization", "Bearer "+connectionConfig.BearerToken)
+	} else if connectionConfig.BasicAuthUsername != "" {
+		credentials := connectionConfig.BasicAuthUsername + ":" + connectionConfig.BasicAuthPassword
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+	return header
+}
@@ -0,0 +1,265 @@
+package tm_client
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// HTTPClient is HTTP transport of Tendermint RPC. Use TmClient (websocket)
+// when subscription to events is needed.
+type HTTPClient struct {
+	address string
+	client  *http.Client
+	header  http.Header
+}
+
+func NewHTTPClient(rpcHost string, rpcPort string, connectionConfig ConnectionConfig) (*HTTPClient, error) {
+	tlsConfig, err := connectionConfig.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &HTTPClient{
+		address: connectionConfig.HTTPAddress(rpcHost, rpcPort),
+		client: &http.Client{
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		header: connectionConfig.header(),
+	}, nil
+}
+
+// Address returns base URL of Tendermint RPC HTTP endpoint
+func (httpClient *HTTPClient) Address() string {
+	return httpClient.address
+}
+
+func (httpClient *HTTPClient) call(method string, parameters url.Values, result interface{}) error {
+	URL, err := url.Parse(httpClient.address)
+	if err != nil {
+		return err
+	}
+	URL.Path += "/" + method
+	URL.RawQuery = parameters.Encode()
+	req, err := http.NewRequest("GET", URL.String(), nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range httpClient.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return errors.Errorf("Tendermint RPC %s: %s", method, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (httpClient *HTTPClient) BroadcastTxCommit(tx []byte) (*HTTPResponseTx, error) {
+	parameters := url.Values{}
+	parameters.Add("tx", `0x`+hex.EncodeToString(tx))
+	var body HTTPResponseTx
+	err := httpClient.call("broadcast_tx_commit", parameters, &body)
+	if err != nil {
+		return nil, err
+	}
+	return &body, nil
+}
+
+func (httpClient *HTTPClient) Status() (*HTTPResponseStatus, error) {
+	var body HTTPResponseStatus
+	err := httpClient.call("status", url.Values{}, &body)
+	if err != nil {
+		return nil, err
+	}
+	return &body, nil
+}
+
+func (httpClient *HTTPClient) Block(height int64) (*HTTPBlockResult, error) {
+	parameters := url.Values{}
+	parameters.Add("height", strconv.FormatInt(height, 10))
+	var body HTTPBlockResult
+	err := httpClient.call("block", parameters, &body)
+	if err != nil {
+		return nil, err
+	}
+	return &body, nil
+}
+
+type HTTPResponseTx struct {
+	Result struct {
+		Height  int `json:"height"`
+		CheckTx struct {
+			Code int      `json:"code"`
+			Log  string   `json:"log"`
+			Fee  struct{} `json:"fee"`
+		} `json:"check_tx"`
+		DeliverTx struct {
+			Log  string   `json:"log"`
+			Fee  struct{} `json:"fee"`
+			Tags []Pair
+		} `json:"deliver_tx"`
+		Hash string `json:"hash"`
+	} `json:"result"`
+	Jsonrpc string `json:"jsonrpc"`
+	ID      string `json:"id"`
+}
+
+type Pair struct {
+	Key   []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+type HTTPResponseQuery struct {
+	Jsonrpc string `json:"jsonrpc"`
+	ID      string `json:"id"`
+	Result  struct {
+		Response struct {
+			Log    string `json:"log"`
+			Value  string `json:"value"`
+			Height string `json:"height"`
+		} `json:"response"`
+	} `json:"result"`
+}
+
+type HTTPResponseStatus struct {
+	Jsonrpc string `json:"jsonrpc"`
+	ID      string `json:"id"`
+	Result  struct {
+		NodeInfo struct {
+			ID         string   `json:"id"`
+			ListenAddr string   `json:"listen_addr"`
+			Network    string   `json:"network"`
+			Version    string   `json:"version"`
+			Channels   string   `json:"channels"`
+			Moniker    string   `json:"moniker"`
+			Other      []string `json:"other"`
+		} `json:"node_info"`
+		SyncInfo struct {
+			LatestBlockHash   string    `json:"latest_block_hash"`
+			LatestAppHash     string    `json:"latest_app_hash"`
+			LatestBlockHeight string    `json:"latest_block_height"`
+			LatestBlockTime   time.Time `json:"latest_block_time"`
+			CatchingUp        bool      `json:"catching_up"`
+		} `json:"sync_info"`
+		ValidatorInfo struct {
+			Address string `json:"address"`
+			PubKey  struct {
+				Type  string `json:"type"`
+				Value string `json:"value"`
+			} `json:"pub_key"`
+			VotingPower string `json:"voting_power"`
+		} `json:"validator_info"`
+	} `json:"result"`
+}
+
+type HTTPBlockResult struct {
+	Jsonrpc string `json:"jsonrpc"`
+	ID      string `json:"id"`
+	Result  struct {
+		BlockMeta struct {
+			BlockID struct {
+				Hash  string `json:"hash"`
+				Parts struct {
+					Total FlexInt `json:"total"`
+					Hash  string  `json:"hash"`
+				} `json:"parts"`
+			} `json:"block_id"`
+			Header struct {
+				ChainID     string    `json:"chain_id"`
+				Height      string    `json:"height"`
+				Time        time.Time `json:"time"`
+				NumTxs      string    `json:"num_txs"`
+				LastBlockID struct {
+					Hash  string `json:"hash"`
+					Parts struct {
+						Total FlexInt `json:"total"`
+						Hash  string  `json:"hash"`
+					} `json:"parts"`
+				} `json:"last_block_id"`
+				TotalTxs        string `json:"total_txs"`
+				LastCommitHash  string `json:"last_commit_hash"`
+				DataHash        string `json:"data_hash"`
+				ValidatorsHash  string `json:"validators_hash"`
+				ConsensusHash   string `json:"consensus_hash"`
+				AppHash         string `json:"app_hash"`
+				LastResultsHash string `json:"last_results_hash"`
+				EvidenceHash    string `json:"evidence_hash"`
+			} `json:"header"`
+		} `json:"block_meta"`
+		Block struct {
+			Header struct {
+				ChainID     string    `json:"chain_id"`
+				Height      string    `json:"height"`
+				Time        time.Time `json:"time"`
+				NumTxs      string    `json:"num_txs"`
+				LastBlockID struct {
+					Hash  string `json:"hash"`
+					Parts struct {
+						Total FlexInt `json:"total"`
+						Hash  string  `json:"hash"`
+					} `json:"parts"`
+				} `json:"last_block_id"`
+				TotalTxs        string `json:"total_txs"`
+				LastCommitHash  string `json:"last_commit_hash"`
+				DataHash        string `json:"data_hash"`
+				ValidatorsHash  string `json:"validators_hash"`
+				ConsensusHash   string `json:"consensus_hash"`
+				AppHash         string `json:"app_hash"`
+				LastResultsHash string `json:"last_results_hash"`
+				EvidenceHash    string `json:"evidence_hash"`
+			} `json:"header"`
+			Data struct {
+				Txs interface{} `json:"txs"`
+			} `json:"data"`
+			Evidence struct {
+				Evidence interface{} `json:"evidence"`
+			} `json:"evidence"`
+			LastCommit struct {
+				BlockID struct {
+					Hash  string `json:"hash"`
+					Parts struct {
+						Total FlexInt `json:"total"`
+						Hash  string  `json:"hash"`
+					} `json:"parts"`
+				} `json:"block_id"`
+				Precommits []struct {
+					ValidatorAddress string    `json:"validator_address"`
+					ValidatorIndex   string    `json:"validator_index"`
+					Height           string    `json:"height"`
+					Round            string    `json:"round"`
+					Timestamp        time.Time `json:"timestamp"`
+					Type             int       `json:"type"`
+					BlockID          struct {
+						Hash  string `json:"hash"`
+						Parts struct {
+							Total FlexInt `json:"total"`
+							Hash  string  `json:"hash"`
+						} `json:"parts"`
+					} `json:"block_id"`
+					Signature struct {
+						Type  string `json:"type"`
+						Value string `json:"value"`
+					} `json:"signature"`
+				} `json:"precommits"`
+			} `json:"last_commit"`
+		} `json:"block"`
+	} `json:"result"`
+}
@@ -1,6 +1,39 @@
 package tm_client
 
-import "time"
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// FlexInt is an integer which may be encoded as either JSON number or JSON
+// string, depending on Tendermint version.
+type FlexInt int64
+
+func (i *FlexInt) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		if str == "" {
+			*i = 0
+			return nil
+		}
+		value, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return err
+		}
+		*i = FlexInt(value)
+		return nil
+	}
+	var value int64
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*i = FlexInt(value)
+	return nil
+}
 
 type JsonRPC struct {
 	Jsonrpc string `json:"jsonrpc"`
@@ -68,16 +101,37 @@ type ResponseStatus struct {
 	} `json:"validator_info"`
 }
 
+type ResponseNetInfo struct {
+	Listening bool     `json:"listening"`
+	Listeners []string `json:"listeners"`
+	NPeers    FlexInt  `json:"n_peers"`
+	Peers     []struct {
+		NodeInfo struct {
+			ID         string `json:"id"`
+			ListenAddr string `json:"listen_addr"`
+			Network    string `json:"network"`
+			Moniker    string `json:"moniker"`
+		} `json:"node_info"`
+		IsOutbound bool   `json:"is_outbound"`
+		RemoteIP   string `json:"remote_ip"`
+	} `json:"peers"`
+}
+
+type BlockID struct {
+	Hash  string `json:"hash"`
+	Parts struct {
+		Total FlexInt `json:"total"`
+		Hash  string  `json:"hash"`
+	} `json:"parts"`
+}
+
 type ResponseBlock struct {
+	// Tendermint 0.33 or later
+	BlockID BlockID `json:"block_id"`
+	// Tendermint 0.32
 	BlockMeta struct {
-		BlockID struct {
-			Hash  string `json:"hash"`
-			Parts struct {
-				Total string `json:"total"`
-				Hash  string `json:"hash"`
-			} `json:"parts"`
-		} `json:"block_id"`
-		Header struct {
+		BlockID BlockID `json:"block_id"`
+		Header  struct {
 			Version struct {
 				Block string `json:"block"`
 				App   string `json:"app"`
@@ -90,8 +144,8 @@ type ResponseBlock struct {
 			LastBlockID struct {
 				Hash  string `json:"hash"`
 				Parts struct {
-					Total string `json:"total"`
-					Hash  string `json:"hash"`
+					Total FlexInt `json:"total"`
+					Hash  string  `json:"hash"`
 				} `json:"parts"`
 			} `json:"last_block_id"`
 			LastCommitHash     string `json:"last_commit_hash"`
@@ -119,8 +173,8 @@ type ResponseBlock struct {
 			LastBlockID struct {
 				Hash  string `json:"hash"`
 				Parts struct {
-					Total string `json:"total"`
-					Hash  string `json:"hash"`
+					Total FlexInt `json:"total"`
+					Hash  string  `json:"hash"`
 				} `json:"parts"`
 			} `json:"last_block_id"`
 			LastCommitHash     string `json:"last_commit_hash"`
@@ -143,8 +197,8 @@ type ResponseBlock struct {
 			BlockID struct {
 				Hash  string `json:"hash"`
 				Parts struct {
-					Total string `json:"total"`
-					Hash  string `json:"hash"`
+					Total FlexInt `json:"total"`
+					Hash  string  `json:"hash"`
 				} `json:"parts"`
 			} `json:"block_id"`
 			Precommits []struct {
@@ -154,8 +208,8 @@ type ResponseBlock struct {
 				BlockID struct {
 					Hash  string `json:"hash"`
 					Parts struct {
-						Total string `json:"total"`
-						Hash  string `json:"hash"`
+						Total FlexInt `json:"total"`
+						Hash  string  `json:"hash"`
 					} `json:"parts"`
 				} `json:"block_id"`
 				Timestamp        time.Time `json:"timestamp"`
@@ -167,24 +221,31 @@ type ResponseBlock struct {
 	} `json:"block"`
 }
 
+type ResponseDeliverTx struct {
+	Code      uint32 `json:"code"`
+	Data      string `json:"data"`
+	Log       string `json:"log"`
+	Info      string `json:"info"`
+	GasWanted string `json:"gasWanted"`
+	GasUsed   string `json:"gasUsed"`
+	Events    []struct {
+		Type       string `json:"type"`
+		Attributes []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"attributes"`
+	} `json:"events"`
+	Codespace string `json:"codespace"`
+}
+
 type ResponseBlockResults struct {
-	Height     string `json:"height"`
-	TxsResults []struct {
-		Code      uint32 `json:"code"`
-		Data      string `json:"data"`
-		Log       string `json:"log"`
-		Info      string `json:"info"`
-		GasWanted string `json:"gasWanted"`
-		GasUsed   string `json:"gasUsed"`
-		Events    []struct {
-			Type       string `json:"type"`
-			Attributes []struct {
-				Key   string `json:"key"`
-				Value string `json:"value"`
-			} `json:"attributes"`
-		} `json:"events"`
-		Codespace string `json:"codespace"`
-	} `json:"txs_results"`
+	Height string `json:"height"`
+	// Tendermint 0.33 or later
+	TxsResults []ResponseDeliverTx `json:"txs_results"`
+	// Tendermint 0.32
+	Results *struct {
+		DeliverTx []ResponseDeliverTx `json:"deliver_tx"`
+	} `json:"results"`
 	BeginBlockEvents []struct {
 		Type       string `json:"type"`
 		Attributes []struct {
@@ -283,8 +344,8 @@ type BlockHeader struct {
 	LastBlockID struct {
 		Hash  string `json:"hash"`
 		Parts struct {
-			Total string `json:"total"`
-			Hash  string `json:"hash"`
+			Total FlexInt `json:"total"`
+			Hash  string  `json:"hash"`
 		} `json:"parts"`
 	} `json:"last_block_id"`
 	LastCommitHash     string `json:"last_commit_hash"`
@@ -333,8 +394,8 @@ type EventDataNewBlock struct {
 					BlockID struct {
 						Hash  string `json:"hash"`
 						Parts struct {
-							Total string `json:"total"`
-							Hash  string `json:"hash"`
+							Total FlexInt `json:"total"`
+							Hash  string  `json:"hash"`
 						} `json:"parts"`
 					} `json:"block_id"`
 					Precommits []struct {
@@ -344,8 +405,8 @@ type EventDataNewBlock struct {
 						BlockID struct {
 							Hash  string `json:"hash"`
 							Parts struct {
-								Total string `json:"total"`
-								Hash  string `json:"hash"`
+								Total FlexInt `json:"total"`
+								Hash  string  `json:"hash"`
 							} `json:"parts"`
 						} `json:"block_id"`
 						Timestamp        time.Time `json:"timestamp"`
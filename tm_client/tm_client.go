@@ -0,0 +1,729 @@
+package tm_client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+
+	"github.com/ndidplatform/migration-tools/log"
+)
+
+const (
+	// Time allowed to write a message to the peer.
+	writeWait = 10 * time.Second
+
+	// Time allowed to read the next pong message from the peer.
+	readWait = 30 * time.Second
+
+	// Send pings to peer with this period. Must be less than pongWait.
+	pingPeriod = (readWait * 9) / 10
+
+	// Maximum message size allowed from peer.
+	// maxMessageSize = 512
+)
+
+var (
+	ErrNotConnected     = errors.New("not connected")
+	ErrClientClosed     = errors.New("client closed")
+	ErrConnectionClosed = errors.New("connection closed while call is not finished")
+)
+
+type TendermintVersion string
+
+const (
+	TendermintVersion0_32 TendermintVersion = "0.32"
+	TendermintVersion0_33 TendermintVersion = "0.33"
+	TendermintVersion0_34 TendermintVersion = "0.34"
+)
+
+type Config struct {
+	// Tendermint version of RPC server. Used to normalize response shapes.
+	TendermintVersion TendermintVersion
+	// Timeout of each RPC call. No timeout if 0.
+	CallTimeout time.Duration
+	// Delay before the first reconnect attempt. Doubled on each failed attempt.
+	ReconnectInitialBackoff time.Duration
+	// Maximum delay between reconnect attempts.
+	ReconnectMaxBackoff time.Duration
+	// Maximum number of reconnect attempts. Reconnect is disabled if 0.
+	ReconnectMaxAttempts int
+	// TLS and authentication settings
+	Connection ConnectionConfig
+}
+
+func DefaultConfig() Config {
+	return Config{
+		TendermintVersion:       TendermintVersion0_34,
+		CallTimeout:             60 * time.Second,
+		ReconnectInitialBackoff: 1 * time.Second,
+		ReconnectMaxBackoff:     30 * time.Second,
+		ReconnectMaxAttempts:    10,
+	}
+}
+
+type TmClient struct {
+	logger  log.Logger
+	config  Config
+	rpcHost string
+	rpcPort string
+
+	dialer *websocket.Dialer
+	header http.Header
+
+	// ctx is canceled when the client is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	connMutex sync.RWMutex
+	conn      *websocket.Conn
+	connected bool
+	// connDone is closed when read routine of current connection has stopped
+	connDone chan struct{}
+	connErr  error
+
+	jsonrpcID      int64
+	jsonrpcIDMutex sync.Mutex
+	calls          map[string]RpcCall
+	callsMutex     sync.Mutex
+	eventProcessWg sync.WaitGroup
+	// eventNewBlockHeaderSubscription *EventNewBlockHeaderCallback
+	eventNewBlockSubscribed           bool
+	eventNewBlockSubscribedMutex      sync.Mutex
+	newBlockSubscriptionHandlers      []chan TxResult
+	newBlockSubscriptionHandlersMutex sync.RWMutex
+	send                              chan SendCall
+}
+
+type SendCall struct {
+	callID string
+	method string
+	params *JsonRPCParams
+}
+
+type RpcCall struct {
+	Channel chan RpcCallChannelReturn
+}
+
+type EventNewBlockHeaderCallback struct {
+	Callback func(*EventDataNewBlockHeader)
+}
+
+type EventNewBlockCallback struct {
+	Callback func(*EventDataNewBlock)
+}
+
+type RpcCallChannelReturn struct {
+	Message []byte
+	Error   *ResponseErrorJsonRPC
+}
+
+type Event struct {
+	CallID  string
+	Message []byte
+	Error   *ResponseErrorJsonRPC
+}
+
+func New(logger log.Logger) (tmClient *TmClient, err error) {
+	return NewWithConfig(logger, DefaultConfig())
+}
+
+func NewWithConfig(logger log.Logger, config Config) (tmClient *TmClient, err error) {
+	tmClient = &TmClient{
+		logger: logger.WithFields(log.Fields{
+			"module": "tm_client",
+		}),
+		config: config,
+	}
+
+	tlsConfig, err := config.Connection.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = tlsConfig
+	tmClient.dialer = &dialer
+	tmClient.header = config.Connection.header()
+
+	tmClient.ctx, tmClient.cancel = context.WithCancel(context.Background())
+
+	tmClient.calls = make(map[string]RpcCall)
+	tmClient.jsonrpcID = 1
+
+	tmClient.newBlockSubscriptionHandlers = make([]chan TxResult, 0)
+
+	tmClient.send = make(chan SendCall)
+
+	return tmClient, nil
+}
+
+func (tmClient *TmClient) websocketURL() string {
+	u := url.URL{
+		Scheme: tmClient.config.Connection.websocketScheme(),
+		Host:   tmClient.rpcHost + ":" + tmClient.rpcPort,
+		Path:   "/websocket",
+	}
+	return u.String()
+}
+
+// Connect dials Tendermint RPC websocket. Dialing is aborted when ctx is done.
+func (tmClient *TmClient) Connect(ctx context.Context, rpcHost string, rpcPort string) (*websocket.Conn, error) {
+	tmClient.rpcHost = rpcHost
+	tmClient.rpcPort = rpcPort
+	tmClient.logger.Infof("Connecting to Tendermint RPC websocket at %s", tmClient.websocketURL())
+
+	conn, err := tmClient.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tmClient.logger.Infof("Connected to Tendermint RPC websocket")
+
+	return conn, nil
+}
+
+func (tmClient *TmClient) dial(ctx context.Context) (*websocket.Conn, error) {
+	select {
+	case <-tmClient.ctx.Done():
+		return nil, ErrClientClosed
+	default:
+	}
+
+	conn, resp, err := tmClient.dialer.DialContext(ctx, tmClient.websocketURL(), tmClient.header)
+	if err != nil {
+		if resp != nil {
+			return nil, errors.Wrapf(err, "websocket handshake failed with status %s", resp.Status)
+		}
+		return nil, err
+	}
+
+	connDone := make(chan struct{})
+
+	tmClient.connMutex.Lock()
+	tmClient.conn = conn
+	tmClient.connected = true
+	tmClient.connDone = connDone
+	tmClient.connErr = nil
+	tmClient.connMutex.Unlock()
+
+	go tmClient.readRoutine(conn, connDone)
+	go tmClient.writeRoutine(conn, connDone)
+
+	return conn, nil
+}
+
+func (tmClient *TmClient) reconnect() {
+	backoff := tmClient.config.ReconnectInitialBackoff
+	var err error
+	for attempt := 1; attempt <= tmClient.config.ReconnectMaxAttempts; attempt++ {
+		select {
+		case <-tmClient.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		tmClient.logger.Infof(
+			"Reconnecting to Tendermint RPC websocket at %s (attempt %d/%d)",
+			tmClient.websocketURL(),
+			attempt,
+			tmClient.config.ReconnectMaxAttempts,
+		)
+		_, err = tmClient.dial(tmClient.ctx)
+		if err == nil {
+			tmClient.logger.Infof("Reconnected to Tendermint RPC websocket")
+
+			// Resubscribe to new block event
+			tmClient.newBlockSubscriptionHandlersMutex.RLock()
+			mustResubscribe := len(tmClient.newBlockSubscriptionHandlers) > 0
+			tmClient.newBlockSubscriptionHandlersMutex.RUnlock()
+			if mustResubscribe {
+				_, err = tmClient.subscribeToNewBlockEvents(tmClient.ctx, nil, true)
+				if err != nil {
+					tmClient.logger.Errorf("Error resubscribing to new block event; err: %+v", err)
+				}
+			}
+			return
+		}
+		tmClient.logger.Errorf("Error reconnecting to Tendermint RPC websocket; err: %+v", err)
+
+		backoff *= 2
+		if backoff > tmClient.config.ReconnectMaxBackoff {
+			backoff = tmClient.config.ReconnectMaxBackoff
+		}
+	}
+
+	tmClient.logger.Errorf("Giving up reconnecting to Tendermint RPC websocket after %d attempt(s)", tmClient.config.ReconnectMaxAttempts)
+	if err == nil {
+		err = ErrNotConnected
+	}
+	tmClient.connMutex.Lock()
+	tmClient.connErr = errors.Wrap(err, "reconnect failed")
+	tmClient.connMutex.Unlock()
+}
+
+// Close waits for event processing to finish, then closes the connection
+// cleanly. It returns when the connection is closed or ctx is done.
+func (tmClient *TmClient) Close(ctx context.Context) error {
+	eventProcessDone := make(chan struct{})
+	go func() {
+		tmClient.eventProcessWg.Wait()
+		close(eventProcessDone)
+	}()
+	select {
+	case <-eventProcessDone:
+	case <-ctx.Done():
+	}
+
+	// Stop reconnecting and signal write routine to send close message
+	tmClient.cancel()
+
+	tmClient.connMutex.RLock()
+	conn := tmClient.conn
+	connDone := tmClient.connDone
+	tmClient.connMutex.RUnlock()
+
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	// Wait (with timeout) for the server to close the connection.
+	select {
+	case <-connDone:
+	case <-ctx.Done():
+	case <-time.After(writeWait):
+	}
+	tmClient.failPendingCalls()
+
+	return conn.Close()
+}
+
+// failPendingCalls closes all pending call channels. Callers waiting on them
+// get ErrConnectionClosed.
+func (tmClient *TmClient) failPendingCalls() {
+	tmClient.callsMutex.Lock()
+	defer tmClient.callsMutex.Unlock()
+	for key := range tmClient.calls {
+		close(tmClient.calls[key].Channel)
+		delete(tmClient.calls, key)
+	}
+}
+
+func (tmClient *TmClient) readRoutine(conn *websocket.Conn, connDone chan struct{}) {
+	defer close(connDone)
+	// conn.SetReadLimit(maxMessageSize)
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readWait))
+	})
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(readWait)); err != nil {
+			tmClient.logger.Errorf("Failed to set read deadline: %+v", err)
+		}
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			tmClient.logger.Debugf("Read error: %v", err)
+
+			tmClient.connMutex.Lock()
+			tmClient.connected = false
+			tmClient.connMutex.Unlock()
+			tmClient.eventNewBlockSubscribedMutex.Lock()
+			tmClient.eventNewBlockSubscribed = false
+			tmClient.eventNewBlockSubscribedMutex.Unlock()
+
+			tmClient.failPendingCalls()
+
+			select {
+			case <-tmClient.ctx.Done():
+				// Closed by client
+				return
+			default:
+			}
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure) {
+				tmClient.logger.Errorf("%+v", err)
+			}
+			conn.Close()
+			go tmClient.reconnect()
+			return
+		}
+
+		tmClient.logger.Debugf("readRoutine recv: %s", message)
+
+		var response ResponseJsonRPC
+		err = json.Unmarshal(message, &response)
+		if err != nil {
+			tmClient.logger.Errorf("Parse response JSON error: %+v", err)
+			continue
+		}
+
+		tmClient.callsMutex.Lock()
+		call, exist := tmClient.calls[response.ID]
+		if exist {
+			// Channel is buffered, this never blocks
+			call.Channel <- RpcCallChannelReturn{
+				message,
+				response.Error,
+			}
+			delete(tmClient.calls, response.ID)
+		}
+		tmClient.callsMutex.Unlock()
+
+		if !exist && strings.HasSuffix(response.ID, "#event") {
+			event := Event{
+				response.ID,
+				message,
+				response.Error,
+			}
+			tmClient.eventProcessWg.Add(1)
+			go func() {
+				defer tmClient.eventProcessWg.Done()
+				tmClient.processEventRecv(event)
+			}()
+		}
+	}
+}
+
+func (tmClient *TmClient) writeRoutine(conn *websocket.Conn, connDone chan struct{}) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+	}()
+	for {
+		select {
+		case <-connDone:
+			return
+		case <-tmClient.ctx.Done():
+			// Cleanly close the connection by sending a close message
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			if err != nil {
+				tmClient.logger.Errorf("Websocket write message (close) error: %+v", err)
+			}
+			return
+		case sendCall := <-tmClient.send:
+			var req RequestJsonRPC
+			req.Jsonrpc = "2.0"
+			req.ID = sendCall.callID
+			req.Method = sendCall.method
+			if sendCall.params != nil {
+				req.Params = *sendCall.params
+			}
+
+			tmClient.logger.Debugf("writeRoutine send: %s", req)
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			err := conn.WriteJSON(req)
+			if err != nil {
+				tmClient.logger.Errorf("Websocket write JSON error: %+v", err)
+				// Let read routine detect the broken connection and reconnect
+				conn.Close()
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				tmClient.logger.Errorf("Websocket write message (ping) error: %+v", err)
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (tmClient *TmClient) nextCallID() string {
+	tmClient.jsonrpcIDMutex.Lock()
+	defer tmClient.jsonrpcIDMutex.Unlock()
+	callID := strconv.FormatInt(tmClient.jsonrpcID, 10)
+	tmClient.jsonrpcID++
+	return callID
+}
+
+func (tmClient *TmClient) call(ctx context.Context, method string, params *JsonRPCParams, predefinedCallID string) ([]byte, error) {
+	if tmClient.config.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tmClient.config.CallTimeout)
+		defer cancel()
+	}
+
+	tmClient.connMutex.RLock()
+	conn := tmClient.conn
+	connected := tmClient.connected
+	connErr := tmClient.connErr
+	tmClient.connMutex.RUnlock()
+	if connErr != nil {
+		return nil, connErr
+	}
+	if conn == nil {
+		return nil, ErrNotConnected
+	}
+	if !connected {
+		tmClient.logger.Debugf("Not connected, waiting for reconnect before calling %s", method)
+	}
+
+	callID := predefinedCallID
+	if callID == "" {
+		callID = tmClient.nextCallID()
+	}
+	channel := make(chan RpcCallChannelReturn, 1)
+	tmClient.callsMutex.Lock()
+	tmClient.calls[callID] = RpcCall{
+		Channel: channel,
+	}
+	tmClient.callsMutex.Unlock()
+	removeCall := func() {
+		tmClient.callsMutex.Lock()
+		if call, ok := tmClient.calls[callID]; ok && call.Channel == channel {
+			delete(tmClient.calls, callID)
+		}
+		tmClient.callsMutex.Unlock()
+	}
+
+	sendCall := SendCall{
+		callID: callID,
+		method: method,
+		params: params,
+	}
+	select {
+	case tmClient.send <- sendCall:
+	case <-ctx.Done():
+		removeCall()
+		return nil, errors.Wrapf(ctx.Err(), "%s call not sent", method)
+	case <-tmClient.ctx.Done():
+		removeCall()
+		return nil, ErrClientClosed
+	}
+
+	var channelReturn RpcCallChannelReturn
+	var ok bool
+	select {
+	case channelReturn, ok = <-channel:
+		if !ok {
+			return nil, ErrConnectionClosed
+		}
+	case <-ctx.Done():
+		removeCall()
+		return nil, errors.Wrapf(ctx.Err(), "%s call not finished", method)
+	}
+	if channelReturn.Error != nil {
+		return nil, errors.Wrap(errors.New(channelReturn.Error.Data), "JSON-RPC error")
+	}
+	var objmap map[string]*json.RawMessage
+	err := json.Unmarshal(channelReturn.Message, &objmap)
+	if err != nil {
+		return nil, err
+	}
+	result, ok := objmap["result"]
+	if !ok || result == nil {
+		return nil, errors.New("JSON-RPC response has no result")
+	}
+	return *result, nil
+}
+
+func (tmClient *TmClient) processEventRecv(event Event) {
+	if event.Error != nil {
+		tmClient.logger.Errorf("Event recv error: " + event.Error.Data)
+		return
+	}
+	tmClient.eventNewBlockSubscribedMutex.Lock()
+	eventNewBlockSubscribed := tmClient.eventNewBlockSubscribed
+	tmClient.eventNewBlockSubscribedMutex.Unlock()
+	if strings.HasPrefix(event.CallID, "NewBlock") && eventNewBlockSubscribed {
+		var objmap map[string]*json.RawMessage
+		err := json.Unmarshal(event.Message, &objmap)
+		if err != nil {
+			tmClient.logger.Errorf("%+v", err)
+			return
+		}
+		result, ok := objmap["result"]
+		if !ok || result == nil {
+			tmClient.logger.Errorf("New block event has no result")
+			return
+		}
+		var newBlockEvent EventDataNewBlock
+		err = json.Unmarshal(*result, &newBlockEvent)
+		if err != nil {
+			tmClient.logger.Errorf("%+v", err)
+			return
+		}
+		tmClient.newBlockHandler(newBlockEvent)
+	}
+	// else if strings.HasPrefix(event.CallID, "NewBlockHeader") && tmClient.eventNewBlockHeaderSubscription != nil {
+	// 	var objmap map[string]*json.RawMessage
+	// 	err := json.Unmarshal(event.Message, &objmap)
+	// 	if err != nil {
+	// 		tmClient.logger.Error(err)
+	// 	}
+	// 	var newBlockHeaderEvent EventDataNewBlockHeader
+	// 	err = json.Unmarshal(*objmap["result"], &newBlockHeaderEvent)
+	// 	if err != nil {
+	// 		tmClient.logger.Error(err)
+	// 	}
+	// 	tmClient.eventNewBlockHeaderSubscription.Callback(&newBlockHeaderEvent)
+	// }
+}
+
+func (tmClient *TmClient) Status(ctx context.Context) (status *ResponseStatus, err error) {
+	res, err := tmClient.call(ctx, "status", nil, "")
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(res, &status)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (tmClient *TmClient) NetInfo(ctx context.Context) (netInfo *ResponseNetInfo, err error) {
+	res, err := tmClient.call(ctx, "net_info", nil, "")
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(res, &netInfo)
+	if err != nil {
+		return nil, err
+	}
+	return netInfo, nil
+}
+
+func (tmClient *TmClient) Block(ctx context.Context, height int) (block *ResponseBlock, err error) {
+	res, err := tmClient.call(ctx, "block", &JsonRPCParams{
+		Height: strconv.Itoa(height),
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(res, &block)
+	if err != nil {
+		return nil, err
+	}
+	if tmClient.config.TendermintVersion == TendermintVersion0_32 {
+		block.BlockID = block.BlockMeta.BlockID
+	}
+	return block, nil
+}
+
+func (tmClient *TmClient) BlockResults(ctx context.Context, height int) (blockResults *ResponseBlockResults, err error) {
+	res, err := tmClient.call(ctx, "block_results", &JsonRPCParams{
+		Height: strconv.Itoa(height),
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(res, &blockResults)
+	if err != nil {
+		return nil, err
+	}
+	if tmClient.config.TendermintVersion == TendermintVersion0_32 && blockResults.Results != nil {
+		blockResults.TxsResults = blockResults.Results.DeliverTx
+	}
+	return blockResults, nil
+}
+
+func (tmClient *TmClient) Query(ctx context.Context, data []byte) (queryRes *ResponseQuery, err error) {
+	tmClient.logger.Debugf("query")
+	res, err := tmClient.call(ctx, "abci_query", &JsonRPCParams{
+		Path:   base64.StdEncoding.EncodeToString([]byte("")),
+		Data:   hex.EncodeToString(data),
+		Height: "0",
+	}, "")
+	if err != nil {
+		tmClient.logger.Errorf("Failed to call: %+v", err)
+		return nil, err
+	}
+	err = json.Unmarshal(res, &queryRes)
+	if err != nil {
+		return nil, err
+	}
+	return queryRes, nil
+}
+
+func (tmClient *TmClient) BroadcastTxCommit(ctx context.Context, tx []byte) (broadcastTxCommitResult *ResponseBroadcastTxCommit, err error) {
+	tmClient.logger.Debugf("broadcast tx commit")
+	res, err := tmClient.call(ctx, "broadcast_tx_commit", &JsonRPCParams{
+		Tx: base64.StdEncoding.EncodeToString(tx),
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(res, &broadcastTxCommitResult)
+	if err != nil {
+		return nil, err
+	}
+	return broadcastTxCommitResult, nil
+}
+
+func (tmClient *TmClient) BroadcastTxSync(ctx context.Context, tx []byte) (broadcastTxSyncResult *ResponseBroadcastTxSync, err error) {
+	tmClient.logger.Debugf("broadcast tx sync")
+	res, err := tmClient.call(ctx, "broadcast_tx_sync", &JsonRPCParams{
+		Tx: base64.StdEncoding.EncodeToString(tx),
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(res, &broadcastTxSyncResult)
+	if err != nil {
+		return nil, err
+	}
+	return broadcastTxSyncResult, nil
+}
+
+// func (tmClient *TmClient) SubscribeToNewBlockHeaderEvents(callback func(*EventDataNewBlockHeader)) (subscribeResult *ResponseSubscribe, err error) {
+// 	tmClient.logger.Debugf("subscribe to new block header event")
+// 	if tmClient.eventNewBlockHeaderSubscription != nil {
+// 		return nil, errors.New("Already subscribed")
+// 	}
+// 	res, err := tmClient.call("subscribe", &JsonRPCParams{
+// 		Query: "tm.event = 'NewBlockHeader'",
+// 	}, "NewBlockHeader")
+// 	if err != nil {
+// 		return nil, err
+// 	}
+// 	err = json.Unmarshal(res, &subscribeResult)
+// 	if err != nil {
+// 		return nil, err
+// 	}
+// 	tmClient.eventNewBlockHeaderSubscription = &EventNewBlockHeaderCallback{
+// 		Callback: callback,
+// 	}
+// 	return subscribeResult, nil
+// }
+
+func (tmClient *TmClient) SubscribeToNewBlockEvents(ctx context.Context, handler chan TxResult) (subscribeResult *ResponseSubscribe, err error) {
+	return tmClient.subscribeToNewBlockEvents(ctx, handler, false)
+}
+
+func (tmClient *TmClient) subscribeToNewBlockEvents(ctx context.Context, handler chan TxResult, subscribeOnReconnect bool) (subscribeResult *ResponseSubscribe, err error) {
+	tmClient.logger.Debugf("subscribe to new block event")
+	tmClient.eventNewBlockSubscribedMutex.Lock()
+	eventNewBlockSubscribed := tmClient.eventNewBlockSubscribed
+	tmClient.eventNewBlockSubscribedMutex.Unlock()
+	if !eventNewBlockSubscribed {
+		res, err := tmClient.call(ctx, "subscribe", &JsonRPCParams{
+			Query: "tm.event = 'NewBlock'",
+		}, "NewBlock#event")
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(res, &subscribeResult)
+		if err != nil {
+			return nil, err
+		}
+		tmClient.eventNewBlockSubscribedMutex.Lock()
+		tmClient.eventNewBlockSubscribed = true
+		tmClient.eventNewBlockSubscribedMutex.Unlock()
+	}
+
+	if handler != nil && !subscribeOnReconnect {
+		tmClient.newBlockSubscriptionHandlersMutex.Lock()
+		tmClient.newBlockSubscriptionHandlers = append(tmClient.newBlockSubscriptionHandlers, handler)
+		tmClient.newBlockSubscriptionHandlersMutex.Unlock()
+	}
+
+	return subscribeResult, nil
+}
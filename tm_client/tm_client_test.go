@@ -0,0 +1,468 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package tm_client
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+
+	"github.com/ndidplatform/migration-tools/log"
+)
+
+// rpcStub is in-process Tendermint RPC websocket stub. handle is called for
+// every JSON-RPC request received.
+type rpcStub struct {
+	t      *testing.T
+	server *httptest.Server
+	handle func(conn *stubConn, request RequestJsonRPC)
+
+	mutex     sync.Mutex
+	refuse    bool
+	dialTimes []time.Time
+	conns     []*stubConn
+}
+
+type stubConn struct {
+	conn       *websocket.Conn
+	writeMutex sync.Mutex
+}
+
+func (c *stubConn) writeJSON(v interface{}) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	return c.conn.WriteJSON(v)
+}
+
+func (c *stubConn) result(id string, result interface{}) error {
+	return c.writeJSON(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"result":  result,
+	})
+}
+
+func newRPCStub(t *testing.T, handle func(conn *stubConn, request RequestJsonRPC)) *rpcStub {
+	stub := &rpcStub{t: t, handle: handle}
+	upgrader := websocket.Upgrader{}
+	stub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.mutex.Lock()
+		stub.dialTimes = append(stub.dialTimes, time.Now())
+		refuse := stub.refuse
+		stub.mutex.Unlock()
+		if refuse {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		c := &stubConn{conn: conn}
+		stub.mutex.Lock()
+		stub.conns = append(stub.conns, c)
+		stub.mutex.Unlock()
+		defer conn.Close()
+		for {
+			var request RequestJsonRPC
+			if err := conn.ReadJSON(&request); err != nil {
+				return
+			}
+			stub.handle(c, request)
+		}
+	}))
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+func (stub *rpcStub) setRefuse(refuse bool) {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	stub.refuse = refuse
+}
+
+func (stub *rpcStub) getDialTimes() []time.Time {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	return append([]time.Time{}, stub.dialTimes...)
+}
+
+// dropConnections closes connections without close handshake
+func (stub *rpcStub) dropConnections() {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	for _, c := range stub.conns {
+		c.conn.Close()
+	}
+	stub.conns = nil
+}
+
+func (stub *rpcStub) connect(t *testing.T, config Config) *TmClient {
+	logger, err := log.NewLogger(&log.Configuration{
+		EnableConsole: true,
+		ConsoleLevel:  "error",
+	}, log.InstanceGoLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmClient, err := NewWithConfig(logger, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(stub.server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tmClient.Connect(context.Background(), host, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		tmClient.Close(ctx)
+	})
+	return tmClient
+}
+
+func testConfig() Config {
+	config := DefaultConfig()
+	config.CallTimeout = 2 * time.Second
+	config.ReconnectInitialBackoff = 10 * time.Millisecond
+	config.ReconnectMaxBackoff = 20 * time.Millisecond
+	config.ReconnectMaxAttempts = 3
+	return config
+}
+
+func statusResult(network string) map[string]interface{} {
+	return map[string]interface{}{
+		"node_info": map[string]interface{}{"network": network},
+	}
+}
+
+// waitFor polls condition until it is true or timeout
+func waitFor(t *testing.T, timeout time.Duration, description string, condition func() bool) {
+	deadline := time.Now().Add(timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", description)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (tmClient *TmClient) isConnected() bool {
+	tmClient.connMutex.RLock()
+	defer tmClient.connMutex.RUnlock()
+	return tmClient.connected
+}
+
+func (tmClient *TmClient) pendingCallCount() int {
+	tmClient.callsMutex.Lock()
+	defer tmClient.callsMutex.Unlock()
+	return len(tmClient.calls)
+}
+
+func TestCall(t *testing.T) {
+	stub := newRPCStub(t, func(conn *stubConn, request RequestJsonRPC) {
+		conn.result(request.ID, statusResult("test-chain"))
+	})
+	tmClient := stub.connect(t, testConfig())
+
+	status, err := tmClient.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.NodeInfo.Network != "test-chain" {
+		t.Errorf("network: %q, expected %q", status.NodeInfo.Network, "test-chain")
+	}
+	if count := tmClient.pendingCallCount(); count != 0 {
+		t.Errorf("pending call count: %d, expected 0", count)
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	// No response
+	stub := newRPCStub(t, func(conn *stubConn, request RequestJsonRPC) {})
+	config := testConfig()
+	config.CallTimeout = 50 * time.Millisecond
+	tmClient := stub.connect(t, config)
+
+	startTime := time.Now()
+	_, err := tmClient.Status(context.Background())
+	if errors.Cause(err) != context.DeadlineExceeded {
+		t.Fatalf("error: %v, expected %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(startTime); elapsed > time.Second {
+		t.Errorf("call returned after %v, expected about %v", elapsed, config.CallTimeout)
+	}
+	if count := tmClient.pendingCallCount(); count != 0 {
+		t.Errorf("pending call count: %d, expected 0", count)
+	}
+}
+
+func TestCallContextCanceled(t *testing.T) {
+	stub := newRPCStub(t, func(conn *stubConn, request RequestJsonRPC) {})
+	tmClient := stub.connect(t, testConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err := tmClient.Status(ctx)
+	if errors.Cause(err) != context.Canceled {
+		t.Fatalf("error: %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestConnectionDroppedDuringCall(t *testing.T) {
+	var stub *rpcStub
+	var dropOnce sync.Once
+	stub = newRPCStub(t, func(conn *stubConn, request RequestJsonRPC) {
+		dropped := false
+		dropOnce.Do(func() {
+			// Drop connection of the first call without responding
+			stub.dropConnections()
+			dropped = true
+		})
+		if !dropped {
+			conn.result(request.ID, statusResult("test-chain"))
+		}
+	})
+	tmClient := stub.connect(t, testConfig())
+
+	_, err := tmClient.Status(context.Background())
+	if err != ErrConnectionClosed {
+		t.Fatalf("error: %v, expected %v", err, ErrConnectionClosed)
+	}
+	if count := tmClient.pendingCallCount(); count != 0 {
+		t.Errorf("pending call count: %d, expected 0", count)
+	}
+
+	// Reconnected
+	waitFor(t, 2*time.Second, "reconnect", func() bool {
+		return len(stub.getDialTimes()) == 2 && tmClient.isConnected()
+	})
+	status, err := tmClient.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.NodeInfo.Network != "test-chain" {
+		t.Errorf("network: %q, expected %q", status.NodeInfo.Network, "test-chain")
+	}
+}
+
+func TestReconnectGiveUp(t *testing.T) {
+	stub := newRPCStub(t, func(conn *stubConn, request RequestJsonRPC) {
+		conn.result(request.ID, statusResult("test-chain"))
+	})
+	config := testConfig()
+	config.ReconnectInitialBackoff = 30 * time.Millisecond
+	config.ReconnectMaxBackoff = 60 * time.Millisecond
+	config.ReconnectMaxAttempts = 4
+	tmClient := stub.connect(t, config)
+
+	stub.setRefuse(true)
+	stub.dropConnections()
+
+	waitFor(t, 3*time.Second, "giving up reconnect", func() bool {
+		tmClient.connMutex.RLock()
+		defer tmClient.connMutex.RUnlock()
+		return tmClient.connErr != nil
+	})
+
+	// Initial connection and ReconnectMaxAttempts attempts
+	dialTimes := stub.getDialTimes()
+	if len(dialTimes) != 1+config.ReconnectMaxAttempts {
+		t.Fatalf("dial count: %d, expected %d", len(dialTimes), 1+config.ReconnectMaxAttempts)
+	}
+	// Backoff is doubled up to ReconnectMaxBackoff: 30ms, 60ms, 60ms
+	expectedBackoffs := []time.Duration{60 * time.Millisecond, 60 * time.Millisecond, 60 * time.Millisecond}
+	for i, expectedBackoff := range expectedBackoffs {
+		gap := dialTimes[i+2].Sub(dialTimes[i+1])
+		if gap < expectedBackoff {
+			t.Errorf("delay before attempt %d: %v, expected at least %v", i+2, gap, expectedBackoff)
+		}
+		if gap >= 2*expectedBackoff {
+			t.Errorf("delay before attempt %d: %v, expected less than %v", i+2, gap, 2*expectedBackoff)
+		}
+	}
+
+	_, err := tmClient.Status(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "reconnect failed") {
+		t.Fatalf("error: %v, expected reconnect failed", err)
+	}
+
+	// No more attempts after giving up
+	time.Sleep(3 * config.ReconnectMaxBackoff)
+	if dialCount := len(stub.getDialTimes()); dialCount != len(dialTimes) {
+		t.Errorf("dial count after giving up: %d, expected %d", dialCount, len(dialTimes))
+	}
+}
+
+func TestReconnectDisabled(t *testing.T) {
+	stub := newRPCStub(t, func(conn *stubConn, request RequestJsonRPC) {})
+	config := testConfig()
+	config.ReconnectMaxAttempts = 0
+	tmClient := stub.connect(t, config)
+
+	stub.dropConnections()
+
+	waitFor(t, time.Second, "disconnect", func() bool {
+		tmClient.connMutex.RLock()
+		defer tmClient.connMutex.RUnlock()
+		return tmClient.connErr != nil
+	})
+	if dialCount := len(stub.getDialTimes()); dialCount != 1 {
+		t.Errorf("dial count: %d, expected 1", dialCount)
+	}
+}
+
+func TestCloseFailsPendingCalls(t *testing.T) {
+	received := make(chan struct{}, 1)
+	stub := newRPCStub(t, func(conn *stubConn, request RequestJsonRPC) {
+		received <- struct{}{}
+	})
+	tmClient := stub.connect(t, testConfig())
+
+	callErr := make(chan error, 1)
+	go func() {
+		_, err := tmClient.Status(context.Background())
+		callErr <- err
+	}()
+	<-received
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	tmClient.Close(ctx)
+
+	select {
+	case err := <-callErr:
+		if err != ErrConnectionClosed {
+			t.Errorf("error: %v, expected %v", err, ErrConnectionClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending call is not failed on close")
+	}
+
+	_, err := tmClient.Status(context.Background())
+	if err != ErrClientClosed {
+		t.Errorf("error after close: %v, expected %v", err, ErrClientClosed)
+	}
+}
+
+func newBlockEvent(height string, txs ...[]byte) map[string]interface{} {
+	encodedTxs := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		encodedTxs = append(encodedTxs, base64.StdEncoding.EncodeToString(tx))
+	}
+	return map[string]interface{}{
+		"query": "tm.event = 'NewBlock'",
+		"data": map[string]interface{}{
+			"type": "tendermint/event/NewBlock",
+			"value": map[string]interface{}{
+				"block": map[string]interface{}{
+					"header": map[string]interface{}{"height": height},
+					"data":   map[string]interface{}{"txs": encodedTxs},
+				},
+			},
+		},
+	}
+}
+
+func TestNewBlockHandler(t *testing.T) {
+	// Block results of height 5 have less results than txs in block
+	txsResultCounts := map[string]int{"5": 1, "6": 1}
+	stub := newRPCStub(t, func(conn *stubConn, request RequestJsonRPC) {
+		switch request.Method {
+		case "subscribe":
+			conn.result(request.ID, map[string]interface{}{})
+		case "block_results":
+			txsResults := make([]interface{}, 0)
+			for i := 0; i < txsResultCounts[request.Params.Height]; i++ {
+				txsResults = append(txsResults, map[string]interface{}{"log": "height " + request.Params.Height})
+			}
+			conn.result(request.ID, map[string]interface{}{
+				"height":      request.Params.Height,
+				"txs_results": txsResults,
+			})
+		}
+	})
+	tmClient := stub.connect(t, testConfig())
+
+	handler := make(chan TxResult, 10)
+	_, err := tmClient.SubscribeToNewBlockEvents(context.Background(), handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stub.mutex.Lock()
+	conn := stub.conns[0]
+	stub.mutex.Unlock()
+	for _, event := range []map[string]interface{}{
+		newBlockEvent("5", []byte("tx5a"), []byte("tx5b")),
+		newBlockEvent("6", []byte("tx6")),
+	} {
+		err = conn.result("NewBlock#event", event)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case txResult := <-handler:
+		if string(txResult.Tx) != "tx6" || txResult.DeliverTxResult.Log != "height 6" {
+			t.Errorf("tx result: %s %+v, expected tx6 of height 6", txResult.Tx, txResult.DeliverTxResult)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("tx result of height 6 not received")
+	}
+	select {
+	case txResult := <-handler:
+		t.Errorf("unexpected tx result: %s %+v", txResult.Tx, txResult.DeliverTxResult)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	stub := newRPCStub(t, func(conn *stubConn, request RequestJsonRPC) {
+		conn.writeJSON(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      request.ID,
+			"error":   map[string]interface{}{"code": -32603, "message": "Internal error", "data": "height must be greater than 0"},
+		})
+	})
+	tmClient := stub.connect(t, testConfig())
+
+	_, err := tmClient.Block(context.Background(), 0)
+	if err == nil || !strings.Contains(err.Error(), "height must be greater than 0") {
+		t.Fatalf("error: %v, expected JSON-RPC error", err)
+	}
+}
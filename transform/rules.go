@@ -0,0 +1,149 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/ndidplatform/migration-tools/statedata"
+	"github.com/ndidplatform/migration-tools/utils"
+)
+
+// Actions of Rule
+const (
+	ActionSet    = "set"
+	ActionDelete = "delete"
+	ActionRename = "rename"
+	ActionFilter = "filter"
+)
+
+// Rule is applied to key-value written by conversion of ABCI version in
+// Versions (target version only when empty) with key matching Match.
+//   - set: set fields of decoded value (dotted path for nested field)
+//   - delete: do not write key
+//   - rename: replace Match.KeyPrefix of key with RenameTo
+//   - filter: remove items of list field which match any of Filter.Remove
+//     (equal value, or all given fields equal for message items)
+type Rule struct {
+	Name     string                 `yaml:"name"`
+	Versions []int                  `yaml:"versions"`
+	Match    RuleMatch              `yaml:"match"`
+	Action   string                 `yaml:"action"`
+	Set      map[string]interface{} `yaml:"set"`
+	RenameTo string                 `yaml:"rename_to"`
+	Filter   *RuleFilter            `yaml:"filter"`
+}
+
+// RuleMatch matches key (without "kvPairKey:" prefix) with KeyPrefix and
+// fields of decoded protobuf value with Fields. Field values are compared as
+// strings.
+type RuleMatch struct {
+	KeyPrefix string                 `yaml:"key_prefix"`
+	Fields    map[string]interface{} `yaml:"fields"`
+}
+
+type RuleFilter struct {
+	Field  string        `yaml:"field"`
+	Remove []interface{} `yaml:"remove"`
+}
+
+type RulesFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// LoadRules reads and validates rules from YAML file
+func LoadRules(filePath string) (rules []Rule, err error) {
+	fileBytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var rulesFile RulesFile
+	err = yaml.UnmarshalStrict(fileBytes, &rulesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rulesFile.Rules {
+		rule := &rulesFile.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		err = rule.validate()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", rule.Name, err)
+		}
+		rule.Set = utils.NormalizeYAMLMap(rule.Set)
+		rule.Match.Fields = utils.NormalizeYAMLMap(rule.Match.Fields)
+		if rule.Filter != nil {
+			for j, item := range rule.Filter.Remove {
+				rule.Filter.Remove[j] = utils.NormalizeYAML(item)
+			}
+		}
+	}
+
+	return rulesFile.Rules, nil
+}
+
+func (rule *Rule) validate() error {
+	if rule.Match.KeyPrefix == "" {
+		return errors.New("match.key_prefix is required")
+	}
+	switch rule.Action {
+	case ActionSet:
+		if len(rule.Set) == 0 {
+			return errors.New("set is required for set action")
+		}
+	case ActionDelete:
+	case ActionRename:
+		if rule.RenameTo == "" {
+			return errors.New("rename_to is required for rename action")
+		}
+		// Renamed key would match again at next conversion step
+		if len(rule.Versions) > 1 && strings.HasPrefix(rule.RenameTo, rule.Match.KeyPrefix) {
+			return errors.New("rename_to starting with match.key_prefix must not apply to more than one version")
+		}
+	case ActionFilter:
+		if rule.Filter == nil || rule.Filter.Field == "" || len(rule.Filter.Remove) == 0 {
+			return errors.New("filter.field and filter.remove are required for filter action")
+		}
+	default:
+		return fmt.Errorf("unknown action: %q", rule.Action)
+	}
+	return nil
+}
+
+func (rule *Rule) appliesToVersion(abciVersion int, targetVersion int) bool {
+	if len(rule.Versions) == 0 {
+		return statedata.SameStateDataVersion(targetVersion, abciVersion)
+	}
+	for _, version := range rule.Versions {
+		if statedata.SameStateDataVersion(version, abciVersion) {
+			return true
+		}
+	}
+	return false
+}
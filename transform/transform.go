@@ -0,0 +1,319 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ndidplatform/migration-tools/statedata"
+	"github.com/ndidplatform/migration-tools/utils"
+)
+
+type AuditEntry struct {
+	Time        time.Time       `json:"time"`
+	Rule        string          `json:"rule"`
+	Action      string          `json:"action"`
+	ABCIVersion int             `json:"abci_version"`
+	Key         string          `json:"key"`
+	NewKey      string          `json:"new_key,omitempty"`
+	OldValue    json.RawMessage `json:"old_value,omitempty"`
+	NewValue    json.RawMessage `json:"new_value,omitempty"`
+	DryRun      bool            `json:"dry_run"`
+}
+
+// Transformer applies rules to key-values written by conversion and logs
+// every applied rule to audit file. In dry run, applied rules are logged but
+// key-values are written unchanged. Rules without versions are applied only
+// to key-values of target ABCI version (last conversion step).
+type Transformer struct {
+	rules          []Rule
+	targetVersion  int
+	dryRun         bool
+	auditFile      *os.File
+	appliedByRules map[string]int64
+}
+
+func NewTransformer(rules []Rule, targetVersion int, auditFilePath string, dryRun bool) (transformer *Transformer, err error) {
+	auditFile, err := utils.OpenFileForAppend(auditFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return &Transformer{
+		rules:          rules,
+		targetVersion:  targetVersion,
+		dryRun:         dryRun,
+		auditFile:      auditFile,
+		appliedByRules: make(map[string]int64),
+	}, nil
+}
+
+// WrapSaveKeyValue returns saveKeyValue which applies rules of given ABCI
+// version before saving. saveKeyValue is returned as is when transformer is
+// nil.
+func (t *Transformer) WrapSaveKeyValue(
+	abciVersion int,
+	saveKeyValue func(key []byte, value []byte) (err error),
+) func(key []byte, value []byte) (err error) {
+	if t == nil {
+		return saveKeyValue
+	}
+	return func(key []byte, value []byte) (err error) {
+		newKey, newValue, err := t.Apply(abciVersion, key, value)
+		if err != nil {
+			return err
+		}
+		if t.dryRun {
+			return saveKeyValue(key, value)
+		}
+		if newKey == nil {
+			return nil
+		}
+		return saveKeyValue(newKey, newValue)
+	}
+}
+
+// Apply applies rules in order to key-value. Returned key is nil when
+// key is deleted.
+func (t *Transformer) Apply(abciVersion int, key []byte, value []byte) (newKey []byte, newValue []byte, err error) {
+	newKey = key
+	newValue = value
+	for i := range t.rules {
+		rule := &t.rules[i]
+		if !rule.appliesToVersion(abciVersion, t.targetVersion) {
+			continue
+		}
+		matchKey := string(statedata.StripKvPairPrefix(newKey))
+		if !strings.HasPrefix(matchKey, rule.Match.KeyPrefix) {
+			continue
+		}
+
+		var decoded map[string]interface{}
+		if len(rule.Match.Fields) > 0 || rule.Action == ActionSet || rule.Action == ActionFilter {
+			decoded, err = decodeValue(abciVersion, newKey, newValue)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: key: %s: %v", rule.Name, matchKey, err)
+			}
+			if !matchFields(decoded, rule.Match.Fields) {
+				continue
+			}
+		}
+
+		auditEntry := AuditEntry{
+			Time:        time.Now(),
+			Rule:        rule.Name,
+			Action:      rule.Action,
+			ABCIVersion: abciVersion,
+			Key:         matchKey,
+			OldValue:    statedata.DecodeValue(abciVersion, newKey, newValue).Value,
+			DryRun:      t.dryRun,
+		}
+
+		switch rule.Action {
+		case ActionDelete:
+			newKey = nil
+			newValue = nil
+		case ActionRename:
+			newKey = []byte(rule.RenameTo + strings.TrimPrefix(matchKey, rule.Match.KeyPrefix))
+			auditEntry.NewKey = string(newKey)
+		case ActionSet:
+			for fieldPath, fieldValue := range rule.Set {
+				setPath(decoded, fieldPath, fieldValue)
+			}
+			newValue, err = encodeValue(abciVersion, newKey, decoded)
+		case ActionFilter:
+			if filterList(decoded, rule.Filter) == 0 {
+				continue
+			}
+			newValue, err = encodeValue(abciVersion, newKey, decoded)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: key: %s: %v", rule.Name, matchKey, err)
+		}
+		if newKey != nil {
+			auditEntry.NewValue = statedata.DecodeValue(abciVersion, newKey, newValue).Value
+		}
+
+		err = t.writeAudit(&auditEntry)
+		if err != nil {
+			return nil, nil, err
+		}
+		t.appliedByRules[rule.Name]++
+
+		if newKey == nil {
+			break
+		}
+	}
+	return newKey, newValue, nil
+}
+
+func (t *Transformer) writeAudit(auditEntry *AuditEntry) error {
+	auditEntryJSON, err := json.Marshal(auditEntry)
+	if err != nil {
+		return err
+	}
+	return utils.AppendLineToOpenedFile(t.auditFile, auditEntryJSON)
+}
+
+// Close logs applied count of each rule and closes audit file
+func (t *Transformer) Close() error {
+	if t == nil {
+		return nil
+	}
+	for _, rule := range t.rules {
+		log.Println("transform rule:", rule.Name, "applied:", t.appliedByRules[rule.Name], "dry run:", t.dryRun)
+	}
+	return t.auditFile.Close()
+}
+
+// decodeValue decodes protobuf value to JSON object with proto field names
+func decodeValue(abciVersion int, key []byte, value []byte) (decoded map[string]interface{}, err error) {
+	message, err := statedata.UnmarshalValue(abciVersion, key, value)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil {
+		return nil, fmt.Errorf("value is not a known protobuf message")
+	}
+	valueJSON, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(valueJSON, &decoded)
+	if err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+func encodeValue(abciVersion int, key []byte, decoded map[string]interface{}) (value []byte, err error) {
+	messageName := statedata.MessageName(abciVersion, key)
+	if messageName == "" {
+		return nil, fmt.Errorf("value is not a known protobuf message")
+	}
+	message, err := statedata.NewMessage(messageName)
+	if err != nil {
+		return nil, err
+	}
+	valueJSON, err := json.Marshal(decoded)
+	if err != nil {
+		return nil, err
+	}
+	err = protojson.Unmarshal(valueJSON, message)
+	if err != nil {
+		return nil, err
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(message)
+}
+
+func getPath(decoded map[string]interface{}, fieldPath string) (value interface{}, ok bool) {
+	var current interface{} = decoded
+	for _, name := range strings.Split(fieldPath, ".") {
+		currentMap, isMap := current.(map[string]interface{})
+		if !isMap {
+			return nil, false
+		}
+		current, ok = currentMap[name]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func setPath(decoded map[string]interface{}, fieldPath string, value interface{}) {
+	names := strings.Split(fieldPath, ".")
+	current := decoded
+	for _, name := range names[:len(names)-1] {
+		next, isMap := current[name].(map[string]interface{})
+		if !isMap {
+			next = make(map[string]interface{})
+			current[name] = next
+		}
+		current = next
+	}
+	current[names[len(names)-1]] = value
+}
+
+func matchFields(decoded map[string]interface{}, fields map[string]interface{}) bool {
+	for fieldPath, expected := range fields {
+		actual, ok := getPath(decoded, fieldPath)
+		if !ok || !equalValue(actual, expected) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalValue compares scalar values as strings (protobuf JSON encodes int64
+// as string)
+func equalValue(actual interface{}, expected interface{}) bool {
+	return fmt.Sprint(actual) == fmt.Sprint(expected)
+}
+
+// filterList removes matching items of list field and returns removed count
+func filterList(decoded map[string]interface{}, filter *RuleFilter) (removed int) {
+	value, ok := getPath(decoded, filter.Field)
+	if !ok {
+		return 0
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return 0
+	}
+	kept := make([]interface{}, 0, len(list))
+	for _, item := range list {
+		if matchAnyItem(item, filter.Remove) {
+			removed++
+			continue
+		}
+		kept = append(kept, item)
+	}
+	if removed > 0 {
+		setPath(decoded, filter.Field, kept)
+	}
+	return removed
+}
+
+func matchAnyItem(item interface{}, patterns []interface{}) bool {
+	for _, pattern := range patterns {
+		patternFields, isMap := pattern.(map[string]interface{})
+		if isMap {
+			itemMap, itemIsMap := item.(map[string]interface{})
+			if itemIsMap && matchFields(itemMap, patternFields) {
+				return true
+			}
+			continue
+		}
+		if equalValue(item, pattern) {
+			return true
+		}
+	}
+	return false
+}
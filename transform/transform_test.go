@@ -0,0 +1,88 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+package transform
+
+import (
+	"path"
+	"testing"
+)
+
+func TestRuleWithoutVersionsAppliedAtTargetVersionOnly(t *testing.T) {
+	rules := []Rule{
+		{
+			Name:     "rename",
+			Match:    RuleMatch{KeyPrefix: "Token"},
+			Action:   ActionRename,
+			RenameTo: "TokenOld",
+		},
+	}
+	transformer, err := NewTransformer(rules, 9, path.Join(t.TempDir(), "audit.log"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Conversion 7 -> 8 -> 9 writes key at each step
+	key := []byte("Token|node1")
+	for _, abciVersion := range []int{8, 9} {
+		key, _, err = transformer.Apply(abciVersion, key, []byte{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(key) != "TokenOld|node1" {
+		t.Errorf("key: %s, expected: TokenOld|node1", key)
+	}
+	if transformer.appliedByRules["rename"] != 1 {
+		t.Errorf("applied: %d, expected: 1", transformer.appliedByRules["rename"])
+	}
+
+	err = transformer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRuleWithVersions(t *testing.T) {
+	rule := Rule{Versions: []int{7}}
+	if !rule.appliesToVersion(8, 9) {
+		t.Error("rule of version 7 should apply to version 8")
+	}
+	if rule.appliesToVersion(9, 9) {
+		t.Error("rule of version 7 should not apply to version 9")
+	}
+}
+
+func TestValidateRepeatedRename(t *testing.T) {
+	rule := Rule{
+		Versions: []int{8, 9},
+		Match:    RuleMatch{KeyPrefix: "Token"},
+		Action:   ActionRename,
+		RenameTo: "TokenOld",
+	}
+	if rule.validate() == nil {
+		t.Error("expected error of rename re-applied at each version")
+	}
+	rule.RenameTo = "OldToken"
+	if err := rule.validate(); err != nil {
+		t.Error(err)
+	}
+}
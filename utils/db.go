@@ -0,0 +1,98 @@
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// ErrDBLocked is returned when a source database is locked by another process
+// (e.g. a running node)
+var ErrDBLocked = errors.New("database is locked by another process")
+
+// OpenDBReadOnly opens an existing source database without write access.
+// The database is never created, compacted or otherwise modified.
+// CLevelDB data is opened with GoLevelDB since both use the same on-disk format.
+// Caller must close the returned DB.
+func OpenDBReadOnly(name string, backend dbm.BackendType, dir string) (db dbm.DB, err error) {
+	dbPath := filepath.Join(dir, name+".db")
+
+	switch backend {
+	case dbm.GoLevelDBBackend, dbm.CLevelDBBackend:
+	default:
+		return nil, fmt.Errorf("opening %s database read-only is not supported (%s)", backend, dbPath)
+	}
+
+	if _, err := os.Stat(filepath.Join(dbPath, "CURRENT")); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("database not found: %s", dbPath)
+		}
+		return nil, err
+	}
+
+	// C LevelDB locks with fcntl while GoLevelDB locks with flock.
+	// Check fcntl lock first since it is not visible to flock on most platforms.
+	err = CheckDBNotLocked(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err = dbm.NewGoLevelDBWithOpts(name, dir, &opt.Options{
+		ReadOnly:       true,
+		ErrorIfMissing: true,
+	})
+	if err != nil {
+		if isLockError(err) {
+			return nil, newDBLockedError(dbPath)
+		}
+		return nil, err
+	}
+	return db, nil
+}
+
+// CheckDBNotLocked returns ErrDBLocked if LevelDB at dbPath is opened by
+// another process
+func CheckDBNotLocked(dbPath string) (err error) {
+	locked, err := isLevelDBLocked(dbPath)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return newDBLockedError(dbPath)
+	}
+	return nil
+}
+
+func newDBLockedError(dbPath string) error {
+	return fmt.Errorf(
+		"%w: %s (stop the node or copy data directory before running migration)",
+		ErrDBLocked,
+		dbPath,
+	)
+}
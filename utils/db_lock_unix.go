@@ -0,0 +1,80 @@
+//go:build !windows
+// +build !windows
+
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package utils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// isLevelDBLocked checks whether LOCK file of LevelDB at dbPath has a POSIX
+// (fcntl, C LevelDB) or BSD (flock, GoLevelDB) write lock held by another
+// process. Locks taken for checking are released right away.
+func isLevelDBLocked(dbPath string) (locked bool, err error) {
+	f, err := os.Open(filepath.Join(dbPath, "LOCK"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	lock := syscall.Flock_t{
+		Type:   syscall.F_RDLCK,
+		Whence: io.SeekStart,
+	}
+	err = syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &lock)
+	if err != nil {
+		if isLockError(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	lock.Type = syscall.F_UNLCK
+	err = syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &lock)
+	if err != nil {
+		return false, err
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB)
+	if err != nil {
+		if isLockError(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+func isLockError(err error) bool {
+	return errors.Is(err, syscall.EWOULDBLOCK) ||
+		errors.Is(err, syscall.EAGAIN) ||
+		errors.Is(err, syscall.EACCES)
+}
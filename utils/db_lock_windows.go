@@ -0,0 +1,45 @@
+//go:build windows
+// +build windows
+
+/**
+ * Copyright (c) 2018, 2019 National Digital ID COMPANY LIMITED
+ *
+ * This file is part of NDID software.
+ *
+ * NDID is the free software: you can redistribute it and/or modify it under
+ * the terms of the Affero GNU General Public License as published by the
+ * Free Software Foundation, either version 3 of the License, or any later
+ * version.
+ *
+ * NDID is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+ * See the Affero GNU General Public License for more details.
+ *
+ * You should have received a copy of the Affero GNU General Public License
+ * along with the NDID source code. If not, see https://www.gnu.org/licenses/agpl.txt.
+ *
+ * Please contact info@ndid.co.th for any further questions
+ *
+ */
+
+package utils
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isLevelDBLocked always returns false on Windows. LOCK file is opened
+// exclusively by LevelDB so a lock held by another process is reported when
+// opening the database.
+func isLevelDBLocked(dbPath string) (locked bool, err error) {
+	return false, nil
+}
+
+// ERROR_SHARING_VIOLATION
+const errSharingViolation syscall.Errno = 32
+
+func isLockError(err error) bool {
+	return errors.Is(err, errSharingViolation)
+}
@@ -122,3 +122,37 @@ func GeneratePublicKey(publicKey *rsa.PublicKey) ([]byte, error) {
 	publicPEM := pem.EncodeToMemory(&privBlock)
 	return publicPEM, nil
 }
+
+// NormalizeYAML converts maps decoded by YAML to map[string]interface{} for
+// JSON encoding
+func NormalizeYAML(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		normalized := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			normalized[fmt.Sprint(k)] = NormalizeYAML(v)
+		}
+		return normalized
+	case map[string]interface{}:
+		return NormalizeYAMLMap(value)
+	case []interface{}:
+		normalized := make([]interface{}, len(value))
+		for i, v := range value {
+			normalized[i] = NormalizeYAML(v)
+		}
+		return normalized
+	}
+	return value
+}
+
+// NormalizeYAMLMap normalizes every value of a map decoded by YAML
+func NormalizeYAMLMap(value map[string]interface{}) map[string]interface{} {
+	if value == nil {
+		return nil
+	}
+	normalized := make(map[string]interface{}, len(value))
+	for k, v := range value {
+		normalized[k] = NormalizeYAML(v)
+	}
+	return normalized
+}